@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
@@ -14,27 +17,86 @@ import (
 	"github.com/elberthcabrales/movies-api/pkg/repositories"
 	"github.com/elberthcabrales/movies-api/pkg/router"
 	"github.com/elberthcabrales/movies-api/pkg/services"
+	"github.com/elberthcabrales/movies-api/pkg/warmup"
 )
 
 func initializeServer() *gin.Engine {
-	// Load Redis configuration
-	log.Println("Loading Redis configuration...")
+	// Load cache configuration
+	log.Println("Loading cache configuration...")
+	cacheConfig := config.LoadCacheConfig()
 	redisConfig := config.LoadConfig()
-	redisCache := cache.NewRedisCache(nil, redisConfig)
-	token := os.Getenv("TOKEN")
+	metrics := cache.NewMetrics()
+	backend, err := cache.NewCache(cacheConfig, redisConfig)
+	if err != nil {
+		log.Fatalf("Invalid cache configuration: %v", err)
+	}
+	var movieCache cache.Cache = cache.NewInstrumentedCache(backend, metrics)
+	valueCodec, err := cache.NewValueCodec(cacheConfig)
+	if err != nil {
+		log.Printf("%v, falling back to JSON", err)
+		valueCodec = cache.JSONValueCodec()
+	}
+	upstreamConfig := config.LoadUpstreamConfig()
+	log.Printf("Using upstream API at %s", upstreamConfig.BaseURL)
+	repoOptions := []repositories.Option{
+		repositories.WithUpstream(upstreamConfig),
+		repositories.WithValueCodec(valueCodec),
+		repositories.WithTTLPolicy(cache.NewTTLPolicy(cacheConfig)),
+		repositories.WithLocalCache(cacheConfig.L1MaxEntries, cacheConfig.L1TTL),
+		repositories.WithLanguage(os.Getenv("TMDB_LANGUAGE")),
+		repositories.WithLegacyKeyFallback(cacheConfig.LegacyKeyFallback),
+		repositories.WithMetrics(metrics),
+		repositories.WithSnapshotLimit(int64(cacheConfig.SnapshotMaxBytes)),
+	}
+	if upstreamConfig.RateLimit > 0 {
+		repoOptions = append(repoOptions, repositories.WithRateLimiter(newRateLimiter(upstreamConfig, cacheConfig, redisConfig)))
+	}
+	var breaker *repositories.CircuitBreaker
+	if upstreamConfig.BreakerFailureRate > 0 {
+		breaker = repositories.NewCircuitBreaker(upstreamConfig)
+		repoOptions = append(repoOptions, repositories.WithCircuitBreaker(breaker))
+	}
+
+	// Keep local caches of other replicas in sync through Redis pub/sub
+	if cacheConfig.Backend == config.CacheBackendRedis && cacheConfig.InvalidationChannel != "" {
+		log.Printf("Subscribing to cache invalidations on channel %s...", cacheConfig.InvalidationChannel)
+		invalidator, err := cache.NewInvalidatorFromConfig(redisConfig, cacheConfig.InvalidationChannel)
+		if err != nil {
+			log.Fatalf("Invalid cache configuration: %v", err)
+		}
+		go invalidator.Run(context.Background())
+		movieCache = cache.NewPublishingCache(movieCache, invalidator)
+		repoOptions = append(repoOptions, repositories.WithInvalidator(invalidator))
+	}
 
 	// Initialize MovieRepository
 	log.Println("Initializing MovieRepository...")
-	movieRepo := repositories.NewMovieRepository(token, redisCache)
+	movieRepo := repositories.NewMovieRepository(upstreamConfig.Token, movieCache, repoOptions...)
 
 	// Initialize MovieService
 	log.Println("Initializing MovieService...")
 	movieService := services.NewMovieService(movieRepo)
+	adminService := services.NewCacheAdminService(movieRepo)
+
+	// Warm the cache at startup and on schedule
+	warmer := warmup.NewWarmer(movieRepo, config.LoadWarmupConfig())
+	go warmer.Run(context.Background())
 
 	// Initialize Router
 	log.Println("Setting up routes...")
 	movieRouter := router.NewMovieRouter(movieService)
 	r := movieRouter.SetupRouter()
+	router.NewHealthRouter(movieCache, breaker).RegisterRoutes(r)
+	router.NewAdminRouter(adminService, metrics, warmer, os.Getenv("ADMIN_TOKEN"), int64(cacheConfig.SnapshotMaxBytes)).RegisterRoutes(r)
+
+	// Prometheus endpoint
+	log.Println("Setting up metrics...")
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metrics)
+	if breaker != nil {
+		registry.MustRegister(breaker)
+	}
+	r.GET("/metrics", gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
 
 	// Swagger endpoint
 	log.Println("Setting up Swagger documentation...")
@@ -43,7 +105,33 @@ func initializeServer() *gin.Engine {
 	return r
 }
 
+// newRateLimiter creates the limiter of upstream requests. It is kept in Redis
+// when it is shared and Redis is the cache backend; otherwise each replica
+// limits its own requests.
+func newRateLimiter(upstreamConfig *config.UpstreamConfig, cacheConfig *config.CacheConfig, redisConfig *config.RedisConfig) repositories.RateLimiter {
+	if !upstreamConfig.RateLimitShared {
+		return repositories.NewLocalRateLimiter(upstreamConfig)
+	}
+	if cacheConfig.Backend != config.CacheBackendRedis {
+		log.Printf("A shared upstream rate limit needs the Redis backend, limiting each replica instead")
+		return repositories.NewLocalRateLimiter(upstreamConfig)
+	}
+	client, err := cache.NewRedisClient(redisConfig)
+	if err != nil {
+		log.Fatalf("Invalid cache configuration: %v", err)
+	}
+	log.Printf("Sharing the upstream rate limit of %g requests per second through Redis", upstreamConfig.RateLimit)
+	return repositories.NewRedisRateLimiter(client, upstreamConfig)
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		if err := runSnapshot(context.Background(), os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("Snapshot failed: %v", err)
+		}
+		return
+	}
+
 	log.Println("Starting server...")
 	r := initializeServer()
 	if err := r.Run(":8080"); err != nil {
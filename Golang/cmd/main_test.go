@@ -14,6 +14,8 @@ func TestInitializeServer(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	os.Setenv("TOKEN", "dummy-token")
+	os.Setenv("CACHE_BACKEND", "memory")
+	defer os.Unsetenv("CACHE_BACKEND")
 
 	r := initializeServer()
 
@@ -30,5 +32,21 @@ func TestInitializeServer(t *testing.T) {
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
-	assert.True(t, w.Code == http.StatusNotFound || w.Code == http.StatusInternalServerError)
+	// The dummy token is rejected by TMDB (502), or TMDB is unreachable (503).
+	assert.Contains(t, []int{http.StatusBadGateway, http.StatusServiceUnavailable}, w.Code)
+
+	req, _ = http.NewRequest("GET", "/health", nil)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "movies_cache_backend_entries")
 }
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+const snapshotUsage = `usage:
+  movies-api snapshot export [-o file] [-gzip]
+  movies-api snapshot import [-i file] [-overwrite]`
+
+// runSnapshot implements the snapshot subcommand, which exports the shared
+// cache to a file or imports a file into it. A file name of "-" stands for
+// standard input or output.
+func runSnapshot(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(snapshotUsage)
+	}
+	cacheConfig := config.LoadCacheConfig()
+	if cacheConfig.Backend == config.CacheBackendMemory {
+		return errors.New("snapshot needs a shared cache backend, CACHE_BACKEND is memory")
+	}
+	redisConfig := config.LoadConfig()
+	movieCache, err := cache.NewCache(cacheConfig, redisConfig)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "export":
+		flags := flag.NewFlagSet("snapshot export", flag.ContinueOnError)
+		output := flags.String("o", "-", "file to write the snapshot to")
+		compress := flags.Bool("gzip", false, "compress the snapshot with gzip, implied by a .gz file name")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		return exportSnapshot(ctx, movieCache, *output, *compress || strings.HasSuffix(*output, ".gz"), stdout)
+	case "import":
+		flags := flag.NewFlagSet("snapshot import", flag.ContinueOnError)
+		input := flags.String("i", "-", "file to read the snapshot from")
+		overwrite := flags.Bool("overwrite", false, "overwrite keys that already hold a value")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if err := importSnapshot(ctx, movieCache, *input, *overwrite, stdin); err != nil {
+			return err
+		}
+		// Other replicas may hold older copies of the imported entries.
+		if cacheConfig.InvalidationChannel != "" {
+			invalidator, err := cache.NewInvalidatorFromConfig(redisConfig, cacheConfig.InvalidationChannel)
+			if err != nil {
+				return err
+			}
+			if err := invalidator.PublishAll(ctx); err != nil {
+				log.Printf("Failed to notify replicas of the import: %v", err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown snapshot command %q\n%s", args[0], snapshotUsage)
+	}
+}
+
+func exportSnapshot(ctx context.Context, c cache.Cache, output string, compress bool, stdout io.Writer) error {
+	if output == "-" {
+		_, err := cache.ExportSnapshot(ctx, c, stdout, compress)
+		return err
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	_, err = cache.ExportSnapshot(ctx, c, f, compress)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Do not leave a partial snapshot behind.
+		os.Remove(output)
+		return err
+	}
+	log.Printf("Cache snapshot written to %s", output)
+	return nil
+}
+
+func importSnapshot(ctx context.Context, c cache.Cache, input string, overwrite bool, stdin io.Reader) error {
+	if input == "-" {
+		_, err := cache.ImportSnapshot(ctx, c, stdin, overwrite, 0)
+		return err
+	}
+	f, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = cache.ImportSnapshot(ctx, c, f, overwrite, 0)
+	return err
+}
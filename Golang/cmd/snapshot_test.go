@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunSnapshot_ExportAndImport(t *testing.T) {
+	source := miniredis.RunT(t)
+	t.Setenv("CACHE_BACKEND", "redis")
+	t.Setenv("REDIS_ADDR", source.Addr())
+	t.Setenv("CACHE_INVALIDATION_CHANNEL", "")
+	assert.NoError(t, source.Set("movies:v2:movie:1:en-US", `{"id":1}`))
+	source.SetTTL("movies:v2:movie:1:en-US", time.Hour)
+	assert.NoError(t, source.Set("movies:v2:discover:en-US:page=1", `{"page":1}`))
+	assert.NoError(t, source.Set("unrelated", "x"))
+
+	file := filepath.Join(t.TempDir(), "snapshot.ndjson.gz")
+	assert.NoError(t, runSnapshot(context.Background(), []string{"export", "-o", file}, nil, nil))
+	content, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x1f, 0x8b}, content[:2], "Expected a .gz file to be compressed")
+
+	target := miniredis.RunT(t)
+	t.Setenv("REDIS_ADDR", target.Addr())
+	assert.NoError(t, target.Set("movies:v2:discover:en-US:page=1", `{"page":1,"local":true}`))
+
+	assert.NoError(t, runSnapshot(context.Background(), []string{"import", "-i", file}, nil, nil))
+	value, _ := target.Get("movies:v2:movie:1:en-US")
+	assert.Equal(t, `{"id":1}`, value)
+	assert.InDelta(t, time.Hour, target.TTL("movies:v2:movie:1:en-US"), float64(time.Second))
+	value, _ = target.Get("movies:v2:discover:en-US:page=1")
+	assert.Equal(t, `{"page":1,"local":true}`, value, "Expected existing keys to be skipped")
+	assert.False(t, target.Exists("unrelated"))
+
+	assert.NoError(t, runSnapshot(context.Background(), []string{"import", "-i", file, "-overwrite"}, nil, nil))
+	value, _ = target.Get("movies:v2:discover:en-US:page=1")
+	assert.Equal(t, `{"page":1}`, value)
+}
+
+func TestRunSnapshot_StandardStreams(t *testing.T) {
+	source := miniredis.RunT(t)
+	t.Setenv("CACHE_BACKEND", "redis")
+	t.Setenv("REDIS_ADDR", source.Addr())
+	t.Setenv("CACHE_INVALIDATION_CHANNEL", "")
+	assert.NoError(t, source.Set("movies:v2:movie:1:en-US", `{"id":1}`))
+
+	var out bytes.Buffer
+	assert.NoError(t, runSnapshot(context.Background(), []string{"export"}, nil, &out))
+	assert.Contains(t, out.String(), `"key":"movies:v2:movie:1:en-US"`)
+
+	target := miniredis.RunT(t)
+	t.Setenv("REDIS_ADDR", target.Addr())
+	assert.NoError(t, runSnapshot(context.Background(), []string{"import", "-i", "-"}, &out, nil))
+	assert.True(t, target.Exists("movies:v2:movie:1:en-US"))
+}
+
+func TestRunSnapshot_InvalidUsage(t *testing.T) {
+	t.Setenv("CACHE_BACKEND", "redis")
+
+	assert.Error(t, runSnapshot(context.Background(), nil, nil, nil))
+	assert.ErrorContains(t, runSnapshot(context.Background(), []string{"restore"}, nil, nil), "unknown snapshot command")
+	assert.Error(t, runSnapshot(context.Background(), []string{"export", "-unknown"}, nil, nil))
+
+	t.Setenv("CACHE_BACKEND", "memory")
+	assert.ErrorContains(t, runSnapshot(context.Background(), []string{"export"}, nil, nil), "memory")
+}
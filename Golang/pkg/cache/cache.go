@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+// ErrCacheMiss is returned by GetValue when the key is not present in the cache.
+var ErrCacheMiss = errors.New("cache: key not found")
+
+// ErrInvalidPattern is returned by DeleteMatching for a malformed key pattern.
+var ErrInvalidPattern = errors.New("cache: invalid key pattern")
+
+// Status describes how a cached read was served. It is reported to clients in
+// the X-Cache response header.
+type Status string
+
+// Possible values of Status.
+const (
+	// StatusHit means the value was served from the cache and is fresh.
+	StatusHit Status = "HIT"
+	// StatusStale means the value was served from the cache past its soft TTL
+	// while a background refresh was started.
+	StatusStale Status = "STALE"
+	// StatusMiss means the value had to be fetched from the upstream API.
+	StatusMiss Status = "MISS"
+)
+
+// Entry is a cached value together with its remaining lifetime.
+type Entry struct {
+	Value string
+	// ExpiresIn is the time left before the entry expires, or zero if it never expires.
+	ExpiresIn time.Duration
+}
+
+// Cache defines the operations the repositories need from a cache backend.
+// Operations give up when ctx is cancelled or its deadline passes.
+type Cache interface {
+	// SetValue stores value under key without expiry, replacing any previous value.
+	SetValue(ctx context.Context, key string, value interface{}) error
+	// SetValueWithTTL stores value under key for the given duration. A zero ttl means no expiry.
+	SetValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	// SetValueIfAbsent stores value under key for the given duration unless the key
+	// already holds a value, and reports whether it was stored.
+	SetValueIfAbsent(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
+	// GetValue returns the value stored under key or ErrCacheMiss.
+	GetValue(ctx context.Context, key string) (string, error)
+	// GetEntry returns the value stored under key with its remaining TTL, or ErrCacheMiss.
+	GetEntry(ctx context.Context, key string) (*Entry, error)
+	// Delete removes key from the cache. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeleteMatching removes every key matching the glob pattern, as understood
+	// by path.Match and Redis SCAN, and returns how many were removed.
+	DeleteMatching(ctx context.Context, pattern string) (int, error)
+	// ScanKeys calls fn for every key matching the glob pattern until fn returns
+	// an error. Keys written or deleted during the scan may or may not be seen.
+	ScanKeys(ctx context.Context, pattern string, fn func(key string) error) error
+	// SetField stores value under field of the hash stored under key, creating
+	// the hash without expiry if needed.
+	SetField(ctx context.Context, key, field string, value interface{}) error
+	// GetField returns the value of field in the hash stored under key, or ErrCacheMiss.
+	GetField(ctx context.Context, key, field string) (string, error)
+	// GetFields returns every field of the hash stored under key with its value,
+	// or an empty map if there is none.
+	GetFields(ctx context.Context, key string) (map[string]string, error)
+	// DeleteField removes field from the hash stored under key. Deleting a
+	// missing field is not an error.
+	DeleteField(ctx context.Context, key, field string) error
+	// Healthy reports whether the backend is reachable. Operations on an
+	// unhealthy backend may fail fast with ErrCacheUnavailable.
+	Healthy() bool
+}
+
+// TTLPolicy holds the expiration applied to each class of cached key.
+// Movies saved through the API are not covered by the policy: they never expire.
+type TTLPolicy struct {
+	// Movie applies to movie details fetched from TMDB. It is the hard TTL:
+	// once it elapses the entry is gone and readers wait on the upstream API.
+	Movie time.Duration
+	// MovieSoft is the age after which a cached movie is served as stale and
+	// refreshed in the background. Zero, or a value not below Movie, disables it.
+	MovieSoft time.Duration
+	// Discover applies to discover pages fetched from TMDB.
+	Discover time.Duration
+	// Search applies to search results fetched from TMDB.
+	Search time.Duration
+	// NotFound applies to negative results for IDs TMDB does not know. Zero
+	// disables negative caching.
+	NotFound time.Duration
+}
+
+// DefaultTTLPolicy returns the policy used when none is configured.
+func DefaultTTLPolicy() TTLPolicy {
+	return TTLPolicy{
+		Movie:     24 * time.Hour,
+		MovieSoft: time.Hour,
+		Discover:  time.Hour,
+		Search:    15 * time.Minute,
+		NotFound:  5 * time.Minute,
+	}
+}
+
+// NewTTLPolicy builds a TTLPolicy from the cache configuration.
+func NewTTLPolicy(cfg *config.CacheConfig) TTLPolicy {
+	return TTLPolicy{
+		Movie:     cfg.MovieTTL,
+		MovieSoft: cfg.MovieSoftTTL,
+		Discover:  cfg.DiscoverTTL,
+		Search:    cfg.SearchTTL,
+		NotFound:  cfg.NotFoundTTL,
+	}
+}
+
+// IsMovieStale reports whether a movie entry has outlived the soft TTL. The age
+// of an entry is derived from its remaining lifetime, so entries without expiry,
+// such as movies saved through the API, are never stale.
+func (p TTLPolicy) IsMovieStale(entry *Entry) bool {
+	if p.MovieSoft <= 0 || p.MovieSoft >= p.Movie || entry.ExpiresIn <= 0 {
+		return false
+	}
+	return entry.ExpiresIn <= p.Movie-p.MovieSoft
+}
+
+// PartialMovieTTL returns the TTL for movie details seeded from partial data,
+// such as a discover page. The entry is stale as soon as it is written, so the
+// first read triggers a fetch of the full record. ok is false when the soft TTL
+// is disabled, since partial data would then be served as complete.
+func (p TTLPolicy) PartialMovieTTL() (ttl time.Duration, ok bool) {
+	if p.MovieSoft <= 0 || p.MovieSoft >= p.Movie {
+		return 0, false
+	}
+	return p.Movie - p.MovieSoft, true
+}
+
+// NewCache creates the cache backend selected by cfg. The Redis configuration is
+// only used when the Redis backend is selected.
+func NewCache(cfg *config.CacheConfig, redisCfg *config.RedisConfig) (Cache, error) {
+	switch cfg.Backend {
+	case config.CacheBackendMemory:
+		log.Println("Using in-memory cache backend")
+		return NewMemoryCache(cfg), nil
+	case config.CacheBackendRedis:
+		log.Println("Using Redis cache backend")
+	default:
+		log.Printf("Unknown cache backend %q, falling back to Redis", cfg.Backend)
+	}
+	return NewRedisCache(nil, redisCfg)
+}
+
+// validatePattern rejects patterns that path.Match cannot parse.
+func validatePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("%w: empty pattern", ErrInvalidPattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidPattern, pattern)
+	}
+	return nil
+}
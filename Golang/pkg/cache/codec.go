@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+// valueMagic starts every value written with a header. Plain JSON, as written
+// by earlier releases, never starts with it.
+const valueMagic = 0x00
+
+// maxDecodedValue bounds the size of a decompressed value.
+const maxDecodedValue = 32 << 20
+
+// Format bytes of the value header.
+const (
+	formatJSON    byte = 'j'
+	formatMsgpack byte = 'm'
+)
+
+// Compression bytes of the value header.
+const (
+	compressionNone byte = 'n'
+	compressionGzip byte = 'g'
+	compressionZstd byte = 'z'
+)
+
+var (
+	formats = map[string]byte{
+		config.CacheCodecJSON:    formatJSON,
+		config.CacheCodecMsgpack: formatMsgpack,
+	}
+	compressions = map[string]byte{
+		config.CacheCompressionNone: compressionNone,
+		config.CacheCompressionGzip: compressionGzip,
+		config.CacheCompressionZstd: compressionZstd,
+	}
+)
+
+// The zstd encoder and decoder are safe for concurrent use and costly to
+// create, so they are shared and only created when first needed.
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return enc
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxDecodedValue))
+		return dec
+	})
+)
+
+// ValueCodec serializes values stored in the shared cache. Values are written
+// with the configured format and, from a size threshold on, compression. A
+// three-byte header records both, so values written with any setting, and
+// the plain JSON of earlier releases, can be read side by side. Uncompressed
+// JSON is written without a header, exactly as earlier releases did.
+type ValueCodec struct {
+	format      byte
+	compression byte
+	minCompress int
+}
+
+// NewValueCodec creates a ValueCodec from the codec and compression settings
+// of cfg. It fails on unknown settings.
+func NewValueCodec(cfg *config.CacheConfig) (*ValueCodec, error) {
+	codecName, compressionName := cfg.Codec, cfg.Compression
+	if codecName == "" {
+		codecName = config.CacheCodecJSON
+	}
+	if compressionName == "" {
+		compressionName = config.CacheCompressionNone
+	}
+	format, ok := formats[codecName]
+	if !ok {
+		return nil, fmt.Errorf("unknown cache codec %q", codecName)
+	}
+	compression, ok := compressions[compressionName]
+	if !ok {
+		return nil, fmt.Errorf("unknown cache compression %q", compressionName)
+	}
+	return &ValueCodec{format: format, compression: compression, minCompress: cfg.CompressionMinBytes}, nil
+}
+
+// JSONValueCodec returns a ValueCodec writing plain JSON.
+func JSONValueCodec() *ValueCodec {
+	return &ValueCodec{format: formatJSON, compression: compressionNone}
+}
+
+// Marshal serializes v.
+func (c *ValueCodec) Marshal(v interface{}) (string, error) {
+	var body []byte
+	var err error
+	switch c.format {
+	case formatMsgpack:
+		body, err = marshalMsgpack(v)
+	default:
+		body, err = json.Marshal(v)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	compression := compressionNone
+	if c.compression != compressionNone && len(body) >= c.minCompress {
+		compressed, err := compress(c.compression, body)
+		if err != nil {
+			return "", err
+		}
+		// Keep values that do not shrink as they are.
+		if len(compressed) < len(body) {
+			body, compression = compressed, c.compression
+		}
+	}
+	if c.format == formatJSON && compression == compressionNone {
+		return string(body), nil
+	}
+	return string(append([]byte{valueMagic, c.format, compression}, body...)), nil
+}
+
+// Unmarshal deserializes data, written by any ValueCodec, into v.
+func (c *ValueCodec) Unmarshal(data string, v interface{}) error {
+	format, body, err := decodeValue(data)
+	if err != nil {
+		return err
+	}
+	if format == formatMsgpack {
+		return unmarshalMsgpack(body, v)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// ToJSON returns data, written by any ValueCodec, as JSON.
+func (c *ValueCodec) ToJSON(data string) (json.RawMessage, error) {
+	format, body, err := decodeValue(data)
+	if err != nil {
+		return nil, err
+	}
+	if format == formatJSON {
+		if !json.Valid(body) {
+			return nil, errors.New("invalid JSON")
+		}
+		return body, nil
+	}
+	var v interface{}
+	if err := unmarshalMsgpack(body, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// NewCodec returns a Codec storing values of type T through vc.
+func NewCodec[T any](vc *ValueCodec) Codec[*T] {
+	return Codec[*T]{
+		Encode: func(v *T) (string, error) {
+			return vc.Marshal(v)
+		},
+		Decode: func(data string) (*T, error) {
+			var v T
+			err := vc.Unmarshal(data, &v)
+			return &v, err
+		},
+	}
+}
+
+// decodeValue reads the header of data and returns the format and the
+// decompressed body. Data without a header is plain JSON.
+func decodeValue(data string) (byte, []byte, error) {
+	if len(data) == 0 || data[0] != valueMagic {
+		return formatJSON, []byte(data), nil
+	}
+	if len(data) < 3 {
+		return 0, nil, errors.New("truncated value header")
+	}
+	format, compression, body := data[1], data[2], []byte(data[3:])
+	if format != formatJSON && format != formatMsgpack {
+		return 0, nil, fmt.Errorf("unknown value format %q", format)
+	}
+	body, err := decompress(compression, body)
+	return format, body, err
+}
+
+func marshalMsgpack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	// Reuse the JSON field names so that both formats decode the same way.
+	enc.SetCustomStructTag("json")
+	enc.SetOmitEmpty(true)
+	err := enc.Encode(v)
+	return buf.Bytes(), err
+}
+
+func unmarshalMsgpack(body []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(body))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+func compress(compression byte, body []byte) ([]byte, error) {
+	switch compression {
+	case compressionZstd:
+		return zstdEncoder().EncodeAll(body, nil), nil
+	case compressionGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return body, nil
+	}
+}
+
+func decompress(compression byte, body []byte) ([]byte, error) {
+	switch compression {
+	case compressionNone:
+		return body, nil
+	case compressionZstd:
+		return zstdDecoder().DecodeAll(body, nil)
+	case compressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		decoded, err := io.ReadAll(io.LimitReader(zr, maxDecodedValue+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(decoded) > maxDecodedValue {
+			return nil, errors.New("decompressed value too large")
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("unknown value compression %q", compression)
+	}
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+type codecTestValue struct {
+	ID       int      `json:"id"`
+	Overview string   `json:"overview"`
+	Genres   []string `json:"genres"`
+}
+
+func newTestValueCodec(t *testing.T, codec, compression string, minBytes int) *ValueCodec {
+	vc, err := NewValueCodec(&config.CacheConfig{Codec: codec, Compression: compression, CompressionMinBytes: minBytes})
+	assert.NoError(t, err)
+	return vc
+}
+
+func TestValueCodec_RoundTrip(t *testing.T) {
+	value := codecTestValue{ID: 1, Overview: strings.Repeat("A long overview. ", 100), Genres: []string{"Action", "Comedy"}}
+	plain, err := JSONValueCodec().Marshal(value)
+	assert.NoError(t, err)
+
+	for _, codec := range []string{config.CacheCodecJSON, config.CacheCodecMsgpack} {
+		for _, compression := range []string{config.CacheCompressionNone, config.CacheCompressionGzip, config.CacheCompressionZstd} {
+			t.Run(codec+"/"+compression, func(t *testing.T) {
+				vc := newTestValueCodec(t, codec, compression, 64)
+
+				encoded, err := vc.Marshal(value)
+				assert.NoError(t, err)
+				if compression != config.CacheCompressionNone {
+					assert.Less(t, len(encoded), len(plain)/4, "Expected a repetitive value to shrink")
+				}
+
+				// Any codec reads what any other wrote.
+				var decoded codecTestValue
+				assert.NoError(t, JSONValueCodec().Unmarshal(encoded, &decoded))
+				assert.Equal(t, value, decoded)
+
+				asJSON, err := vc.ToJSON(encoded)
+				assert.NoError(t, err)
+				assert.JSONEq(t, plain, string(asJSON))
+			})
+		}
+	}
+}
+
+func TestValueCodec_WritesPlainJSONWithoutHeader(t *testing.T) {
+	// Earlier releases read these values, so they must stay plain JSON.
+	encoded, err := JSONValueCodec().Marshal(codecTestValue{ID: 1})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"overview":"","genres":null}`, encoded)
+
+	// Below the threshold values are not compressed.
+	encoded, err = newTestValueCodec(t, config.CacheCodecJSON, config.CacheCompressionZstd, 1024).Marshal(codecTestValue{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, byte('{'), encoded[0])
+
+	encoded, err = newTestValueCodec(t, config.CacheCodecMsgpack, config.CacheCompressionZstd, 1024).Marshal(codecTestValue{ID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "\x00mn", encoded[:3])
+}
+
+func TestValueCodec_ReadsLegacyJSON(t *testing.T) {
+	var decoded codecTestValue
+	err := newTestValueCodec(t, config.CacheCodecMsgpack, config.CacheCompressionGzip, 0).Unmarshal(`{"id":7,"genres":["Drama"]}`, &decoded)
+
+	assert.NoError(t, err)
+	assert.Equal(t, codecTestValue{ID: 7, Genres: []string{"Drama"}}, decoded)
+}
+
+func TestValueCodec_Invalid(t *testing.T) {
+	_, err := NewValueCodec(&config.CacheConfig{Codec: "protobuf"})
+	assert.Error(t, err)
+	_, err = NewValueCodec(&config.CacheConfig{Compression: "lz4"})
+	assert.Error(t, err)
+
+	var decoded codecTestValue
+	for _, value := range []string{"\x00", "\x00xn{}", "\x00jx{}", "\x00jzgarbage", "not json"} {
+		assert.Error(t, JSONValueCodec().Unmarshal(value, &decoded), "Expected %q to be rejected", value)
+	}
+}
+
+func TestNewCodec(t *testing.T) {
+	codec := NewCodec[codecTestValue](newTestValueCodec(t, config.CacheCodecMsgpack, config.CacheCompressionNone, 0))
+
+	encoded, err := codec.Encode(&codecTestValue{ID: 3})
+	assert.NoError(t, err)
+	decoded, err := codec.Decode(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, &codecTestValue{ID: 3}, decoded)
+}
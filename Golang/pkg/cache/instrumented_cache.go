@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// InstrumentedCache wraps a Cache and records hits, misses, errors and latency
+// of every operation in a Metrics, labelled by the class of the key.
+type InstrumentedCache struct {
+	Cache
+	metrics *Metrics
+}
+
+// evictionNotifier is implemented by backends that report their own evictions.
+type evictionNotifier interface {
+	OnEvict(fn func(key string))
+}
+
+// NewInstrumentedCache wraps c so that its operations are recorded in m. When c
+// reports evictions or backend statistics they are recorded as well; it must
+// therefore be called before c is used.
+func NewInstrumentedCache(c Cache, m *Metrics) *InstrumentedCache {
+	if n, ok := c.(evictionNotifier); ok {
+		n.OnEvict(m.RecordEviction)
+	}
+	if r, ok := c.(StatsReporter); ok {
+		m.mu.Lock()
+		m.backend = r
+		m.mu.Unlock()
+	}
+	return &InstrumentedCache{Cache: c, metrics: m}
+}
+
+// SetValue sets a key-value pair without expiry.
+func (c *InstrumentedCache) SetValue(ctx context.Context, key string, value interface{}) error {
+	defer c.observe(key, OpSet, time.Now())
+	return c.record(key, c.Cache.SetValue(ctx, key, value))
+}
+
+// SetValueWithTTL sets a key-value pair that expires after ttl.
+func (c *InstrumentedCache) SetValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	defer c.observe(key, OpSet, time.Now())
+	return c.record(key, c.Cache.SetValueWithTTL(ctx, key, value, ttl))
+}
+
+// SetValueIfAbsent adds a key-value pair unless the key already holds a value.
+func (c *InstrumentedCache) SetValueIfAbsent(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	defer c.observe(key, OpSet, time.Now())
+	added, err := c.Cache.SetValueIfAbsent(ctx, key, value, ttl)
+	return added, c.record(key, err)
+}
+
+// GetValue retrieves the value of the key.
+func (c *InstrumentedCache) GetValue(ctx context.Context, key string) (string, error) {
+	defer c.observe(key, OpGet, time.Now())
+	value, err := c.Cache.GetValue(ctx, key)
+	return value, c.recordLookup(key, err)
+}
+
+// GetEntry retrieves the value of the key together with its remaining TTL.
+func (c *InstrumentedCache) GetEntry(ctx context.Context, key string) (*Entry, error) {
+	defer c.observe(key, OpGet, time.Now())
+	entry, err := c.Cache.GetEntry(ctx, key)
+	return entry, c.recordLookup(key, err)
+}
+
+// Delete removes the key.
+func (c *InstrumentedCache) Delete(ctx context.Context, key string) error {
+	defer c.observe(key, OpDelete, time.Now())
+	return c.record(key, c.Cache.Delete(ctx, key))
+}
+
+// DeleteMatching removes every key matching pattern. It is recorded under the
+// class of the pattern, e.g. "movies:v2:discover:*" counts as a discover delete.
+func (c *InstrumentedCache) DeleteMatching(ctx context.Context, pattern string) (int, error) {
+	defer c.observe(pattern, OpDelete, time.Now())
+	n, err := c.Cache.DeleteMatching(ctx, pattern)
+	return n, c.record(pattern, err)
+}
+
+// SetField sets a field of the hash stored under key.
+func (c *InstrumentedCache) SetField(ctx context.Context, key, field string, value interface{}) error {
+	defer c.observe(key, OpSet, time.Now())
+	return c.record(key, c.Cache.SetField(ctx, key, field, value))
+}
+
+// GetField retrieves a field of the hash stored under key.
+func (c *InstrumentedCache) GetField(ctx context.Context, key, field string) (string, error) {
+	defer c.observe(key, OpGet, time.Now())
+	value, err := c.Cache.GetField(ctx, key, field)
+	return value, c.recordLookup(key, err)
+}
+
+// GetFields retrieves every field of the hash stored under key.
+func (c *InstrumentedCache) GetFields(ctx context.Context, key string) (map[string]string, error) {
+	defer c.observe(key, OpGet, time.Now())
+	fields, err := c.Cache.GetFields(ctx, key)
+	return fields, c.record(key, err)
+}
+
+// DeleteField removes a field of the hash stored under key.
+func (c *InstrumentedCache) DeleteField(ctx context.Context, key, field string) error {
+	defer c.observe(key, OpDelete, time.Now())
+	return c.record(key, c.Cache.DeleteField(ctx, key, field))
+}
+
+// BackendStats reports the statistics of the wrapped backend, if it has any.
+func (c *InstrumentedCache) BackendStats(ctx context.Context) (BackendStats, error) {
+	r, ok := c.Cache.(StatsReporter)
+	if !ok {
+		return BackendStats{}, errors.New("cache: backend does not report statistics")
+	}
+	return r.BackendStats(ctx)
+}
+
+func (c *InstrumentedCache) observe(key, op string, start time.Time) {
+	c.metrics.ObserveLatency(key, op, time.Since(start))
+}
+
+func (c *InstrumentedCache) recordLookup(key string, err error) error {
+	switch {
+	case err == nil:
+		c.metrics.RecordHit(key)
+	case errors.Is(err, ErrCacheMiss):
+		c.metrics.RecordMiss(key)
+	default:
+		c.metrics.RecordError(key)
+	}
+	return err
+}
+
+func (c *InstrumentedCache) record(key string, err error) error {
+	if err != nil {
+		c.metrics.RecordError(key)
+	}
+	return err
+}
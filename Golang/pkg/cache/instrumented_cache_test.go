@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedCache_RecordsLookups(t *testing.T) {
+	m := NewMetrics()
+	c := NewInstrumentedCache(newTestMemoryCache(10, 0, 1), m)
+	key := MovieKey("573435", DefaultLanguage)
+
+	assert.NoError(t, c.SetValueWithTTL(context.Background(), key, "{}", time.Hour))
+	_, err := c.GetEntry(context.Background(), key)
+	assert.NoError(t, err)
+	_, err = c.GetValue(context.Background(), MovieKey("1", DefaultLanguage))
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.NoError(t, c.Delete(context.Background(), key))
+
+	stats := m.Snapshot(context.Background()).Classes[KeyClassMovie]
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(0), stats.Errors)
+	assert.Equal(t, uint64(2), stats.Operations[OpGet].Count)
+	assert.Equal(t, uint64(1), stats.Operations[OpSet].Count)
+	assert.Equal(t, uint64(1), stats.Operations[OpDelete].Count)
+}
+
+func TestInstrumentedCache_RecordsErrors(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	mock.ExpectGet("movies:v2:discover:en-US:page=1").SetErr(errors.New("connection refused"))
+	mock.ExpectSet("movies:v2:discover:en-US:page=1", "{}", 0).SetErr(errors.New("connection refused"))
+
+	m := NewMetrics()
+	c := NewInstrumentedCache(&RedisCache{client: db}, m)
+
+	_, err := c.GetValue(context.Background(), "movies:v2:discover:en-US:page=1")
+	assert.Error(t, err)
+	assert.Error(t, c.SetValue(context.Background(), "movies:v2:discover:en-US:page=1", "{}"))
+
+	stats := m.Snapshot(context.Background()).Classes[KeyClassDiscover]
+	assert.Equal(t, uint64(2), stats.Errors)
+	assert.Equal(t, uint64(0), stats.Misses)
+}
+
+func TestInstrumentedCache_RecordsEvictions(t *testing.T) {
+	m := NewMetrics()
+	c := NewInstrumentedCache(newTestMemoryCache(1, 0, 1), m)
+
+	assert.NoError(t, c.SetValue(context.Background(), MovieKey("1", DefaultLanguage), "{}"))
+	assert.NoError(t, c.SetValue(context.Background(), MovieKey("2", DefaultLanguage), "{}"))
+
+	stats := m.Snapshot(context.Background())
+	assert.Equal(t, uint64(1), stats.Classes[KeyClassMovie].Evictions)
+	assert.Equal(t, &BackendStats{Entries: 1, Evictions: 1}, stats.Backend)
+}
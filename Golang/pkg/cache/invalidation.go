@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+// Bounds of the delay between attempts to re-establish a dropped subscription.
+const (
+	minResubscribeDelay = 100 * time.Millisecond
+	maxResubscribeDelay = 30 * time.Second
+)
+
+// InvalidationHandler drops local copies of keys that were changed elsewhere.
+type InvalidationHandler interface {
+	// Invalidate drops the given keys.
+	Invalidate(keys ...string)
+	// InvalidateAll drops every key, used when invalidation events may have been missed.
+	InvalidateAll()
+}
+
+// Invalidator broadcasts written or deleted keys to the other replicas.
+type Invalidator interface {
+	// Publish announces that keys were written or deleted by this replica.
+	Publish(ctx context.Context, keys ...string) error
+	// PublishAll announces that an unknown set of keys was changed by this
+	// replica, so that the others drop every local copy.
+	PublishAll(ctx context.Context) error
+	// Subscribe registers a handler for keys changed by other replicas.
+	Subscribe(handler InvalidationHandler)
+}
+
+// invalidationMessage is the payload sent on the invalidation channel.
+type invalidationMessage struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+	// All asks the receivers to drop every key, e.g. after a purge by pattern.
+	All bool `json:"all,omitempty"`
+}
+
+// RedisInvalidator publishes and receives invalidation events over Redis pub/sub.
+// Events published by this replica are ignored when they come back.
+type RedisInvalidator struct {
+	client  redis.UniversalClient
+	channel string
+	origin  string
+
+	mu       sync.RWMutex
+	handlers []InvalidationHandler
+}
+
+// NewRedisInvalidator creates a RedisInvalidator on the given channel.
+func NewRedisInvalidator(client redis.UniversalClient, channel string) *RedisInvalidator {
+	return &RedisInvalidator{
+		client:  client,
+		channel: channel,
+		origin:  newReplicaID(),
+	}
+}
+
+// NewInvalidatorFromConfig creates a RedisInvalidator with its own connection to
+// the configured Redis deployment.
+func NewInvalidatorFromConfig(cfg *config.RedisConfig, channel string) (*RedisInvalidator, error) {
+	client, err := NewRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewRedisInvalidator(client, channel), nil
+}
+
+func newReplicaID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format(time.RFC3339Nano)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Publish announces that keys were written or deleted by this replica.
+func (i *RedisInvalidator) Publish(ctx context.Context, keys ...string) error {
+	err := i.publish(ctx, invalidationMessage{Origin: i.origin, Keys: keys})
+	if err != nil {
+		log.Printf("Failed to publish invalidation for keys %v: %v", keys, err)
+		return err
+	}
+	return nil
+}
+
+// PublishAll announces that an unknown set of keys was changed by this replica.
+func (i *RedisInvalidator) PublishAll(ctx context.Context) error {
+	err := i.publish(ctx, invalidationMessage{Origin: i.origin, All: true})
+	if err != nil {
+		log.Printf("Failed to publish invalidation of all keys: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (i *RedisInvalidator) publish(ctx context.Context, msg invalidationMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return i.client.Publish(ctx, i.channel, payload).Err()
+}
+
+// Subscribe registers a handler for keys changed by other replicas.
+func (i *RedisInvalidator) Subscribe(handler InvalidationHandler) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.handlers = append(i.handlers, handler)
+}
+
+// Run listens for invalidation events until ctx is cancelled. When the
+// subscription drops it is re-established with exponential backoff, and every
+// handler is told to drop all keys since events may have been missed meanwhile.
+func (i *RedisInvalidator) Run(ctx context.Context) {
+	delay := minResubscribeDelay
+	connected := false
+	for {
+		err := i.listen(ctx, func() {
+			if connected {
+				log.Println("Invalidation subscription restored, dropping local cache entries")
+				i.dispatchAll()
+			}
+			connected = true
+			delay = minResubscribeDelay
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Invalidation subscription dropped: %v, retrying in %s", err, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxResubscribeDelay {
+			delay = maxResubscribeDelay
+		}
+	}
+}
+
+// listen subscribes to the channel, calls onSubscribed once the subscription
+// is confirmed and dispatches messages until an error occurs.
+func (i *RedisInvalidator) listen(ctx context.Context, onSubscribed func()) error {
+	pubsub := i.client.Subscribe(ctx, i.channel)
+	defer pubsub.Close()
+
+	// Blocking reads do not observe ctx, so closing the subscription is what
+	// unblocks them on shutdown.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			pubsub.Close()
+		case <-stop:
+		}
+	}()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+	onSubscribed()
+
+	for {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		i.handleMessage(msg.Payload)
+	}
+}
+
+func (i *RedisInvalidator) handleMessage(payload string) {
+	var msg invalidationMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("Ignoring malformed invalidation message: %v", err)
+		return
+	}
+	if msg.Origin == i.origin {
+		return
+	}
+	if msg.All {
+		i.dispatchAll()
+		return
+	}
+	if len(msg.Keys) == 0 {
+		return
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, handler := range i.handlers {
+		handler.Invalidate(msg.Keys...)
+	}
+}
+
+func (i *RedisInvalidator) dispatchAll() {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for _, handler := range i.handlers {
+		handler.InvalidateAll()
+	}
+}
+
+// PublishingCache wraps a Cache and publishes an invalidation event after
+// every successful write or delete.
+type PublishingCache struct {
+	Cache
+	invalidator Invalidator
+}
+
+// NewPublishingCache wraps c so that its writes are announced through invalidator.
+func NewPublishingCache(c Cache, invalidator Invalidator) *PublishingCache {
+	return &PublishingCache{Cache: c, invalidator: invalidator}
+}
+
+// SetValue sets a key-value pair and announces the write.
+func (p *PublishingCache) SetValue(ctx context.Context, key string, value interface{}) error {
+	return p.SetValueWithTTL(ctx, key, value, 0)
+}
+
+// SetValueWithTTL sets a key-value pair that expires after ttl and announces the write.
+func (p *PublishingCache) SetValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := p.Cache.SetValueWithTTL(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	p.publish(ctx, key)
+	return nil
+}
+
+// SetValueIfAbsent adds a key-value pair and announces the write if it was stored.
+func (p *PublishingCache) SetValueIfAbsent(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	added, err := p.Cache.SetValueIfAbsent(ctx, key, value, ttl)
+	if err != nil || !added {
+		return added, err
+	}
+	p.publish(ctx, key)
+	return true, nil
+}
+
+// Delete removes the key and announces the deletion.
+func (p *PublishingCache) Delete(ctx context.Context, key string) error {
+	if err := p.Cache.Delete(ctx, key); err != nil {
+		return err
+	}
+	p.publish(ctx, key)
+	return nil
+}
+
+// DeleteMatching removes every key matching pattern and, if any was removed,
+// tells the other replicas to drop all their local copies.
+func (p *PublishingCache) DeleteMatching(ctx context.Context, pattern string) (int, error) {
+	n, err := p.Cache.DeleteMatching(ctx, pattern)
+	if n > 0 {
+		if err := p.invalidator.PublishAll(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Other replicas were not notified about the purge of %s: %v", pattern, err)
+		}
+	}
+	return n, err
+}
+
+// publish announces a change. The write already succeeded, so the announcement
+// is not cancelled with the caller's ctx, and a failure to publish is logged
+// rather than returned; other replicas catch up when their local copies expire.
+func (p *PublishingCache) publish(ctx context.Context, key string) {
+	if err := p.invalidator.Publish(context.WithoutCancel(ctx), key); err != nil {
+		log.Printf("Other replicas were not notified about key %s: %v", key, err)
+	}
+}
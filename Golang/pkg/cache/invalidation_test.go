@@ -0,0 +1,239 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+type recordingHandler struct {
+	mu      sync.Mutex
+	keys    []string
+	resets  int
+	invalid chan struct{}
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{invalid: make(chan struct{}, 16)}
+}
+
+func (h *recordingHandler) Invalidate(keys ...string) {
+	h.mu.Lock()
+	h.keys = append(h.keys, keys...)
+	h.mu.Unlock()
+	h.invalid <- struct{}{}
+}
+
+func (h *recordingHandler) InvalidateAll() {
+	h.mu.Lock()
+	h.resets++
+	h.mu.Unlock()
+}
+
+func (h *recordingHandler) snapshot() ([]string, int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.keys...), h.resets
+}
+
+type recordingInvalidator struct {
+	published []string
+	purges    int
+	err       error
+}
+
+func (r *recordingInvalidator) Publish(ctx context.Context, keys ...string) error {
+	r.published = append(r.published, keys...)
+	return r.err
+}
+
+func (r *recordingInvalidator) PublishAll(ctx context.Context) error {
+	r.purges++
+	return r.err
+}
+
+func (r *recordingInvalidator) Subscribe(InvalidationHandler) {}
+
+// startReplica runs an invalidator against the server and waits until it is subscribed.
+func startReplica(t *testing.T, server *miniredis.Miniredis) (*RedisInvalidator, *recordingHandler) {
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	invalidator := NewRedisInvalidator(client, "movies:invalidate")
+	handler := newRecordingHandler()
+	invalidator.Subscribe(handler)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		invalidator.Run(runCtx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		client.Close()
+	})
+
+	assert.Eventually(t, func() bool {
+		return server.PubSubNumSub("movies:invalidate")["movies:invalidate"] > 0
+	}, time.Second, 5*time.Millisecond)
+	return invalidator, handler
+}
+
+func TestRedisInvalidator_DeliversToOtherReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	replicaA, handlerA := startReplica(t, server)
+	_, handlerB := startReplica(t, server)
+
+	assert.NoError(t, replicaA.Publish(context.Background(), "573435"))
+
+	select {
+	case <-handlerB.invalid:
+	case <-time.After(time.Second):
+		t.Fatal("replica B did not receive the invalidation")
+	}
+	keys, _ := handlerB.snapshot()
+	assert.Equal(t, []string{"573435"}, keys)
+
+	// Give replica A the same chance to (wrongly) handle its own event.
+	time.Sleep(50 * time.Millisecond)
+	keys, _ = handlerA.snapshot()
+	assert.Empty(t, keys, "Expected replicas to ignore their own events")
+}
+
+func TestRedisInvalidator_ResubscribesAfterDrop(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	_, handler := startReplica(t, server)
+
+	server.Close()
+	assert.NoError(t, server.Restart())
+
+	// The replica comes back, drops everything it may have missed and keeps receiving.
+	assert.Eventually(t, func() bool {
+		_, resets := handler.snapshot()
+		return resets == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	publisher := NewRedisInvalidator(redis.NewClient(&redis.Options{Addr: server.Addr()}), "movies:invalidate")
+	assert.NoError(t, publisher.Publish(context.Background(), "200002"))
+
+	select {
+	case <-handler.invalid:
+	case <-time.After(time.Second):
+		t.Fatal("replica did not receive the invalidation after resubscribing")
+	}
+	keys, _ := handler.snapshot()
+	assert.Equal(t, []string{"200002"}, keys)
+}
+
+func TestRedisInvalidator_IgnoresMalformedMessages(t *testing.T) {
+	invalidator := NewRedisInvalidator(nil, "movies:invalidate")
+	handler := newRecordingHandler()
+	invalidator.Subscribe(handler)
+
+	invalidator.handleMessage("not json")
+	invalidator.handleMessage(`{"origin":"other","keys":[]}`)
+
+	keys, _ := handler.snapshot()
+	assert.Empty(t, keys)
+}
+
+func TestRedisInvalidator_HandlesPurges(t *testing.T) {
+	invalidator := NewRedisInvalidator(nil, "movies:invalidate")
+	handler := newRecordingHandler()
+	invalidator.Subscribe(handler)
+
+	invalidator.handleMessage(`{"origin":"other","keys":null,"all":true}`)
+
+	keys, resets := handler.snapshot()
+	assert.Empty(t, keys)
+	assert.Equal(t, 1, resets)
+}
+
+func TestRedisInvalidator_PublishAll(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	invalidator := NewRedisInvalidator(db, "movies:invalidate")
+	invalidator.origin = "replica-a"
+
+	mock.ExpectPublish("movies:invalidate", []byte(`{"origin":"replica-a","keys":null,"all":true}`)).SetVal(1)
+
+	assert.NoError(t, invalidator.PublishAll(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisInvalidator_Publish(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	invalidator := NewRedisInvalidator(db, "movies:invalidate")
+	invalidator.origin = "replica-a"
+
+	mock.ExpectPublish("movies:invalidate", []byte(`{"origin":"replica-a","keys":["573435"]}`)).SetVal(1)
+
+	assert.NoError(t, invalidator.Publish(context.Background(), "573435"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishingCache_PublishesWrites(t *testing.T) {
+	invalidator := &recordingInvalidator{}
+	publishing := NewPublishingCache(NewMemoryCache(&config.CacheConfig{Shards: 1}), invalidator)
+
+	assert.NoError(t, publishing.SetValue(context.Background(), "a", "1"))
+	assert.NoError(t, publishing.SetValueWithTTL(context.Background(), "b", "2", time.Minute))
+	assert.NoError(t, publishing.Delete(context.Background(), "a"))
+
+	added, err := publishing.SetValueIfAbsent(context.Background(), "c", "3", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = publishing.SetValueIfAbsent(context.Background(), "c", "4", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, added)
+
+	value, err := publishing.GetValue(context.Background(), "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", value)
+	assert.Equal(t, []string{"a", "b", "a", "c"}, invalidator.published)
+}
+
+func TestPublishingCache_SkipsFailedWrites(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	invalidator := &recordingInvalidator{}
+	publishing := NewPublishingCache(&RedisCache{client: db}, invalidator)
+
+	mock.ExpectSet("a", "1", time.Duration(0)).SetErr(errors.New("connection refused"))
+
+	assert.Error(t, publishing.SetValue(context.Background(), "a", "1"))
+	assert.Empty(t, invalidator.published)
+}
+
+func TestPublishingCache_PublishFailureDoesNotFailWrite(t *testing.T) {
+	invalidator := &recordingInvalidator{err: errors.New("connection refused")}
+	publishing := NewPublishingCache(NewMemoryCache(&config.CacheConfig{Shards: 1}), invalidator)
+
+	assert.NoError(t, publishing.SetValue(context.Background(), "a", "1"))
+	assert.Equal(t, []string{"a"}, invalidator.published)
+}
+
+func TestPublishingCache_PublishesPurges(t *testing.T) {
+	invalidator := &recordingInvalidator{}
+	publishing := NewPublishingCache(NewMemoryCache(&config.CacheConfig{Shards: 1}), invalidator)
+
+	n, err := publishing.DeleteMatching(context.Background(), "movies:*")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 0, invalidator.purges, "Expected no announcement when nothing was deleted")
+
+	assert.NoError(t, publishing.SetValue(context.Background(), "movies:v2:movie:1:en-US", "{}"))
+	n, err = publishing.DeleteMatching(context.Background(), "movies:*")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, invalidator.purges)
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// KeyNamespace prefixes every key written by this service so that it can share
+// a Redis database with other applications.
+const KeyNamespace = "movies"
+
+// KeySchemaVersion is part of every key. Bump it whenever the cached
+// representation of a value changes incompatibly, so that replicas running
+// different versions during a rollout never read each other's entries.
+const KeySchemaVersion = 2
+
+// DefaultLanguage is the language of entries written before keys carried one.
+const DefaultLanguage = "en-US"
+
+// Key classes identify the kind of entity stored under a key.
+const (
+	KeyClassMovie     = "movie"
+	KeyClassDiscover  = "discover"
+	KeyClassSearch    = "search"
+	KeyClassTombstone = "tombstone"
+	KeyClassSaved     = "saved"
+	// KeyClassLegacy covers the bare-ID keys written by earlier releases.
+	KeyClassLegacy = "legacy"
+	// KeyClassOther covers any key this service did not build.
+	KeyClassOther = "other"
+)
+
+// keyPrefix is the part shared by every key built by BuildKey.
+var keyPrefix = fmt.Sprintf("%s:v%d:", KeyNamespace, KeySchemaVersion)
+
+// BuildKey returns the namespaced, versioned key for an entity of the given
+// class, e.g. "movies:v2:movie:573435:en-US".
+func BuildKey(class string, parts ...string) string {
+	var b strings.Builder
+	b.WriteString(keyPrefix)
+	b.WriteString(class)
+	for _, part := range parts {
+		b.WriteByte(':')
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// MovieKey returns the key of the details of a movie in the given language.
+func MovieKey(id, language string) string {
+	return BuildKey(KeyClassMovie, id, language)
+}
+
+// MovieTombstoneKey returns the key recording that the upstream API does not
+// know the movie, e.g. "movies:v2:tombstone:movie:573435:en-US".
+func MovieTombstoneKey(id, language string) string {
+	return BuildKey(KeyClassTombstone, KeyClassMovie, id, language)
+}
+
+// SavedMoviesKey returns the key of the hash holding, by ID, the movies saved
+// through the API in the given language, e.g. "movies:v2:saved:movie:en-US".
+func SavedMoviesKey(language string) string {
+	return BuildKey(KeyClassSaved, KeyClassMovie, language)
+}
+
+// DiscoverKey returns the key of a discover page in the given language. The
+// query parameters are encoded sorted by name, so the same combination always
+// maps to the same key, e.g. "movies:v2:discover:en-US:page=1".
+func DiscoverKey(language string, params url.Values) string {
+	return BuildKey(KeyClassDiscover, language, params.Encode())
+}
+
+// SearchKey returns the key of a page of search results in the given language.
+// The parameters are encoded like those of DiscoverKey, e.g.
+// "movies:v2:search:en-US:include_adult=false&page=1&query=bad+boys".
+func SearchKey(language string, params url.Values) string {
+	return BuildKey(KeyClassSearch, language, params.Encode())
+}
+
+// LegacyMovieKey returns the key movie details were stored under before keys
+// were namespaced: the bare numeric ID, implicitly in DefaultLanguage.
+func LegacyMovieKey(id string) string {
+	return id
+}
+
+// KeyClassOf returns the class of key. Keys of unknown classes, or not built by
+// BuildKey, map to KeyClassOther so that metrics labelled by class stay bounded.
+func KeyClassOf(key string) string {
+	rest, ok := strings.CutPrefix(key, keyPrefix)
+	if !ok {
+		if isLegacyKey(key) {
+			return KeyClassLegacy
+		}
+		return KeyClassOther
+	}
+	class, _, _ := strings.Cut(rest, ":")
+	switch class {
+	case KeyClassMovie, KeyClassDiscover, KeyClassSearch, KeyClassTombstone, KeyClassSaved:
+		return class
+	default:
+		return KeyClassOther
+	}
+}
+
+func isLegacyKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildKey(t *testing.T) {
+	assert.Equal(t, "movies:v2:movie", BuildKey(KeyClassMovie))
+	assert.Equal(t, "movies:v2:movie:573435:en-US", BuildKey(KeyClassMovie, "573435", "en-US"))
+}
+
+func TestMovieKey(t *testing.T) {
+	assert.Equal(t, "movies:v2:movie:573435:en-US", MovieKey("573435", DefaultLanguage))
+	assert.Equal(t, "movies:v2:movie:573435:es-MX", MovieKey("573435", "es-MX"))
+	assert.Equal(t, "movies:v2:tombstone:movie:573435:en-US", MovieTombstoneKey("573435", DefaultLanguage))
+	assert.Equal(t, "movies:v2:saved:movie:en-US", SavedMoviesKey(DefaultLanguage))
+	assert.Equal(t, "573435", LegacyMovieKey("573435"))
+}
+
+func TestDiscoverKey(t *testing.T) {
+	assert.Equal(t, "movies:v2:discover:en-US:page=1", DiscoverKey(DefaultLanguage, url.Values{"page": {"1"}}))
+
+	a := url.Values{}
+	a.Set("page", "2")
+	a.Set("with_genres", "28")
+	b := url.Values{}
+	b.Set("with_genres", "28")
+	b.Set("page", "2")
+	assert.Equal(t, "movies:v2:discover:es-MX:page=2&with_genres=28", DiscoverKey("es-MX", a))
+	assert.Equal(t, DiscoverKey("es-MX", a), DiscoverKey("es-MX", b), "parameter order must not matter")
+}
+
+func TestSearchKey(t *testing.T) {
+	params := url.Values{"query": {"bad boys"}, "page": {"1"}, "include_adult": {"false"}}
+	assert.Equal(t, "movies:v2:search:en-US:include_adult=false&page=1&query=bad+boys", SearchKey(DefaultLanguage, params))
+}
+
+func TestKeyClassOf(t *testing.T) {
+	assert.Equal(t, KeyClassMovie, KeyClassOf(MovieKey("573435", DefaultLanguage)))
+	assert.Equal(t, KeyClassDiscover, KeyClassOf(DiscoverKey(DefaultLanguage, url.Values{"page": {"1"}})))
+	assert.Equal(t, KeyClassSearch, KeyClassOf(SearchKey(DefaultLanguage, url.Values{"query": {"bad boys"}})))
+	assert.Equal(t, KeyClassTombstone, KeyClassOf(MovieTombstoneKey("573435", DefaultLanguage)))
+	assert.Equal(t, KeyClassSaved, KeyClassOf(SavedMoviesKey(DefaultLanguage)))
+	assert.Equal(t, KeyClassLegacy, KeyClassOf(LegacyMovieKey("573435")))
+	assert.Equal(t, KeyClassOther, KeyClassOf("movies:v2:unknown:1"))
+	assert.Equal(t, KeyClassOther, KeyClassOf("movies:v1:movie:573435"))
+	assert.Equal(t, KeyClassOther, KeyClassOf(""))
+}
@@ -0,0 +1,290 @@
+package cache
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shardedLRU is an in-process map split into independently locked shards. Each
+// shard evicts its least recently used entries once it exceeds its share of the
+// entry and byte budget. Expired entries are dropped lazily when they are read
+// or pushed out by the LRU.
+type shardedLRU[V any] struct {
+	shards []*lruShard[V]
+	// size reports the bytes accounted for an entry; nil disables byte accounting.
+	size func(key string, value V) int
+	now  func() time.Time
+
+	// evictions counts entries pushed out by the LRU; expired entries are not counted.
+	evictions atomic.Uint64
+	// onEvict, when set, is called with the key of every evicted entry while
+	// its shard is locked. It must be set before the cache is used.
+	onEvict func(key string)
+}
+
+type lruShard[V any] struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List
+	bytes      int
+	maxEntries int
+	maxBytes   int
+}
+
+type lruEntry[V any] struct {
+	key       string
+	value     V
+	size      int
+	expiresAt time.Time
+}
+
+func (e *lruEntry[V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+func newShardedLRU[V any](shardCount, maxEntries, maxBytes int, size func(string, V) int) *shardedLRU[V] {
+	if shardCount <= 0 {
+		shardCount = 1
+	}
+
+	c := &shardedLRU[V]{shards: make([]*lruShard[V], shardCount), size: size, now: time.Now}
+	for i := range c.shards {
+		c.shards[i] = &lruShard[V]{
+			items:      make(map[string]*list.Element),
+			order:      list.New(),
+			maxEntries: perShard(maxEntries, shardCount),
+			maxBytes:   perShard(maxBytes, shardCount),
+		}
+	}
+	return c
+}
+
+// perShard splits a budget across shards, rounding up so that no shard ends up with zero.
+func perShard(total, shards int) int {
+	if total <= 0 {
+		return 0
+	}
+	return (total + shards - 1) / shards
+}
+
+func (c *shardedLRU[V]) shard(key string) *lruShard[V] {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return c.shards[h.Sum32()%uint32(len(c.shards))]
+}
+
+// set stores value under key. A zero ttl means the entry never expires.
+func (c *shardedLRU[V]) set(key string, value V, ttl time.Duration) error {
+	return c.shard(key).set(c.newEntry(key, value, ttl), c.evicted)
+}
+
+// add stores value under key unless the key holds an unexpired entry, and
+// reports whether it was stored.
+func (c *shardedLRU[V]) add(key string, value V, ttl time.Duration) (bool, error) {
+	entry := c.newEntry(key, value, ttl)
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		if !el.Value.(*lruEntry[V]).expired(c.now()) {
+			return false, nil
+		}
+		s.removeElement(el)
+	}
+	if err := s.setLocked(entry, c.evicted); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// update replaces the value stored under key with the one fn returns for the
+// current value, if any, while the shard is locked. The entry keeps its expiry.
+// If fn reports false the key is removed instead.
+func (c *shardedLRU[V]) update(key string, fn func(value V, ok bool) (V, bool)) error {
+	s := c.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current V
+	var expiresAt time.Time
+	el, ok := s.items[key]
+	if ok {
+		if entry := el.Value.(*lruEntry[V]); !entry.expired(c.now()) {
+			current, expiresAt = entry.value, entry.expiresAt
+		} else {
+			s.removeElement(el)
+			ok = false
+		}
+	}
+	value, keep := fn(current, ok)
+	if !keep {
+		if ok {
+			s.removeElement(el)
+		}
+		return nil
+	}
+	entry := c.newEntry(key, value, 0)
+	entry.expiresAt = expiresAt
+	return s.setLocked(entry, c.evicted)
+}
+
+func (c *shardedLRU[V]) evicted(key string) {
+	c.evictions.Add(1)
+	if c.onEvict != nil {
+		c.onEvict(key)
+	}
+}
+
+func (c *shardedLRU[V]) newEntry(key string, value V, ttl time.Duration) *lruEntry[V] {
+	entry := &lruEntry[V]{key: key, value: value}
+	if c.size != nil {
+		entry.size = c.size(key, value)
+	}
+	if ttl > 0 {
+		entry.expiresAt = c.now().Add(ttl)
+	}
+	return entry
+}
+
+// get returns the value stored under key and its remaining lifetime, which is
+// zero for entries that never expire.
+func (c *shardedLRU[V]) get(key string) (V, time.Duration, bool) {
+	now := c.now()
+	entry, ok := c.shard(key).get(key, now)
+	if !ok {
+		var zero V
+		return zero, 0, false
+	}
+	var expiresIn time.Duration
+	if !entry.expiresAt.IsZero() {
+		expiresIn = entry.expiresAt.Sub(now)
+	}
+	return entry.value, expiresIn, true
+}
+
+func (c *shardedLRU[V]) delete(key string) {
+	c.shard(key).delete(key)
+}
+
+// deleteFunc removes every entry whose key satisfies match and returns how many
+// were removed.
+func (c *shardedLRU[V]) deleteFunc(match func(key string) bool) int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, el := range s.items {
+			if match(key) {
+				s.removeElement(el)
+				n++
+			}
+		}
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// keys returns the keys satisfying match, including expired entries not yet dropped.
+func (c *shardedLRU[V]) keys(match func(key string) bool) []string {
+	var keys []string
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key := range s.items {
+			if match(key) {
+				keys = append(keys, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+	return keys
+}
+
+func (c *shardedLRU[V]) clear() {
+	for _, s := range c.shards {
+		s.mu.Lock()
+		s.items = make(map[string]*list.Element)
+		s.order.Init()
+		s.bytes = 0
+		s.mu.Unlock()
+	}
+}
+
+func (c *shardedLRU[V]) len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += s.order.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+func (s *lruShard[V]) set(entry *lruEntry[V], evicted func(key string)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setLocked(entry, evicted)
+}
+
+func (s *lruShard[V]) setLocked(entry *lruEntry[V], evicted func(key string)) error {
+	if s.maxBytes > 0 && entry.size > s.maxBytes {
+		return ErrValueTooLarge
+	}
+
+	if el, ok := s.items[entry.key]; ok {
+		s.bytes -= el.Value.(*lruEntry[V]).size
+		el.Value = entry
+		s.order.MoveToFront(el)
+	} else {
+		s.items[entry.key] = s.order.PushFront(entry)
+	}
+	s.bytes += entry.size
+
+	for s.overBudget() {
+		oldest := s.order.Back()
+		s.removeElement(oldest)
+		evicted(oldest.Value.(*lruEntry[V]).key)
+	}
+	return nil
+}
+
+func (s *lruShard[V]) get(key string, now time.Time) (*lruEntry[V], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*lruEntry[V])
+	if entry.expired(now) {
+		s.removeElement(el)
+		return nil, false
+	}
+	s.order.MoveToFront(el)
+	return entry, true
+}
+
+func (s *lruShard[V]) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		s.removeElement(el)
+	}
+}
+
+func (s *lruShard[V]) overBudget() bool {
+	if s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		return true
+	}
+	return s.maxBytes > 0 && s.bytes > s.maxBytes
+}
+
+func (s *lruShard[V]) removeElement(el *list.Element) {
+	entry := s.order.Remove(el).(*lruEntry[V])
+	delete(s.items, entry.key)
+	s.bytes -= entry.size
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+// ErrValueTooLarge is returned when a single value does not fit in the memory budget of a shard.
+var ErrValueTooLarge = errors.New("cache: value exceeds memory budget")
+
+// MemoryCache is an in-process cache split into independently locked shards.
+// Each shard evicts its least recently used entries once it exceeds its share
+// of the configured entry and byte budget. Expired entries are dropped lazily
+// when they are read or pushed out by the LRU.
+type MemoryCache struct {
+	store *shardedLRU[string]
+}
+
+// NewMemoryCache creates a new MemoryCache sized according to the configuration.
+func NewMemoryCache(cfg *config.CacheConfig) *MemoryCache {
+	return &MemoryCache{
+		store: newShardedLRU(cfg.Shards, cfg.MaxEntries, cfg.MaxBytes, func(key, value string) int {
+			return len(key) + len(value)
+		}),
+	}
+}
+
+// SetValue sets a key-value pair in the in-memory cache.
+func (c *MemoryCache) SetValue(ctx context.Context, key string, value interface{}) error {
+	return c.SetValueWithTTL(ctx, key, value, 0)
+}
+
+// SetValueWithTTL sets a key-value pair in the in-memory cache that expires after ttl.
+func (c *MemoryCache) SetValueWithTTL(_ context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.store.set(key, stringify(value), ttl)
+}
+
+// SetValueIfAbsent sets a key-value pair in the in-memory cache that expires
+// after ttl, unless the key already holds an unexpired value.
+func (c *MemoryCache) SetValueIfAbsent(_ context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	return c.store.add(key, stringify(value), ttl)
+}
+
+// GetValue retrieves the value associated with the key from the in-memory cache.
+func (c *MemoryCache) GetValue(ctx context.Context, key string) (string, error) {
+	entry, err := c.GetEntry(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return entry.Value, nil
+}
+
+// GetEntry retrieves the value associated with the key together with its remaining TTL.
+func (c *MemoryCache) GetEntry(_ context.Context, key string) (*Entry, error) {
+	value, expiresIn, ok := c.store.get(key)
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return &Entry{Value: value, ExpiresIn: expiresIn}, nil
+}
+
+// Delete removes the key from the in-memory cache.
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.store.delete(key)
+	return nil
+}
+
+// DeleteMatching removes every key matching pattern from the in-memory cache.
+func (c *MemoryCache) DeleteMatching(_ context.Context, pattern string) (int, error) {
+	if err := validatePattern(pattern); err != nil {
+		return 0, err
+	}
+	return c.store.deleteFunc(func(key string) bool {
+		matched, _ := path.Match(pattern, key)
+		return matched
+	}), nil
+}
+
+// ScanKeys calls fn for every key matching pattern. The keys are collected
+// first, so fn may use the cache.
+func (c *MemoryCache) ScanKeys(ctx context.Context, pattern string, fn func(key string) error) error {
+	if err := validatePattern(pattern); err != nil {
+		return err
+	}
+	keys := c.store.keys(func(key string) bool {
+		matched, _ := path.Match(pattern, key)
+		return matched
+	})
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetField sets a field of a hash in the in-memory cache. Hashes are stored
+// as JSON objects.
+func (c *MemoryCache) SetField(_ context.Context, key, field string, value interface{}) error {
+	var decodeErr error
+	err := c.store.update(key, func(current string, ok bool) (string, bool) {
+		fields, err := decodeFields(current, ok)
+		if err != nil {
+			decodeErr = err
+			return current, ok
+		}
+		fields[field] = stringify(value)
+		return encodeFields(fields), true
+	})
+	if decodeErr != nil {
+		return decodeErr
+	}
+	return err
+}
+
+// GetField retrieves a field of a hash from the in-memory cache.
+func (c *MemoryCache) GetField(ctx context.Context, key, field string) (string, error) {
+	fields, err := c.GetFields(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", ErrCacheMiss
+	}
+	return value, nil
+}
+
+// GetFields retrieves every field of a hash from the in-memory cache.
+func (c *MemoryCache) GetFields(_ context.Context, key string) (map[string]string, error) {
+	value, _, ok := c.store.get(key)
+	return decodeFields(value, ok)
+}
+
+// DeleteField removes a field of a hash from the in-memory cache. The hash is
+// removed with its last field.
+func (c *MemoryCache) DeleteField(_ context.Context, key, field string) error {
+	var decodeErr error
+	err := c.store.update(key, func(current string, ok bool) (string, bool) {
+		if !ok {
+			return current, false
+		}
+		fields, err := decodeFields(current, ok)
+		if err != nil {
+			decodeErr = err
+			return current, true
+		}
+		delete(fields, field)
+		return encodeFields(fields), len(fields) > 0
+	})
+	if decodeErr != nil {
+		return decodeErr
+	}
+	return err
+}
+
+// Healthy always reports true: the in-memory cache cannot be unreachable.
+func (c *MemoryCache) Healthy() bool {
+	return true
+}
+
+// OnEvict registers fn to be called with the key of every entry evicted to
+// stay within the memory budget. It must be called before the cache is used.
+func (c *MemoryCache) OnEvict(fn func(key string)) {
+	c.store.onEvict = fn
+}
+
+// BackendStats reports the number of entries held and evicted so far.
+func (c *MemoryCache) BackendStats(context.Context) (BackendStats, error) {
+	return BackendStats{
+		Entries:   int64(c.store.len()),
+		Evictions: c.store.evictions.Load(),
+	}, nil
+}
+
+// Len returns the number of entries currently held by the cache.
+func (c *MemoryCache) Len() int {
+	return c.store.len()
+}
+
+// stringify converts a value to the string representation stored by the cache,
+// mirroring how the Redis client serialises its arguments.
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// decodeFields returns the fields of a hash stored as value, or an empty map
+// if there is no value.
+func decodeFields(value string, ok bool) (map[string]string, error) {
+	fields := map[string]string{}
+	if !ok {
+		return fields, nil
+	}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return nil, fmt.Errorf("cache: value is not a hash: %w", err)
+	}
+	return fields, nil
+}
+
+func encodeFields(fields map[string]string) string {
+	encoded, _ := json.Marshal(fields)
+	return string(encoded)
+}
@@ -0,0 +1,327 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+func newTestMemoryCache(maxEntries, maxBytes, shards int) *MemoryCache {
+	return NewMemoryCache(&config.CacheConfig{
+		Backend:    config.CacheBackendMemory,
+		MaxEntries: maxEntries,
+		MaxBytes:   maxBytes,
+		Shards:     shards,
+	})
+}
+
+func TestMemoryCache_SetAndGetValue(t *testing.T) {
+	cache := newTestMemoryCache(10, 0, 4)
+
+	err := cache.SetValue(context.Background(), "example_key", "example_value")
+	assert.NoError(t, err)
+
+	value, err := cache.GetValue(context.Background(), "example_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "example_value", value)
+}
+
+func TestMemoryCache_GetValue_NotFound(t *testing.T) {
+	cache := newTestMemoryCache(10, 0, 4)
+
+	value, err := cache.GetValue(context.Background(), "missing_key")
+
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, "", value)
+}
+
+func TestMemoryCache_SetValue_NonString(t *testing.T) {
+	cache := newTestMemoryCache(10, 0, 1)
+
+	assert.NoError(t, cache.SetValue(context.Background(), "bytes", []byte("raw")))
+	assert.NoError(t, cache.SetValue(context.Background(), "int", 42))
+
+	value, _ := cache.GetValue(context.Background(), "bytes")
+	assert.Equal(t, "raw", value)
+	value, _ = cache.GetValue(context.Background(), "int")
+	assert.Equal(t, "42", value)
+}
+
+func TestMemoryCache_SetValueWithTTL_Expires(t *testing.T) {
+	cache := newTestMemoryCache(10, 0, 1)
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	cache.store.now = func() time.Time { return now }
+
+	assert.NoError(t, cache.SetValueWithTTL(context.Background(), "short", "value", time.Minute))
+	assert.NoError(t, cache.SetValue(context.Background(), "forever", "value"))
+
+	now = now.Add(45 * time.Second)
+	entry, err := cache.GetEntry(context.Background(), "short")
+	assert.NoError(t, err)
+	assert.Equal(t, &Entry{Value: "value", ExpiresIn: 15 * time.Second}, entry)
+
+	entry, err = cache.GetEntry(context.Background(), "forever")
+	assert.NoError(t, err)
+	assert.Equal(t, &Entry{Value: "value"}, entry)
+
+	now = now.Add(14 * time.Second)
+	_, err = cache.GetValue(context.Background(), "short")
+	assert.NoError(t, err)
+
+	now = now.Add(time.Second)
+	_, err = cache.GetValue(context.Background(), "short")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, 1, cache.Len(), "Expected the expired entry to be dropped on read")
+
+	now = now.Add(365 * 24 * time.Hour)
+	_, err = cache.GetValue(context.Background(), "forever")
+	assert.NoError(t, err)
+}
+
+func TestMemoryCache_SetValueIfAbsent(t *testing.T) {
+	cache := newTestMemoryCache(10, 0, 1)
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	cache.store.now = func() time.Time { return now }
+
+	added, err := cache.SetValueIfAbsent(context.Background(), "key", "first", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = cache.SetValueIfAbsent(context.Background(), "key", "second", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, added)
+
+	value, err := cache.GetValue(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "first", value)
+
+	now = now.Add(time.Minute)
+	added, err = cache.SetValueIfAbsent(context.Background(), "key", "third", 0)
+	assert.NoError(t, err)
+	assert.True(t, added, "Expected an expired entry to be replaced")
+
+	value, err = cache.GetValue(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "third", value)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestMemoryCache_Delete(t *testing.T) {
+	cache := newTestMemoryCache(10, 0, 4)
+
+	assert.NoError(t, cache.SetValue(context.Background(), "example_key", "example_value"))
+	assert.NoError(t, cache.Delete(context.Background(), "example_key"))
+	assert.NoError(t, cache.Delete(context.Background(), "missing_key"))
+
+	_, err := cache.GetValue(context.Background(), "example_key")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestMemoryCache_DeleteMatching(t *testing.T) {
+	cache := newTestMemoryCache(10, 0, 4)
+	for _, key := range []string{"movies:v2:movie:1:en-US", "movies:v2:movie:2:en-US", "movies:v2:discover:en-US:page=1", "573435"} {
+		assert.NoError(t, cache.SetValue(context.Background(), key, "{}"))
+	}
+
+	n, err := cache.DeleteMatching(context.Background(), "movies:v2:movie:*")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, cache.Len())
+
+	_, err = cache.DeleteMatching(context.Background(), "movies:[")
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+	_, err = cache.DeleteMatching(context.Background(), "")
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestMemoryCache_ScanKeys(t *testing.T) {
+	cache := newTestMemoryCache(10, 0, 4)
+	for _, key := range []string{"movies:v2:movie:1:en-US", "movies:v2:movie:2:en-US", "movies:v2:discover:en-US:page=1"} {
+		assert.NoError(t, cache.SetValue(context.Background(), key, "{}"))
+	}
+
+	var keys []string
+	err := cache.ScanKeys(context.Background(), "movies:v2:movie:*", func(key string) error {
+		keys = append(keys, key)
+		// The callback may use the cache.
+		return cache.Delete(context.Background(), key)
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"movies:v2:movie:1:en-US", "movies:v2:movie:2:en-US"}, keys)
+	assert.Equal(t, 1, cache.Len())
+
+	stop := fmt.Errorf("stop")
+	err = cache.ScanKeys(context.Background(), "movies:*", func(string) error { return stop })
+	assert.ErrorIs(t, err, stop)
+	assert.ErrorIs(t, cache.ScanKeys(context.Background(), "movies:[", func(string) error { return nil }), ErrInvalidPattern)
+}
+
+func TestMemoryCache_Fields(t *testing.T) {
+	cache := newTestMemoryCache(10, 0, 1)
+
+	fields, err := cache.GetFields(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	assert.NoError(t, cache.SetField(context.Background(), "hash", "1", "one"))
+	assert.NoError(t, cache.SetField(context.Background(), "hash", "2", 2))
+	value, err := cache.GetField(context.Background(), "hash", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", value)
+	_, err = cache.GetField(context.Background(), "hash", "3")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, cache.DeleteField(context.Background(), "hash", "2"))
+	assert.NoError(t, cache.DeleteField(context.Background(), "missing", "2"))
+	fields, err = cache.GetFields(context.Background(), "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"1": "one"}, fields)
+
+	// The hash goes away with its last field.
+	assert.NoError(t, cache.DeleteField(context.Background(), "hash", "1"))
+	assert.Equal(t, 0, cache.Len())
+
+	assert.NoError(t, cache.SetValue(context.Background(), "plain", "value"))
+	assert.Error(t, cache.SetField(context.Background(), "plain", "1", "one"))
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsedByEntries(t *testing.T) {
+	cache := newTestMemoryCache(2, 0, 1)
+
+	assert.NoError(t, cache.SetValue(context.Background(), "a", "1"))
+	assert.NoError(t, cache.SetValue(context.Background(), "b", "2"))
+
+	// Touch "a" so that "b" becomes the least recently used entry.
+	_, err := cache.GetValue(context.Background(), "a")
+	assert.NoError(t, err)
+
+	assert.NoError(t, cache.SetValue(context.Background(), "c", "3"))
+
+	_, err = cache.GetValue(context.Background(), "b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	_, err = cache.GetValue(context.Background(), "a")
+	assert.NoError(t, err)
+	_, err = cache.GetValue(context.Background(), "c")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestMemoryCache_EvictsByBytes(t *testing.T) {
+	// Each entry is 1 byte of key plus 4 bytes of value.
+	cache := newTestMemoryCache(0, 10, 1)
+
+	assert.NoError(t, cache.SetValue(context.Background(), "a", "aaaa"))
+	assert.NoError(t, cache.SetValue(context.Background(), "b", "bbbb"))
+	assert.NoError(t, cache.SetValue(context.Background(), "c", "cccc"))
+
+	_, err := cache.GetValue(context.Background(), "a")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Equal(t, 2, cache.Len())
+
+	// Overwriting a key accounts for the size difference.
+	assert.NoError(t, cache.SetValue(context.Background(), "c", "c"))
+	assert.NoError(t, cache.SetValue(context.Background(), "d", "dd"))
+	assert.Equal(t, 3, cache.Len())
+}
+
+func TestMemoryCache_ReportsEvictions(t *testing.T) {
+	cache := newTestMemoryCache(1, 0, 1)
+	var evicted []string
+	cache.OnEvict(func(key string) { evicted = append(evicted, key) })
+
+	assert.NoError(t, cache.SetValue(context.Background(), "a", "1"))
+	assert.NoError(t, cache.SetValue(context.Background(), "b", "2"))
+	// Deleting is not an eviction.
+	assert.NoError(t, cache.Delete(context.Background(), "b"))
+
+	assert.Equal(t, []string{"a"}, evicted)
+	stats, err := cache.BackendStats(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, BackendStats{Entries: 0, Evictions: 1}, stats)
+}
+
+func TestMemoryCache_ValueTooLarge(t *testing.T) {
+	cache := newTestMemoryCache(0, 8, 1)
+
+	err := cache.SetValue(context.Background(), "key", strings.Repeat("x", 16))
+
+	assert.ErrorIs(t, err, ErrValueTooLarge)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestMemoryCache_ConcurrentAccess(t *testing.T) {
+	cache := newTestMemoryCache(100, 0, 8)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key-%d", (i+j)%200)
+				_ = cache.SetValue(context.Background(), key, fmt.Sprintf("value-%d", j))
+				_, _ = cache.GetValue(context.Background(), key)
+				if j%10 == 0 {
+					_ = cache.Delete(context.Background(), key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// 8 shards with a rounded-up share of 13 entries each.
+	assert.LessOrEqual(t, cache.Len(), 104)
+}
+
+func TestNewCache_SelectsBackend(t *testing.T) {
+	memory, err := NewCache(&config.CacheConfig{Backend: config.CacheBackendMemory, Shards: 2}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryCache{}, memory)
+
+	_, err = NewCache(&config.CacheConfig{Backend: config.CacheBackendRedis}, &config.RedisConfig{Mode: config.RedisModeSentinel})
+	assert.Error(t, err, "Expected sentinel mode without a master name to be rejected")
+}
+
+func TestNewTTLPolicy(t *testing.T) {
+	policy := NewTTLPolicy(&config.CacheConfig{
+		MovieTTL:     time.Hour,
+		MovieSoftTTL: 10 * time.Minute,
+		DiscoverTTL:  time.Minute,
+		SearchTTL:    30 * time.Second,
+		NotFoundTTL:  time.Second,
+	})
+
+	assert.Equal(t, TTLPolicy{Movie: time.Hour, MovieSoft: 10 * time.Minute, Discover: time.Minute, Search: 30 * time.Second, NotFound: time.Second}, policy)
+}
+
+func TestTTLPolicy_IsMovieStale(t *testing.T) {
+	policy := TTLPolicy{Movie: 24 * time.Hour, MovieSoft: time.Hour}
+
+	assert.False(t, policy.IsMovieStale(&Entry{ExpiresIn: 23*time.Hour + time.Minute}), "younger than the soft TTL")
+	assert.True(t, policy.IsMovieStale(&Entry{ExpiresIn: 23 * time.Hour}), "exactly the soft TTL old")
+	assert.True(t, policy.IsMovieStale(&Entry{ExpiresIn: time.Minute}), "close to the hard TTL")
+	assert.False(t, policy.IsMovieStale(&Entry{}), "entries without expiry are never stale")
+
+	disabled := TTLPolicy{Movie: time.Hour, MovieSoft: time.Hour}
+	assert.False(t, disabled.IsMovieStale(&Entry{ExpiresIn: time.Minute}), "soft TTL not below the hard TTL")
+}
+
+func TestTTLPolicy_PartialMovieTTL(t *testing.T) {
+	policy := TTLPolicy{Movie: 24 * time.Hour, MovieSoft: time.Hour}
+
+	ttl, ok := policy.PartialMovieTTL()
+	assert.True(t, ok)
+	assert.Equal(t, 23*time.Hour, ttl)
+	assert.True(t, policy.IsMovieStale(&Entry{ExpiresIn: ttl}), "partial entries are stale when written")
+
+	_, ok = TTLPolicy{Movie: time.Hour}.PartialMovieTTL()
+	assert.False(t, ok, "soft TTL disabled")
+}
@@ -0,0 +1,331 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Operations timed by Metrics.
+const (
+	OpGet    = "get"
+	OpSet    = "set"
+	OpDelete = "delete"
+)
+
+// metricsNamespace prefixes every Prometheus metric exported by Metrics.
+const metricsNamespace = "movies_cache"
+
+// backendStatsTimeout bounds the backend round-trip made on every scrape.
+const backendStatsTimeout = time.Second
+
+// latencyBuckets are the upper bounds, in seconds, of the latency histograms.
+var latencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+var operations = []string{OpGet, OpSet, OpDelete}
+
+// BackendStats describes the contents of a cache backend.
+type BackendStats struct {
+	Entries   int64  `json:"entries"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// StatsReporter is implemented by backends that can report their size.
+type StatsReporter interface {
+	BackendStats(ctx context.Context) (BackendStats, error)
+}
+
+// OperationStats summarises the latency of one kind of cache operation.
+type OperationStats struct {
+	Count  uint64  `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+}
+
+// TierStats holds the lookups of a TieredCache that one tier answered or not.
+// An L1 miss falls through to L2, so only L2 misses are misses of the cache.
+type TierStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+// ClassStats holds the counters of one key class. Stale serves are hits served
+// past the soft TTL and are included in Hits. Tiers breaks down the lookups
+// made through a TieredCache, for the tiers that saw any.
+type ClassStats struct {
+	Hits       uint64                    `json:"hits"`
+	Misses     uint64                    `json:"misses"`
+	Stale      uint64                    `json:"stale"`
+	Errors     uint64                    `json:"errors"`
+	Evictions  uint64                    `json:"evictions"`
+	HitRatio   float64                   `json:"hit_ratio"`
+	Tiers      map[Tier]TierStats        `json:"tiers,omitempty"`
+	Operations map[string]OperationStats `json:"operations"`
+}
+
+// Stats is a snapshot of Metrics.
+type Stats struct {
+	Backend      *BackendStats         `json:"backend,omitempty"`
+	BackendError string                `json:"backend_error,omitempty"`
+	Classes      map[string]ClassStats `json:"classes"`
+}
+
+// Metrics records cache activity per key class. It is safe for concurrent use
+// and implements prometheus.Collector.
+type Metrics struct {
+	mu      sync.RWMutex
+	classes map[string]*classMetrics
+	backend StatsReporter
+
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	stale      *prometheus.Desc
+	errors     *prometheus.Desc
+	evictions  *prometheus.Desc
+	latency    *prometheus.Desc
+	entries    *prometheus.Desc
+	backendEvs *prometheus.Desc
+	tierHits   *prometheus.Desc
+	tierMisses *prometheus.Desc
+}
+
+type classMetrics struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	stale     atomic.Uint64
+	errors    atomic.Uint64
+	evictions atomic.Uint64
+	// latency is keyed by operation and tiers by tier; neither is modified
+	// after creation.
+	latency map[string]*latencyHistogram
+	tiers   map[Tier]*tierCounters
+}
+
+type tierCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+var tiers = []Tier{TierL1, TierL2}
+
+type latencyHistogram struct {
+	// buckets holds non-cumulative counts; the last one is the +Inf bucket.
+	buckets  []atomic.Uint64
+	count    atomic.Uint64
+	sumNanos atomic.Uint64
+}
+
+// NewMetrics creates an empty Metrics.
+func NewMetrics() *Metrics {
+	classLabels := []string{"class"}
+	return &Metrics{
+		classes:    make(map[string]*classMetrics),
+		hits:       prometheus.NewDesc(metricsNamespace+"_hits_total", "Cache lookups that found a value, including stale ones.", classLabels, nil),
+		misses:     prometheus.NewDesc(metricsNamespace+"_misses_total", "Cache lookups that found no value.", classLabels, nil),
+		stale:      prometheus.NewDesc(metricsNamespace+"_stale_total", "Values served past their soft TTL.", classLabels, nil),
+		errors:     prometheus.NewDesc(metricsNamespace+"_errors_total", "Cache operations that failed.", classLabels, nil),
+		evictions:  prometheus.NewDesc(metricsNamespace+"_evictions_total", "Entries evicted by the in-memory backend to stay within its budget.", classLabels, nil),
+		latency:    prometheus.NewDesc(metricsNamespace+"_operation_duration_seconds", "Latency of cache backend operations.", []string{"class", "op"}, nil),
+		entries:    prometheus.NewDesc(metricsNamespace+"_backend_entries", "Entries held by the cache backend.", nil, nil),
+		backendEvs: prometheus.NewDesc(metricsNamespace+"_backend_evictions_total", "Entries evicted by the cache backend.", nil, nil),
+		tierHits:   prometheus.NewDesc(metricsNamespace+"_tier_hits_total", "Tiered cache lookups answered by the tier.", []string{"class", "tier"}, nil),
+		tierMisses: prometheus.NewDesc(metricsNamespace+"_tier_misses_total", "Tiered cache lookups the tier could not answer.", []string{"class", "tier"}, nil),
+	}
+}
+
+func (m *Metrics) class(key string) *classMetrics {
+	name := KeyClassOf(key)
+	m.mu.RLock()
+	c, ok := m.classes[name]
+	m.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.classes[name]; ok {
+		return c
+	}
+	c = &classMetrics{
+		latency: make(map[string]*latencyHistogram, len(operations)),
+		tiers:   make(map[Tier]*tierCounters, len(tiers)),
+	}
+	for _, op := range operations {
+		c.latency[op] = &latencyHistogram{buckets: make([]atomic.Uint64, len(latencyBuckets)+1)}
+	}
+	for _, tier := range tiers {
+		c.tiers[tier] = &tierCounters{}
+	}
+	m.classes[name] = c
+	return c
+}
+
+// RecordHit counts a lookup of key that found a value.
+func (m *Metrics) RecordHit(key string) { m.class(key).hits.Add(1) }
+
+// RecordMiss counts a lookup of key that found no value.
+func (m *Metrics) RecordMiss(key string) { m.class(key).misses.Add(1) }
+
+// RecordStale counts a value of key served past its soft TTL.
+func (m *Metrics) RecordStale(key string) { m.class(key).stale.Add(1) }
+
+// RecordError counts a failed operation on key.
+func (m *Metrics) RecordError(key string) { m.class(key).errors.Add(1) }
+
+// RecordEviction counts the eviction of key.
+func (m *Metrics) RecordEviction(key string) { m.class(key).evictions.Add(1) }
+
+// RecordTierHit counts a tiered lookup of key answered by tier.
+func (m *Metrics) RecordTierHit(key string, tier Tier) {
+	if t, ok := m.class(key).tiers[tier]; ok {
+		t.hits.Add(1)
+	}
+}
+
+// RecordTierMiss counts a tiered lookup of key that tier could not answer.
+func (m *Metrics) RecordTierMiss(key string, tier Tier) {
+	if t, ok := m.class(key).tiers[tier]; ok {
+		t.misses.Add(1)
+	}
+}
+
+// ObserveLatency records how long an operation on key took.
+func (m *Metrics) ObserveLatency(key, op string, d time.Duration) {
+	h, ok := m.class(key).latency[op]
+	if !ok {
+		return
+	}
+	seconds := d.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && seconds > latencyBuckets[i] {
+		i++
+	}
+	h.buckets[i].Add(1)
+	h.count.Add(1)
+	h.sumNanos.Add(uint64(d.Nanoseconds()))
+}
+
+// Snapshot returns the current counters. Backend statistics are included when
+// the instrumented backend can report them.
+func (m *Metrics) Snapshot(ctx context.Context) Stats {
+	stats := Stats{Classes: make(map[string]ClassStats)}
+	m.mu.RLock()
+	backend := m.backend
+	for name, c := range m.classes {
+		stats.Classes[name] = c.snapshot()
+	}
+	m.mu.RUnlock()
+
+	if backend != nil {
+		b, err := backend.BackendStats(ctx)
+		if err != nil {
+			stats.BackendError = err.Error()
+		} else {
+			stats.Backend = &b
+		}
+	}
+	return stats
+}
+
+func (c *classMetrics) snapshot() ClassStats {
+	s := ClassStats{
+		Hits:       c.hits.Load(),
+		Misses:     c.misses.Load(),
+		Stale:      c.stale.Load(),
+		Errors:     c.errors.Load(),
+		Evictions:  c.evictions.Load(),
+		Operations: make(map[string]OperationStats, len(c.latency)),
+	}
+	if lookups := s.Hits + s.Misses; lookups > 0 {
+		s.HitRatio = float64(s.Hits) / float64(lookups)
+	}
+	for tier, t := range c.tiers {
+		stats := TierStats{Hits: t.hits.Load(), Misses: t.misses.Load()}
+		lookups := stats.Hits + stats.Misses
+		if lookups == 0 {
+			continue
+		}
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+		if s.Tiers == nil {
+			s.Tiers = make(map[Tier]TierStats, len(c.tiers))
+		}
+		s.Tiers[tier] = stats
+	}
+	for op, h := range c.latency {
+		count := h.count.Load()
+		stats := OperationStats{Count: count}
+		if count > 0 {
+			stats.MeanMs = float64(h.sumNanos.Load()) / float64(count) / float64(time.Millisecond)
+		}
+		s.Operations[op] = stats
+	}
+	return s
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{m.hits, m.misses, m.stale, m.errors, m.evictions, m.latency, m.entries, m.backendEvs, m.tierHits, m.tierMisses} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.mu.RLock()
+	backend := m.backend
+	for name, c := range m.classes {
+		ch <- prometheus.MustNewConstMetric(m.hits, prometheus.CounterValue, float64(c.hits.Load()), name)
+		ch <- prometheus.MustNewConstMetric(m.misses, prometheus.CounterValue, float64(c.misses.Load()), name)
+		ch <- prometheus.MustNewConstMetric(m.stale, prometheus.CounterValue, float64(c.stale.Load()), name)
+		ch <- prometheus.MustNewConstMetric(m.errors, prometheus.CounterValue, float64(c.errors.Load()), name)
+		ch <- prometheus.MustNewConstMetric(m.evictions, prometheus.CounterValue, float64(c.evictions.Load()), name)
+		for op, h := range c.latency {
+			ch <- h.metric(m.latency, name, op)
+		}
+		for tier, t := range c.tiers {
+			hits, misses := t.hits.Load(), t.misses.Load()
+			if hits+misses == 0 {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(m.tierHits, prometheus.CounterValue, float64(hits), name, string(tier))
+			ch <- prometheus.MustNewConstMetric(m.tierMisses, prometheus.CounterValue, float64(misses), name, string(tier))
+		}
+	}
+	m.mu.RUnlock()
+
+	if backend == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backendStatsTimeout)
+	defer cancel()
+	b, err := backend.BackendStats(ctx)
+	if err != nil {
+		if !errors.Is(err, ErrCacheUnavailable) {
+			ch <- prometheus.NewInvalidMetric(m.entries, err)
+		}
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(m.entries, prometheus.GaugeValue, float64(b.Entries))
+	ch <- prometheus.MustNewConstMetric(m.backendEvs, prometheus.CounterValue, float64(b.Evictions))
+}
+
+func (h *latencyHistogram) metric(desc *prometheus.Desc, class, op string) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(latencyBuckets))
+	var cumulative uint64
+	for i, upper := range latencyBuckets {
+		cumulative += h.buckets[i].Load()
+		buckets[upper] = cumulative
+	}
+	// count is read last so that it is never below the bucket totals.
+	count := h.count.Load()
+	if count < cumulative {
+		count = cumulative
+	}
+	sum := time.Duration(h.sumNanos.Load()).Seconds()
+	return prometheus.MustNewConstHistogram(desc, count, sum, buckets, class, op)
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_Snapshot(t *testing.T) {
+	m := NewMetrics()
+	movie := MovieKey("573435", DefaultLanguage)
+
+	m.RecordHit(movie)
+	m.RecordHit(movie)
+	m.RecordStale(movie)
+	m.RecordMiss(movie)
+	m.RecordError(movie)
+	m.RecordEviction("unrelated")
+	m.ObserveLatency(movie, OpGet, 2*time.Millisecond)
+	m.ObserveLatency(movie, OpGet, 4*time.Millisecond)
+	m.ObserveLatency(movie, "unknown", time.Second)
+
+	stats := m.Snapshot(context.Background())
+
+	assert.Nil(t, stats.Backend)
+	assert.Equal(t, ClassStats{
+		Hits:     2,
+		Misses:   1,
+		Stale:    1,
+		Errors:   1,
+		HitRatio: 2.0 / 3.0,
+		Operations: map[string]OperationStats{
+			OpGet:    {Count: 2, MeanMs: 3},
+			OpSet:    {},
+			OpDelete: {},
+		},
+	}, stats.Classes[KeyClassMovie])
+	assert.Equal(t, uint64(1), stats.Classes[KeyClassOther].Evictions)
+}
+
+func TestMetrics_Collect(t *testing.T) {
+	m := NewMetrics()
+	backend := newTestMemoryCache(10, 0, 1)
+	NewInstrumentedCache(backend, m)
+	assert.NoError(t, backend.SetValue(context.Background(), "a", "1"))
+
+	m.RecordHit(MovieKey("1", DefaultLanguage))
+	m.RecordMiss(DiscoverKey(DefaultLanguage, nil))
+	m.ObserveLatency(MovieKey("1", DefaultLanguage), OpGet, 3*time.Millisecond)
+
+	expected := `
+# HELP movies_cache_backend_entries Entries held by the cache backend.
+# TYPE movies_cache_backend_entries gauge
+movies_cache_backend_entries 1
+# HELP movies_cache_hits_total Cache lookups that found a value, including stale ones.
+# TYPE movies_cache_hits_total counter
+movies_cache_hits_total{class="discover"} 0
+movies_cache_hits_total{class="movie"} 1
+# HELP movies_cache_misses_total Cache lookups that found no value.
+# TYPE movies_cache_misses_total counter
+movies_cache_misses_total{class="discover"} 1
+movies_cache_misses_total{class="movie"} 0
+`
+	err := testutil.CollectAndCompare(m, strings.NewReader(expected),
+		"movies_cache_backend_entries", "movies_cache_hits_total", "movies_cache_misses_total")
+	assert.NoError(t, err)
+
+	// Every class exports each operation's histogram.
+	assert.Equal(t, 6, testutil.CollectAndCount(m, "movies_cache_operation_duration_seconds"))
+}
+
+func TestMetrics_Tiers(t *testing.T) {
+	m := NewMetrics()
+	movie := MovieKey("573435", DefaultLanguage)
+
+	m.RecordTierHit(movie, TierL1)
+	m.RecordTierMiss(movie, TierL1)
+	m.RecordTierMiss(movie, TierL1)
+	m.RecordTierHit(movie, TierL2)
+	m.RecordTierMiss(movie, TierL2)
+	m.RecordHit(MovieTombstoneKey("1", DefaultLanguage))
+
+	stats := m.Snapshot(context.Background())
+
+	assert.Equal(t, map[Tier]TierStats{
+		TierL1: {Hits: 1, Misses: 2, HitRatio: 1.0 / 3.0},
+		TierL2: {Hits: 1, Misses: 1, HitRatio: 0.5},
+	}, stats.Classes[KeyClassMovie].Tiers)
+	assert.Nil(t, stats.Classes[KeyClassTombstone].Tiers, "Expected no tiers for classes never looked up through a tiered cache")
+
+	expected := `
+# HELP movies_cache_tier_hits_total Tiered cache lookups answered by the tier.
+# TYPE movies_cache_tier_hits_total counter
+movies_cache_tier_hits_total{class="movie",tier="l1"} 1
+movies_cache_tier_hits_total{class="movie",tier="l2"} 1
+# HELP movies_cache_tier_misses_total Tiered cache lookups the tier could not answer.
+# TYPE movies_cache_tier_misses_total counter
+movies_cache_tier_misses_total{class="movie",tier="l1"} 2
+movies_cache_tier_misses_total{class="movie",tier="l2"} 1
+`
+	err := testutil.CollectAndCompare(m, strings.NewReader(expected),
+		"movies_cache_tier_hits_total", "movies_cache_tier_misses_total")
+	assert.NoError(t, err)
+}
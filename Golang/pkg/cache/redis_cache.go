@@ -2,63 +2,480 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 
 	"github.com/elberthcabrales/movies-api/pkg/config"
 )
 
-var ctx = context.Background()
+// Delays used by the connection monitor of a RedisCache.
+const (
+	healthCheckInterval = 5 * time.Second
+	minReconnectDelay   = 100 * time.Millisecond
+	maxReconnectDelay   = 30 * time.Second
+)
+
+// ErrCacheUnavailable is returned while the cache backend cannot be reached.
+var ErrCacheUnavailable = errors.New("cache: backend unavailable")
 
 // RedisCache is a wrapper around the Redis client providing caching functionality.
 type RedisCache struct {
-	client *redis.Client
+	client redis.UniversalClient
+
+	// down is set while Redis cannot be reached. Commands then fail fast with
+	// ErrCacheUnavailable instead of waiting on connection timeouts.
+	down atomic.Bool
+	// wake asks the connection monitor to probe Redis right away. It is nil
+	// when no monitor runs, i.e. when the client was provided by the caller.
+	wake      chan struct{}
+	stop      chan struct{}
+	closeOnce sync.Once
 }
 
 // NewRedisCache creates a new RedisCache using the provided Redis client and configuration.
-func NewRedisCache(client *redis.Client, cfg *config.RedisConfig) *RedisCache {
-	if cfg != nil {
-		client = redis.NewClient(&redis.Options{
-			Addr:     cfg.Addr,
-			Password: cfg.Password,
-			DB:       cfg.DB,
-		})
+// When a configuration is given, the cache connects on its own and keeps running
+// in degraded mode while Redis is unreachable, reconnecting in the background.
+// It fails if the configuration is invalid, e.g. sentinel mode without a master name.
+func NewRedisCache(client redis.UniversalClient, cfg *config.RedisConfig) (*RedisCache, error) {
+	if cfg == nil {
+		return &RedisCache{client: client}, nil
+	}
+	client, err := NewRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newMonitoredRedisCache(client), nil
+}
+
+// newMonitoredRedisCache creates a RedisCache that watches the connection of client.
+func newMonitoredRedisCache(client redis.UniversalClient) *RedisCache {
+	r := &RedisCache{
+		client: client,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+
+	log.Println("Pinging Redis...")
+	if err := r.client.Ping(context.Background()).Err(); err != nil {
+		log.Printf("Failed to connect to Redis, starting in degraded mode: %v", err)
+		r.down.Store(true)
+	} else {
+		log.Println("Successfully connected to Redis")
+	}
+	go r.monitor()
+	return r
+}
+
+// Healthy reports whether Redis was reachable at the last check.
+func (r *RedisCache) Healthy() bool {
+	return !r.down.Load()
+}
+
+// Close stops the connection monitor and closes the client.
+func (r *RedisCache) Close() error {
+	r.closeOnce.Do(func() {
+		if r.stop != nil {
+			close(r.stop)
+		}
+	})
+	return r.client.Close()
+}
+
+// monitor pings Redis every healthCheckInterval while it is reachable, and with
+// exponential backoff while it is not, moving the cache in and out of degraded mode.
+func (r *RedisCache) monitor() {
+	delay := minReconnectDelay
+	for {
+		wait := healthCheckInterval
+		if r.down.Load() {
+			wait = delay
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-r.stop:
+			timer.Stop()
+			return
+		case <-r.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+
+		err := r.client.Ping(context.Background()).Err()
+		if err == nil {
+			if r.down.Swap(false) {
+				log.Println("Reconnected to Redis, leaving degraded mode")
+			}
+			delay = minReconnectDelay
+			continue
+		}
+		if !r.down.Swap(true) {
+			log.Printf("Lost connection to Redis, entering degraded mode: %v", err)
+			continue
+		}
+		log.Printf("Redis still unreachable, retrying in %s: %v", delay, err)
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// BackendStats reports the number of keys in the Redis database, including keys
+// written by other applications, and the keys Redis evicted under memory pressure.
+func (r *RedisCache) BackendStats(ctx context.Context) (BackendStats, error) {
+	if err := r.available(); err != nil {
+		return BackendStats{}, err
+	}
+	entries, err := r.client.DBSize(ctx).Result()
+	if err != nil {
+		r.fail(ctx, err)
+		return BackendStats{}, err
+	}
+	evictions, err := r.evictedKeys(ctx)
+	if err != nil {
+		r.fail(ctx, err)
+		return BackendStats{}, err
+	}
+	return BackendStats{Entries: entries, Evictions: evictions}, nil
+}
+
+// evictedKeys returns the evicted_keys counter of the server, summed over the
+// masters of a cluster.
+func (r *RedisCache) evictedKeys(ctx context.Context) (uint64, error) {
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		info, err := r.client.Info(ctx, "stats").Result()
+		return parseEvictedKeys(info), err
+	}
+	var evictions atomic.Uint64
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		info, err := node.Info(ctx, "stats").Result()
+		evictions.Add(parseEvictedKeys(info))
+		return err
+	})
+	return evictions.Load(), err
+}
 
-		log.Println("Pinging Redis...")
-		_, err := client.Ping(ctx).Result()
+// parseEvictedKeys extracts the evicted_keys counter from the output of INFO stats.
+func parseEvictedKeys(info string) uint64 {
+	for _, line := range strings.Split(info, "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "evicted_keys:")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 64)
 		if err != nil {
-			log.Fatalf("Failed to connect to Redis: %v", err)
+			return 0
 		}
-		log.Println("Successfully connected to Redis")
+		return n
 	}
+	return 0
+}
 
-	return &RedisCache{
-		client: client,
+// available returns ErrCacheUnavailable while the cache is in degraded mode.
+func (r *RedisCache) available() error {
+	if r.down.Load() {
+		return ErrCacheUnavailable
+	}
+	return nil
+}
+
+// fail records a failed command. Misses, errors replied by Redis itself and
+// commands abandoned because ctx ended say nothing about the connection;
+// anything else puts the cache in degraded mode until the monitor reaches
+// Redis again.
+func (r *RedisCache) fail(ctx context.Context, err error) {
+	var replyErr redis.Error
+	if r.wake == nil || ctx.Err() != nil || errors.Is(err, redis.Nil) || errors.As(err, &replyErr) {
+		return
+	}
+	if !r.down.Swap(true) {
+		log.Printf("Redis command failed, entering degraded mode: %v", err)
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
 	}
 }
 
 // SetValue sets a key-value pair in the Redis cache.
-func (r *RedisCache) SetValue(key string, value interface{}) error {
-	log.Printf("Setting value in Redis for key: %s", key)
-	err := r.client.Set(ctx, key, value, 0).Err()
+func (r *RedisCache) SetValue(ctx context.Context, key string, value interface{}) error {
+	return r.SetValueWithTTL(ctx, key, value, 0)
+}
+
+// SetValueWithTTL sets a key-value pair in the Redis cache that expires after ttl.
+func (r *RedisCache) SetValueWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := r.available(); err != nil {
+		return err
+	}
+	log.Printf("Setting value in Redis for key: %s (ttl %s)", key, ttl)
+	err := r.client.Set(ctx, key, value, ttl).Err()
 	if err != nil {
 		log.Printf("Failed to set value for key %s: %v", key, err)
+		r.fail(ctx, err)
 		return err
 	}
 	log.Printf("Value set successfully for key: %s", key)
 	return nil
 }
 
+// SetValueIfAbsent sets a key-value pair in the Redis cache that expires after
+// ttl, unless the key already exists.
+func (r *RedisCache) SetValueIfAbsent(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if err := r.available(); err != nil {
+		return false, err
+	}
+	log.Printf("Adding value in Redis for key: %s (ttl %s)", key, ttl)
+	added, err := r.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		log.Printf("Failed to add value for key %s: %v", key, err)
+		r.fail(ctx, err)
+		return false, err
+	}
+	return added, nil
+}
+
 // GetValue retrieves the value associated with the key from the Redis cache.
-func (r *RedisCache) GetValue(key string) (string, error) {
+func (r *RedisCache) GetValue(ctx context.Context, key string) (string, error) {
+	if err := r.available(); err != nil {
+		return "", err
+	}
 	log.Printf("Getting value from Redis for key: %s", key)
 	val, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		log.Printf("Key %s not found in Redis", key)
+		return "", ErrCacheMiss
+	}
 	if err != nil {
 		log.Printf("Failed to get value for key %s: %v", key, err)
+		r.fail(ctx, err)
 		return "", err
 	}
 	log.Printf("Successfully retrieved value for key: %s", key)
 
 	return val, nil
 }
+
+// Delete removes the key from the Redis cache.
+func (r *RedisCache) Delete(ctx context.Context, key string) error {
+	if err := r.available(); err != nil {
+		return err
+	}
+	log.Printf("Deleting key from Redis: %s", key)
+	err := r.client.Del(ctx, key).Err()
+	if err != nil {
+		log.Printf("Failed to delete key %s: %v", key, err)
+		r.fail(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// SetField sets a field of a hash in the Redis cache.
+func (r *RedisCache) SetField(ctx context.Context, key, field string, value interface{}) error {
+	if err := r.available(); err != nil {
+		return err
+	}
+	log.Printf("Setting field %s in Redis for key: %s", field, key)
+	if err := r.client.HSet(ctx, key, field, value).Err(); err != nil {
+		log.Printf("Failed to set field %s for key %s: %v", field, key, err)
+		r.fail(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// GetField retrieves a field of a hash from the Redis cache.
+func (r *RedisCache) GetField(ctx context.Context, key, field string) (string, error) {
+	if err := r.available(); err != nil {
+		return "", err
+	}
+	val, err := r.client.HGet(ctx, key, field).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrCacheMiss
+	}
+	if err != nil {
+		log.Printf("Failed to get field %s for key %s: %v", field, key, err)
+		r.fail(ctx, err)
+		return "", err
+	}
+	return val, nil
+}
+
+// GetFields retrieves every field of a hash from the Redis cache.
+func (r *RedisCache) GetFields(ctx context.Context, key string) (map[string]string, error) {
+	if err := r.available(); err != nil {
+		return nil, err
+	}
+	fields, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		log.Printf("Failed to get fields for key %s: %v", key, err)
+		r.fail(ctx, err)
+		return nil, err
+	}
+	return fields, nil
+}
+
+// DeleteField removes a field of a hash from the Redis cache.
+func (r *RedisCache) DeleteField(ctx context.Context, key, field string) error {
+	if err := r.available(); err != nil {
+		return err
+	}
+	log.Printf("Deleting field %s from Redis for key: %s", field, key)
+	if err := r.client.HDel(ctx, key, field).Err(); err != nil {
+		log.Printf("Failed to delete field %s for key %s: %v", field, key, err)
+		r.fail(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// scanBatchSize is the number of keys requested per SCAN call while purging.
+const scanBatchSize = 500
+
+// DeleteMatching removes every key matching pattern. Keys are found with SCAN,
+// so the server is never blocked, and deleted batch by batch. Keys written while
+// the scan runs may be missed.
+func (r *RedisCache) DeleteMatching(ctx context.Context, pattern string) (int, error) {
+	if err := validatePattern(pattern); err != nil {
+		return 0, err
+	}
+	if err := r.available(); err != nil {
+		return 0, err
+	}
+	log.Printf("Deleting keys matching %s from Redis", pattern)
+	deleted := 0
+	err := r.scan(ctx, pattern, func(keys []string) error {
+		n, err := r.deleteKeys(ctx, keys)
+		deleted += n
+		if err != nil {
+			log.Printf("Failed to delete keys matching %s: %v", pattern, err)
+			r.fail(ctx, err)
+		}
+		return err
+	})
+	return deleted, err
+}
+
+// deleteKeys removes keys. A cluster only deletes several keys at once when
+// they share a hash slot, so there each key is deleted on its own, in a
+// single pipeline.
+func (r *RedisCache) deleteKeys(ctx context.Context, keys []string) (int, error) {
+	if _, ok := r.client.(*redis.ClusterClient); !ok {
+		n, err := r.client.Del(ctx, keys...).Result()
+		return int(n), err
+	}
+	cmds, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	deleted := 0
+	for _, cmd := range cmds {
+		deleted += int(cmd.(*redis.IntCmd).Val())
+	}
+	return deleted, err
+}
+
+// ScanKeys calls fn for every key matching pattern, fetching them with SCAN
+// one batch at a time.
+func (r *RedisCache) ScanKeys(ctx context.Context, pattern string, fn func(key string) error) error {
+	if err := validatePattern(pattern); err != nil {
+		return err
+	}
+	if err := r.available(); err != nil {
+		return err
+	}
+	return r.scan(ctx, pattern, func(keys []string) error {
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// scan calls fn with every batch of keys matching pattern. SCAN only covers
+// the node it runs on, so a cluster is scanned master by master; fn is never
+// called concurrently.
+func (r *RedisCache) scan(ctx context.Context, pattern string, fn func(keys []string) error) error {
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		return r.scanNode(ctx, r.client, pattern, fn)
+	}
+	var mu sync.Mutex
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return r.scanNode(ctx, node, pattern, func(keys []string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return fn(keys)
+		})
+	})
+}
+
+func (r *RedisCache) scanNode(ctx context.Context, node redis.Cmdable, pattern string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := node.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			log.Printf("Failed to scan keys matching %s: %v", pattern, err)
+			r.fail(ctx, err)
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// GetEntry retrieves the value associated with the key together with its remaining TTL.
+func (r *RedisCache) GetEntry(ctx context.Context, key string) (*Entry, error) {
+	if err := r.available(); err != nil {
+		return nil, err
+	}
+	log.Printf("Getting entry from Redis for key: %s", key)
+	pipe := r.client.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	// Errors are reported per command and inspected below.
+	_, _ = pipe.Exec(ctx)
+
+	val, err := get.Result()
+	if errors.Is(err, redis.Nil) {
+		log.Printf("Key %s not found in Redis", key)
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		log.Printf("Failed to get entry for key %s: %v", key, err)
+		r.fail(ctx, err)
+		return nil, err
+	}
+	ttl, err := pttl.Result()
+	if err != nil {
+		log.Printf("Failed to get TTL for key %s: %v", key, err)
+		r.fail(ctx, err)
+		return nil, err
+	}
+
+	entry := &Entry{Value: val}
+	// PTTL reports -1 for keys without expiry.
+	if ttl > 0 {
+		entry.ExpiresIn = ttl
+	}
+	return entry, nil
+}
@@ -1,8 +1,12 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redismock/v8"
 	"github.com/stretchr/testify/assert"
 
@@ -15,7 +19,7 @@ func TestSetValue(t *testing.T) {
 	mock.ExpectSet("example_key", "example_value", 0).SetVal("OK")
 
 	repo := &RedisCache{client: db}
-	err := repo.SetValue("example_key", "example_value")
+	err := repo.SetValue(context.Background(), "example_key", "example_value")
 
 	assert.NoError(t, err)
 
@@ -24,13 +28,48 @@ func TestSetValue(t *testing.T) {
 	}
 }
 
+func TestSetValueWithTTL(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectSet("example_key", "example_value", time.Hour).SetVal("OK")
+
+	repo := &RedisCache{client: db}
+	err := repo.SetValueWithTTL(context.Background(), "example_key", "example_value", time.Hour)
+
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSetValueIfAbsent(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectSetNX("example_key", "example_value", time.Hour).SetVal(true)
+	mock.ExpectSetNX("example_key", "other_value", time.Hour).SetVal(false)
+
+	repo := &RedisCache{client: db}
+	added, err := repo.SetValueIfAbsent(context.Background(), "example_key", "example_value", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = repo.SetValueIfAbsent(context.Background(), "example_key", "other_value", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, added)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestGetValue(t *testing.T) {
 	db, mock := redismock.NewClientMock()
 
 	mock.ExpectGet("example_key").SetVal("example_value")
 
 	repo := &RedisCache{client: db}
-	value, err := repo.GetValue("example_key")
+	value, err := repo.GetValue(context.Background(), "example_key")
 
 	assert.NoError(t, err)
 	assert.Equal(t, "example_value", value)
@@ -46,9 +85,9 @@ func TestGetValue_NotFound(t *testing.T) {
 	mock.ExpectGet("missing_key").RedisNil()
 
 	repo := &RedisCache{client: db}
-	value, err := repo.GetValue("missing_key")
+	value, err := repo.GetValue(context.Background(), "missing_key")
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrCacheMiss)
 	assert.Equal(t, "", value)
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -56,14 +95,60 @@ func TestGetValue_NotFound(t *testing.T) {
 	}
 }
 
+func TestGetEntry(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectGet("example_key").SetVal("example_value")
+	mock.ExpectPTTL("example_key").SetVal(time.Minute)
+
+	repo := &RedisCache{client: db}
+	entry, err := repo.GetEntry(context.Background(), "example_key")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Entry{Value: "example_value", ExpiresIn: time.Minute}, entry)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetEntry_NoExpiry(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectGet("example_key").SetVal("example_value")
+	mock.ExpectPTTL("example_key").SetVal(-1)
+
+	repo := &RedisCache{client: db}
+	entry, err := repo.GetEntry(context.Background(), "example_key")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Entry{Value: "example_value"}, entry)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGetEntry_NotFound(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectGet("missing_key").RedisNil()
+
+	repo := &RedisCache{client: db}
+	entry, err := repo.GetEntry(context.Background(), "missing_key")
+
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Nil(t, entry)
+}
+
 func TestNewRedisCacheWithOutConfig(t *testing.T) {
 	db, mock := redismock.NewClientMock()
 
 	mock.ExpectPing().SetVal("PONG")
 
-	cache := NewRedisCache(db, nil)
+	cache, _ := NewRedisCache(db, nil)
 
-	_, err := cache.client.Ping(ctx).Result()
+	_, err := cache.client.Ping(context.Background()).Result()
 
 	assert.NoError(t, err)
 	assert.NotNil(t, cache)
@@ -74,15 +159,232 @@ func TestNewRedisCacheWithOutConfig(t *testing.T) {
 	}
 }
 
+// newConfiguredRedisCache creates a RedisCache that connects on its own.
+func newConfiguredRedisCache(t *testing.T, cfg *config.RedisConfig) *RedisCache {
+	cache, err := NewRedisCache(nil, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create Redis cache: %v", err)
+	}
+	return cache
+}
+
+func TestNewRedisCache_InvalidConfig(t *testing.T) {
+	cache, err := NewRedisCache(nil, &config.RedisConfig{Mode: config.RedisModeSentinel, Addrs: []string{"localhost:26379"}})
+
+	assert.Error(t, err)
+	assert.Nil(t, cache)
+}
+
 func TestNewRedisCacheWithConfig(t *testing.T) {
-	cache := NewRedisCache(nil, &config.RedisConfig{
-		Addr:     "localhost:6379",
+	server := miniredis.RunT(t)
+
+	cache := newConfiguredRedisCache(t, &config.RedisConfig{
+		Addr:     server.Addr(),
 		Password: "",
 		DB:       0,
 	})
+	defer cache.Close()
 
-	_, err := cache.client.Ping(ctx).Result()
+	_, err := cache.client.Ping(context.Background()).Result()
 
 	assert.NoError(t, err)
 	assert.NotNil(t, cache)
+	assert.True(t, cache.Healthy())
+}
+
+func TestNewRedisCache_StartsDegradedAndReconnects(t *testing.T) {
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+
+	cache := newConfiguredRedisCache(t, &config.RedisConfig{Addr: addr})
+	defer cache.Close()
+
+	assert.False(t, cache.Healthy())
+	_, err := cache.GetValue(context.Background(), "example_key")
+	assert.ErrorIs(t, err, ErrCacheUnavailable)
+	assert.ErrorIs(t, cache.SetValue(context.Background(), "example_key", "example_value"), ErrCacheUnavailable)
+
+	assert.NoError(t, server.Restart())
+	assert.Eventually(t, cache.Healthy, 5*time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, cache.SetValue(context.Background(), "example_key", "example_value"))
+	value, err := cache.GetValue(context.Background(), "example_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "example_value", value)
+}
+
+func TestRedisCache_FailedCommandEntersDegradedMode(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	cache := newConfiguredRedisCache(t, &config.RedisConfig{Addr: server.Addr()})
+	defer cache.Close()
+	assert.True(t, cache.Healthy())
+
+	// Errors replied by Redis say nothing about the connection.
+	server.SetError("ERR busy")
+	_, err := cache.GetValue(context.Background(), "example_key")
+	assert.Error(t, err)
+	assert.True(t, cache.Healthy())
+	server.SetError("")
+
+	server.Close()
+	_, err = cache.GetValue(context.Background(), "example_key")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrCacheMiss))
+	assert.False(t, cache.Healthy())
+
+	assert.NoError(t, server.Restart())
+	assert.Eventually(t, cache.Healthy, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRedisCache_CancelledContext(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	cache := newConfiguredRedisCache(t, &config.RedisConfig{Addr: server.Addr()})
+	defer cache.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cache.GetValue(ctx, "example_key")
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, cache.Healthy(), "Expected an abandoned command not to enter degraded mode")
+}
+
+func TestRedisCache_InjectedClientStaysHealthy(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectGet("example_key").SetErr(errors.New("connection refused"))
+
+	cache, _ := NewRedisCache(db, nil)
+	_, err := cache.GetValue(context.Background(), "example_key")
+
+	assert.Error(t, err)
+	assert.True(t, cache.Healthy(), "Expected no monitor, and so no degraded mode, for injected clients")
+}
+
+func TestDelete(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectDel("example_key").SetVal(1)
+
+	repo := &RedisCache{client: db}
+	err := repo.Delete(context.Background(), "example_key")
+
+	assert.NoError(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRedisCache_Fields(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectHSet("example_hash", "1", "one").SetVal(1)
+	mock.ExpectHGet("example_hash", "1").SetVal("one")
+	mock.ExpectHGet("example_hash", "2").RedisNil()
+	mock.ExpectHGetAll("example_hash").SetVal(map[string]string{"1": "one"})
+	mock.ExpectHDel("example_hash", "1").SetVal(1)
+
+	repo := &RedisCache{client: db}
+	assert.NoError(t, repo.SetField(context.Background(), "example_hash", "1", "one"))
+	value, err := repo.GetField(context.Background(), "example_hash", "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "one", value)
+	_, err = repo.GetField(context.Background(), "example_hash", "2")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	fields, err := repo.GetFields(context.Background(), "example_hash")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"1": "one"}, fields)
+	assert.NoError(t, repo.DeleteField(context.Background(), "example_hash", "1"))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRedisCache_BackendStats(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectDBSize().SetVal(42)
+	mock.ExpectInfo("stats").SetVal("# Stats\r\nexpired_keys:3\r\nevicted_keys:7\r\n")
+
+	repo := &RedisCache{client: db}
+	stats, err := repo.BackendStats(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, BackendStats{Entries: 42, Evictions: 7}, stats)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRedisCache_DeleteMatching(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectScan(0, "movies:v2:movie:*", scanBatchSize).SetVal([]string{"movies:v2:movie:1:en-US"}, 7)
+	mock.ExpectDel("movies:v2:movie:1:en-US").SetVal(1)
+	mock.ExpectScan(7, "movies:v2:movie:*", scanBatchSize).SetVal([]string{"movies:v2:movie:2:en-US", "movies:v2:movie:3:en-US"}, 0)
+	mock.ExpectDel("movies:v2:movie:2:en-US", "movies:v2:movie:3:en-US").SetVal(2)
+
+	repo := &RedisCache{client: db}
+	n, err := repo.DeleteMatching(context.Background(), "movies:v2:movie:*")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRedisCache_ScanKeys(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectScan(0, "movies:v2:movie:*", scanBatchSize).SetVal([]string{"movies:v2:movie:1:en-US"}, 3)
+	mock.ExpectScan(3, "movies:v2:movie:*", scanBatchSize).SetVal([]string{"movies:v2:movie:2:en-US"}, 0)
+
+	repo := &RedisCache{client: db}
+	var keys []string
+	err := repo.ScanKeys(context.Background(), "movies:v2:movie:*", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"movies:v2:movie:1:en-US", "movies:v2:movie:2:en-US"}, keys)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRedisCache_ClusterMode(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	cache := newConfiguredRedisCache(t, &config.RedisConfig{Mode: config.RedisModeCluster, Addrs: []string{server.Addr()}})
+	defer cache.Close()
+	assert.True(t, cache.Healthy())
+
+	// The keys hash to different slots, so a real cluster refuses to delete them with a single DEL.
+	for _, key := range []string{"movies:v2:movie:1:en-US", "movies:v2:movie:2:en-US", "movies:v2:discover:en-US:page=1"} {
+		assert.NoError(t, cache.SetValue(context.Background(), key, "{}"))
+	}
+
+	var keys []string
+	err := cache.ScanKeys(context.Background(), "movies:v2:movie:*", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"movies:v2:movie:1:en-US", "movies:v2:movie:2:en-US"}, keys)
+
+	n, err := cache.DeleteMatching(context.Background(), "movies:v2:movie:*")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	assert.Equal(t, []string{"movies:v2:discover:en-US:page=1"}, server.Keys())
 }
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+// NewRedisClient creates a client for the standalone server, sentinel-managed
+// failover group or cluster described by cfg. An unknown mode falls back to
+// standalone.
+func NewRedisClient(cfg *config.RedisConfig) (redis.UniversalClient, error) {
+	opts, err := redisOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Mode {
+	case config.RedisModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, errors.New("redis: sentinel mode needs a master name")
+		}
+		log.Printf("Using Redis sentinels %v for master %s", opts.Addrs, cfg.MasterName)
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case config.RedisModeCluster:
+		log.Printf("Using Redis cluster with seed nodes %v", opts.Addrs)
+		return redis.NewClusterClient(opts.Cluster()), nil
+	case config.RedisModeStandalone, "":
+		return redis.NewClient(opts.Simple()), nil
+	default:
+		log.Printf("Unknown Redis mode %q, falling back to standalone", cfg.Mode)
+		return redis.NewClient(opts.Simple()), nil
+	}
+}
+
+func redisOptions(cfg *config.RedisConfig) (*redis.UniversalOptions, error) {
+	addrs := cfg.Addrs
+	if len(addrs) == 0 || cfg.Mode == config.RedisModeStandalone || cfg.Mode == "" {
+		addrs = []string{cfg.Addr}
+	}
+	opts := &redis.UniversalOptions{
+		Addrs:            addrs,
+		MasterName:       cfg.MasterName,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		SentinelUsername: cfg.SentinelUsername,
+		SentinelPassword: cfg.SentinelPassword,
+		DB:               cfg.DB,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+		PoolTimeout:      cfg.PoolTimeout,
+	}
+	if cfg.TLS {
+		tlsConfig, err := redisTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+	return opts, nil
+}
+
+func redisTLSConfig(cfg *config.RedisConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecureSkipVerify,
+	}
+	if cfg.TLSCAFile == "" {
+		return tlsConfig, nil
+	}
+	pem, err := os.ReadFile(cfg.TLSCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("redis: reading CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("redis: no certificate found in %s", cfg.TLSCAFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+func TestNewRedisClient_Modes(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	standalone, err := NewRedisClient(&config.RedisConfig{Mode: config.RedisModeStandalone, Addr: server.Addr(), PoolSize: 3})
+	assert.NoError(t, err)
+	defer standalone.Close()
+	assert.IsType(t, &redis.Client{}, standalone)
+	assert.Equal(t, 3, standalone.(*redis.Client).Options().PoolSize)
+	assert.NoError(t, standalone.Ping(context.Background()).Err())
+
+	cluster, err := NewRedisClient(&config.RedisConfig{Mode: config.RedisModeCluster, Addrs: []string{server.Addr()}})
+	assert.NoError(t, err)
+	defer cluster.Close()
+	assert.IsType(t, &redis.ClusterClient{}, cluster)
+	assert.NoError(t, cluster.Ping(context.Background()).Err())
+
+	sentinel, err := NewRedisClient(&config.RedisConfig{
+		Mode:        config.RedisModeSentinel,
+		Addrs:       []string{"sentinel-1:26379", "sentinel-2:26379"},
+		MasterName:  "movies",
+		DB:          2,
+		DialTimeout: time.Second,
+	})
+	assert.NoError(t, err)
+	defer sentinel.Close()
+	options := sentinel.(*redis.Client).Options()
+	assert.Equal(t, "FailoverClient", options.Addr)
+	assert.Equal(t, 2, options.DB)
+	assert.Equal(t, time.Second, options.DialTimeout)
+
+	_, err = NewRedisClient(&config.RedisConfig{Mode: config.RedisModeSentinel, Addrs: []string{"sentinel-1:26379"}})
+	assert.Error(t, err, "Expected sentinel mode without a master name to be rejected")
+}
+
+func TestNewRedisClient_TLS(t *testing.T) {
+	client, err := NewRedisClient(&config.RedisConfig{Addr: "localhost:6379", Username: "api", TLS: true})
+	assert.NoError(t, err)
+	defer client.Close()
+	options := client.(*redis.Client).Options()
+	assert.Equal(t, "api", options.Username)
+	assert.NotNil(t, options.TLSConfig)
+	assert.Nil(t, options.TLSConfig.RootCAs, "Expected the system roots without a CA file")
+
+	_, err = NewRedisClient(&config.RedisConfig{Addr: "localhost:6379", TLS: true, TLSCAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+	_, err = NewRedisClient(&config.RedisConfig{Addr: "localhost:6379", TLS: true, TLSCAFile: notPEM})
+	assert.Error(t, err)
+}
@@ -0,0 +1,336 @@
+package cache
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SnapshotVersion is the version of the snapshot format written by ExportSnapshot.
+const SnapshotVersion = 1
+
+// ErrInvalidSnapshot is returned when a snapshot cannot be read.
+var ErrInvalidSnapshot = errors.New("cache: invalid snapshot")
+
+// ErrSnapshotTooLarge is returned when a snapshot is larger than the size an
+// import accepts.
+var ErrSnapshotTooLarge = errors.New("cache: snapshot too large")
+
+// maxSnapshotLine bounds the size of a single snapshot record.
+const maxSnapshotLine = 16 << 20
+
+// snapshotPatterns select the keys included in a snapshot: movie details,
+// discover pages and the saved movies hashes. Not-found results and legacy
+// keys are left out. Saved movies come last, after the movies they refer to.
+var snapshotPatterns = []string{
+	BuildKey(KeyClassMovie) + ":*",
+	BuildKey(KeyClassDiscover) + ":*",
+	BuildKey(KeyClassSaved) + ":*",
+}
+
+// snapshotHeader is the first line of a snapshot.
+type snapshotHeader struct {
+	Version   int       `json:"snapshot"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// snapshotRecord is one cache entry of a snapshot, or one field of a saved
+// movies hash. Values that are not valid UTF-8 are stored base64-encoded in
+// ValueBase64 instead of Value.
+type snapshotRecord struct {
+	Key         string `json:"key"`
+	Field       string `json:"field,omitempty"`
+	Value       string `json:"value,omitempty"`
+	ValueBase64 string `json:"value_base64,omitempty"`
+	// TTLMs is the lifetime the entry had left when it was exported, in
+	// milliseconds, or zero if it never expires.
+	TTLMs int64 `json:"ttl_ms,omitempty"`
+}
+
+// SnapshotResult counts the entries handled by an export or import.
+type SnapshotResult struct {
+	// Written counts entries exported, or stored by an import.
+	Written int `json:"written"`
+	// Skipped counts entries not imported because the key already held a
+	// value, and saved movies whose movie was not imported.
+	Skipped int `json:"skipped"`
+}
+
+// ExportSnapshot writes every cached movie, discover page and saved movie of
+// c to w as newline-delimited JSON, gzip-compressed if compress is set. The
+// first line is a header; each following line holds one entry with its
+// remaining TTL, or one saved movie. Entries that expire or are deleted while
+// the export runs are left out.
+func ExportSnapshot(ctx context.Context, c Cache, w io.Writer, compress bool) (SnapshotResult, error) {
+	var result SnapshotResult
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(w)
+		w = zw
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(snapshotHeader{Version: SnapshotVersion, CreatedAt: time.Now().UTC()}); err != nil {
+		return result, err
+	}
+	for _, pattern := range snapshotPatterns {
+		err := c.ScanKeys(ctx, pattern, func(key string) error {
+			if KeyClassOf(key) == KeyClassSaved {
+				written, err := exportFields(ctx, c, enc, key)
+				result.Written += written
+				return err
+			}
+			entry, err := c.GetEntry(ctx, key)
+			if errors.Is(err, ErrCacheMiss) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(newSnapshotRecord(key, entry)); err != nil {
+				return err
+			}
+			result.Written++
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return result, err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return result, err
+		}
+	}
+	log.Printf("Exported %d cache entries to snapshot", result.Written)
+	return result, nil
+}
+
+// exportFields writes one record per field of the hash stored under key,
+// sorted by field, and returns how many it wrote.
+func exportFields(ctx context.Context, c Cache, enc *json.Encoder, key string) (int, error) {
+	fields, err := c.GetFields(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		record := snapshotRecord{Key: key, Field: name}
+		record.setValue(fields[name])
+		if err := enc.Encode(record); err != nil {
+			return 0, err
+		}
+	}
+	return len(names), nil
+}
+
+func newSnapshotRecord(key string, entry *Entry) snapshotRecord {
+	record := snapshotRecord{Key: key, TTLMs: entry.ExpiresIn.Milliseconds()}
+	if entry.ExpiresIn > 0 && record.TTLMs == 0 {
+		// Rounding down would turn an entry about to expire into one that never does.
+		record.TTLMs = 1
+	}
+	record.setValue(entry.Value)
+	return record
+}
+
+func (r *snapshotRecord) setValue(value string) {
+	if utf8.ValidString(value) {
+		r.Value = value
+	} else {
+		r.ValueBase64 = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+}
+
+// ImportSnapshot loads a snapshot written by ExportSnapshot into c, whether or
+// not it is gzip-compressed. Each entry gets the TTL it had left when it was
+// exported. Keys that already hold a value are overwritten if overwrite is set
+// and skipped otherwise. A saved movie is imported only if this import stored
+// its movie; a stored movie the snapshot does not list as saved is no longer
+// saved, as when it is fetched from the upstream API. A snapshot of more than maxBytes once decompressed
+// fails with ErrSnapshotTooLarge; zero means unlimited.
+func ImportSnapshot(ctx context.Context, c Cache, r io.Reader, overwrite bool, maxBytes int64) (SnapshotResult, error) {
+	var result SnapshotResult
+	r, err := maybeGunzip(r)
+	if err != nil {
+		return result, err
+	}
+	if maxBytes > 0 {
+		r = &snapshotLimitReader{r: r, max: maxBytes, remaining: maxBytes}
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSnapshotLine)
+
+	if !scanner.Scan() || scanner.Err() != nil {
+		if err := scanner.Err(); err != nil {
+			return result, err
+		}
+		return result, fmt.Errorf("%w: empty snapshot", ErrInvalidSnapshot)
+	}
+	var header snapshotHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version == 0 {
+		return result, fmt.Errorf("%w: missing header", ErrInvalidSnapshot)
+	}
+	if header.Version != SnapshotVersion {
+		return result, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, header.Version)
+	}
+
+	// imported holds the keys of the movies stored by this import, and whether
+	// the snapshot lists them as saved.
+	imported := make(map[string]bool)
+	for line := 2; scanner.Scan(); line++ {
+		// After a read error the scanner still returns the partial line read
+		// so far; it must not be taken for a record.
+		if scanner.Err() != nil {
+			break
+		}
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		key, field, value, ttl, err := decodeSnapshotRecord(scanner.Bytes())
+		if err != nil {
+			return result, fmt.Errorf("%w: line %d: %v", ErrInvalidSnapshot, line, err)
+		}
+		if field != "" {
+			movieKey := MovieKey(field, strings.TrimPrefix(key, SavedMoviesKey("")))
+			if _, ok := imported[movieKey]; !ok {
+				result.Skipped++
+				continue
+			}
+			if err := c.SetField(ctx, key, field, value); err != nil {
+				return result, err
+			}
+			imported[movieKey] = true
+			result.Written++
+			continue
+		}
+		stored := true
+		if overwrite {
+			err = c.SetValueWithTTL(ctx, key, value, ttl)
+		} else {
+			stored, err = c.SetValueIfAbsent(ctx, key, value, ttl)
+		}
+		if err != nil {
+			return result, err
+		}
+		if !stored {
+			result.Skipped++
+			continue
+		}
+		if KeyClassOf(key) == KeyClassMovie {
+			imported[key] = false
+		}
+		result.Written++
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	for key, saved := range imported {
+		if saved {
+			continue
+		}
+		id, language := movieKeyParts(key)
+		if err := c.DeleteField(ctx, SavedMoviesKey(language), id); err != nil {
+			return result, err
+		}
+	}
+	log.Printf("Imported %d cache entries from snapshot, skipped %d", result.Written, result.Skipped)
+	return result, nil
+}
+
+// decodeSnapshotRecord returns the key, value and TTL of a snapshot record,
+// and the field it sets if it is a saved movie.
+func decodeSnapshotRecord(line []byte) (string, string, string, time.Duration, error) {
+	var record snapshotRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return "", "", "", 0, err
+	}
+	switch KeyClassOf(record.Key) {
+	case KeyClassMovie, KeyClassDiscover:
+		if record.Field != "" {
+			return "", "", "", 0, fmt.Errorf("unexpected field in key %q", record.Key)
+		}
+	case KeyClassSaved:
+		language, ok := strings.CutPrefix(record.Key, SavedMoviesKey(""))
+		if !ok || language == "" || strings.Contains(language, ":") || record.Field == "" {
+			return "", "", "", 0, fmt.Errorf("unexpected saved movie %q", record.Key)
+		}
+	default:
+		return "", "", "", 0, fmt.Errorf("unexpected key %q", record.Key)
+	}
+	if record.TTLMs < 0 {
+		return "", "", "", 0, errors.New("negative ttl")
+	}
+	value := record.Value
+	if record.ValueBase64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(record.ValueBase64)
+		if err != nil {
+			return "", "", "", 0, err
+		}
+		value = string(decoded)
+	}
+	return record.Key, record.Field, value, time.Duration(record.TTLMs) * time.Millisecond, nil
+}
+
+// movieKeyParts returns the ID and language of a key built by MovieKey.
+func movieKeyParts(key string) (string, string) {
+	rest := strings.TrimPrefix(key, BuildKey(KeyClassMovie)+":")
+	id, language, _ := strings.Cut(rest, ":")
+	return id, language
+}
+
+// maybeGunzip returns a reader of the decompressed content if r starts with
+// the gzip magic number, and of r itself otherwise.
+func maybeGunzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// snapshotLimitReader reads from r until more than max bytes were read, then
+// fails with ErrSnapshotTooLarge. Unlike io.LimitReader, it does not pass a
+// truncated snapshot off as a complete one.
+type snapshotLimitReader struct {
+	r         io.Reader
+	max       int64
+	remaining int64
+}
+
+func (l *snapshotLimitReader) Read(p []byte) (int, error) {
+	// Reading one byte past the limit tells a snapshot of exactly max bytes
+	// from a larger one.
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return 0, fmt.Errorf("%w: more than %d bytes decompressed", ErrSnapshotTooLarge, l.max)
+	}
+	return n, err
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+func newSnapshotSource(t *testing.T) *MemoryCache {
+	source := NewMemoryCache(&config.CacheConfig{Shards: 4})
+	assert.NoError(t, source.SetValue(context.Background(), MovieKey("1", DefaultLanguage), `{"id":1}`))
+	assert.NoError(t, source.SetValueWithTTL(context.Background(), MovieKey("2", DefaultLanguage), `{"id":2}`, time.Hour))
+	assert.NoError(t, source.SetValueWithTTL(context.Background(), "movies:v2:discover:en-US:page=1", `{"page":1}`, time.Minute))
+	assert.NoError(t, source.SetValue(context.Background(), "movies:v2:movie:3:en-US", "\xff\xfe binary"))
+	assert.NoError(t, source.SetField(context.Background(), SavedMoviesKey(DefaultLanguage), "1", `{"id":1}`))
+	// Not-found results, legacy keys and foreign keys are not exported.
+	assert.NoError(t, source.SetValue(context.Background(), MovieTombstoneKey("4", DefaultLanguage), "1"))
+	assert.NoError(t, source.SetValue(context.Background(), LegacyMovieKey("5"), `{"id":5}`))
+	assert.NoError(t, source.SetValue(context.Background(), "other:key", "x"))
+	return source
+}
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		source := newSnapshotSource(t)
+		var buf bytes.Buffer
+
+		exported, err := ExportSnapshot(context.Background(), source, &buf, compress)
+		assert.NoError(t, err)
+		assert.Equal(t, SnapshotResult{Written: 5}, exported)
+		if compress {
+			assert.Equal(t, []byte{0x1f, 0x8b}, buf.Bytes()[:2])
+		}
+
+		target := NewMemoryCache(&config.CacheConfig{Shards: 1})
+		imported, err := ImportSnapshot(context.Background(), target, &buf, false, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, SnapshotResult{Written: 5}, imported)
+		assert.Equal(t, 5, target.Len())
+
+		entry, err := target.GetEntry(context.Background(), MovieKey("1", DefaultLanguage))
+		assert.NoError(t, err)
+		assert.Equal(t, &Entry{Value: `{"id":1}`}, entry)
+
+		entry, err = target.GetEntry(context.Background(), MovieKey("2", DefaultLanguage))
+		assert.NoError(t, err)
+		assert.InDelta(t, time.Hour, entry.ExpiresIn, float64(time.Second))
+
+		value, err := target.GetValue(context.Background(), "movies:v2:movie:3:en-US")
+		assert.NoError(t, err)
+		assert.Equal(t, "\xff\xfe binary", value)
+
+		value, err = target.GetField(context.Background(), SavedMoviesKey(DefaultLanguage), "1")
+		assert.NoError(t, err)
+		assert.Equal(t, `{"id":1}`, value)
+	}
+}
+
+func TestImportSnapshot_SkipsOrOverwritesExistingKeys(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := ExportSnapshot(context.Background(), newSnapshotSource(t), &buf, false)
+	assert.NoError(t, err)
+	snapshot := buf.String()
+
+	target := NewMemoryCache(&config.CacheConfig{Shards: 1})
+	assert.NoError(t, target.SetValue(context.Background(), MovieKey("1", DefaultLanguage), `{"id":1,"title":"local"}`))
+	assert.NoError(t, target.SetField(context.Background(), SavedMoviesKey(DefaultLanguage), "2", `{"id":2,"title":"local"}`))
+
+	// Movie 1 is kept, so its saved copy is not imported; movie 2 is
+	// imported, so it is no longer saved.
+	result, err := ImportSnapshot(context.Background(), target, strings.NewReader(snapshot), false, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, SnapshotResult{Written: 3, Skipped: 2}, result)
+	value, _ := target.GetValue(context.Background(), MovieKey("1", DefaultLanguage))
+	assert.Equal(t, `{"id":1,"title":"local"}`, value)
+	fields, err := target.GetFields(context.Background(), SavedMoviesKey(DefaultLanguage))
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	result, err = ImportSnapshot(context.Background(), target, strings.NewReader(snapshot), true, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, SnapshotResult{Written: 5}, result)
+	value, _ = target.GetValue(context.Background(), MovieKey("1", DefaultLanguage))
+	assert.Equal(t, `{"id":1}`, value)
+	fields, err = target.GetFields(context.Background(), SavedMoviesKey(DefaultLanguage))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"1": `{"id":1}`}, fields)
+}
+
+func TestImportSnapshot_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot string
+	}{
+		{name: "empty", snapshot: ""},
+		{name: "no header", snapshot: `{"key":"movies:v2:movie:1:en-US","value":"{}"}` + "\n"},
+		{name: "unsupported version", snapshot: `{"snapshot":99}` + "\n"},
+		{name: "malformed record", snapshot: `{"snapshot":1}` + "\n" + "not json\n"},
+		{name: "foreign key", snapshot: `{"snapshot":1}` + "\n" + `{"key":"other:key","value":"x"}` + "\n"},
+		{name: "saved movie without field", snapshot: `{"snapshot":1}` + "\n" + `{"key":"movies:v2:saved:movie:en-US","value":"{}"}` + "\n"},
+		{name: "field of a movie", snapshot: `{"snapshot":1}` + "\n" + `{"key":"movies:v2:movie:1:en-US","field":"1","value":"{}"}` + "\n"},
+		{name: "negative ttl", snapshot: `{"snapshot":1}` + "\n" + `{"key":"movies:v2:movie:1:en-US","value":"{}","ttl_ms":-1}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := NewMemoryCache(&config.CacheConfig{Shards: 1})
+			_, err := ImportSnapshot(context.Background(), target, strings.NewReader(tt.snapshot), true, 0)
+			assert.ErrorIs(t, err, ErrInvalidSnapshot)
+			assert.Equal(t, 0, target.Len())
+		})
+	}
+}
+
+func TestImportSnapshot_TooLarge(t *testing.T) {
+	snapshot := `{"snapshot":1}` + "\n" + `{"key":"movies:v2:movie:1:en-US","value":"` + strings.Repeat("a", 1<<20) + `"}` + "\n"
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte(snapshot))
+	assert.NoError(t, gz.Close())
+
+	target := NewMemoryCache(&config.CacheConfig{Shards: 1})
+	// The limit applies to the decompressed size, not to the upload.
+	_, err := ImportSnapshot(context.Background(), target, bytes.NewReader(compressed.Bytes()), true, 64<<10)
+	assert.ErrorIs(t, err, ErrSnapshotTooLarge)
+	assert.Less(t, compressed.Len(), 64<<10)
+	assert.Equal(t, 0, target.Len())
+
+	result, err := ImportSnapshot(context.Background(), target, strings.NewReader(snapshot), true, int64(len(snapshot)))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Written)
+}
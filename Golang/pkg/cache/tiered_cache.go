@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCorruptEntry is returned when a cached value cannot be decoded.
+var ErrCorruptEntry = errors.New("cache: corrupt entry")
+
+// l1Shards is the number of shards of the process-local tier.
+const l1Shards = 8
+
+// Tier identifies the cache level that answered a lookup.
+type Tier string
+
+// Possible values of Tier.
+const (
+	TierL1 Tier = "l1"
+	TierL2 Tier = "l2"
+)
+
+// Codec converts between decoded values and their cached representation.
+type Codec[V any] struct {
+	Encode func(V) (string, error)
+	Decode func(string) (V, error)
+}
+
+// TieredOptions configures the process-local tier of a TieredCache.
+type TieredOptions struct {
+	// L1MaxEntries bounds the process-local tier; zero disables it.
+	L1MaxEntries int
+	// L1TTL caps how long a value is kept in the process-local tier.
+	L1TTL time.Duration
+	// IsStale reports whether an L2 entry is past its soft TTL. Stale entries
+	// are returned but not promoted to L1. May be nil.
+	IsStale func(*Entry) bool
+	// Metrics, when set, records L1 hits, stale serves and the hits and
+	// misses of each tier. Other L2 lookups are recorded by an
+	// InstrumentedCache acting as L2. May be nil.
+	Metrics *Metrics
+}
+
+// Hit is the result of a successful TieredCache lookup.
+type Hit[V any] struct {
+	Value V
+	Tier  Tier
+	Stale bool
+}
+
+// TieredCache keeps decoded values in a small process-local L1 in front of a
+// shared Cache acting as L2. Reads fall through from L1 to L2 and promote fresh
+// L2 entries; writes go to both tiers. Values served from L1 are shared between
+// callers and must be treated as read-only.
+type TieredCache[V any] struct {
+	l1    *shardedLRU[V]
+	l1TTL time.Duration
+	l2    Cache
+	codec Codec[V]
+
+	isStale func(*Entry) bool
+	metrics *Metrics
+}
+
+// NewTieredCache creates a TieredCache in front of l2.
+func NewTieredCache[V any](l2 Cache, codec Codec[V], opts TieredOptions) *TieredCache[V] {
+	t := &TieredCache[V]{
+		l1TTL:   opts.L1TTL,
+		l2:      l2,
+		codec:   codec,
+		isStale: opts.IsStale,
+		metrics: opts.Metrics,
+	}
+	if opts.L1MaxEntries > 0 && opts.L1TTL > 0 {
+		t.l1 = newShardedLRU[V](l1Shards, opts.L1MaxEntries, 0, nil)
+	}
+	return t
+}
+
+// Get returns the value stored under key, looking in L1 before L2. It returns
+// ErrCacheMiss when neither tier holds the key and ErrCorruptEntry when the L2
+// value cannot be decoded.
+func (t *TieredCache[V]) Get(ctx context.Context, key string) (*Hit[V], error) {
+	if t.l1 != nil {
+		if value, _, ok := t.l1.get(key); ok {
+			if t.metrics != nil {
+				t.metrics.RecordHit(key)
+				t.metrics.RecordTierHit(key, TierL1)
+			}
+			return &Hit[V]{Value: value, Tier: TierL1}, nil
+		}
+		t.recordTier(key, TierL1, false)
+	}
+
+	entry, err := t.l2.GetEntry(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			t.recordTier(key, TierL2, false)
+		}
+		return nil, err
+	}
+	t.recordTier(key, TierL2, true)
+
+	value, err := t.codec.Decode(entry.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorruptEntry, key, err)
+	}
+	stale := t.isStale != nil && t.isStale(entry)
+	if !stale {
+		t.setL1(key, value, entry.ExpiresIn)
+	} else if t.metrics != nil {
+		t.metrics.RecordStale(key)
+	}
+	return &Hit[V]{Value: value, Tier: TierL2, Stale: stale}, nil
+}
+
+// Set writes value to L2 with the given ttl and then to L1. A zero ttl means
+// the L2 entry never expires.
+func (t *TieredCache[V]) Set(ctx context.Context, key string, value V, ttl time.Duration) error {
+	encoded, err := t.codec.Encode(value)
+	if err != nil {
+		return err
+	}
+	if err := t.l2.SetValueWithTTL(ctx, key, encoded, ttl); err != nil {
+		t.Invalidate(key)
+		return err
+	}
+	t.setL1(key, value, ttl)
+	return nil
+}
+
+// Delete removes key from both tiers.
+func (t *TieredCache[V]) Delete(ctx context.Context, key string) error {
+	t.Invalidate(key)
+	return t.l2.Delete(ctx, key)
+}
+
+// Invalidate drops keys from L1 only, so the next read goes to L2. It is meant
+// to be called when another replica has updated L2.
+func (t *TieredCache[V]) Invalidate(keys ...string) {
+	if t.l1 == nil {
+		return
+	}
+	for _, key := range keys {
+		t.l1.delete(key)
+	}
+}
+
+// InvalidateAll drops every key from L1.
+func (t *TieredCache[V]) InvalidateAll() {
+	if t.l1 != nil {
+		t.l1.clear()
+	}
+}
+
+// recordTier counts a lookup of key that tier answered, or not.
+func (t *TieredCache[V]) recordTier(key string, tier Tier, hit bool) {
+	switch {
+	case t.metrics == nil:
+	case hit:
+		t.metrics.RecordTierHit(key, tier)
+	default:
+		t.metrics.RecordTierMiss(key, tier)
+	}
+}
+
+// setL1 stores value in L1 for at most the L1 TTL and never beyond the
+// remaining lifetime of the L2 entry.
+func (t *TieredCache[V]) setL1(key string, value V, expiresIn time.Duration) {
+	if t.l1 == nil {
+		return
+	}
+	ttl := t.l1TTL
+	if expiresIn > 0 && expiresIn < ttl {
+		ttl = expiresIn
+	}
+	_ = t.l1.set(key, value, ttl)
+}
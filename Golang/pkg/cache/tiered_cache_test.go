@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+var intCodec = Codec[int]{
+	Encode: func(v int) (string, error) { return strconv.Itoa(v), nil },
+	Decode: strconv.Atoi,
+}
+
+func newTestTieredCache(l2 Cache, opts TieredOptions) *TieredCache[int] {
+	return NewTieredCache(l2, intCodec, opts)
+}
+
+func TestTieredCache_FallsThroughAndPromotes(t *testing.T) {
+	m := NewMetrics()
+	l2 := NewMemoryCache(&config.CacheConfig{Shards: 1})
+	tiered := newTestTieredCache(l2, TieredOptions{L1MaxEntries: 10, L1TTL: time.Minute, Metrics: m})
+
+	assert.NoError(t, l2.SetValue(context.Background(), "answer", "42"))
+
+	hit, err := tiered.Get(context.Background(), "answer")
+	assert.NoError(t, err)
+	assert.Equal(t, &Hit[int]{Value: 42, Tier: TierL2}, hit)
+
+	// The value was promoted, so L1 answers even after L2 loses it.
+	assert.NoError(t, l2.Delete(context.Background(), "answer"))
+	hit, err = tiered.Get(context.Background(), "answer")
+	assert.NoError(t, err)
+	assert.Equal(t, &Hit[int]{Value: 42, Tier: TierL1}, hit)
+
+	_, err = tiered.Get(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.Equal(t, map[Tier]TierStats{
+		TierL1: {Hits: 1, Misses: 2, HitRatio: 1.0 / 3.0},
+		TierL2: {Hits: 1, Misses: 1, HitRatio: 0.5},
+	}, m.Snapshot(context.Background()).Classes[KeyClassOther].Tiers)
+}
+
+func TestTieredCache_SetPopulatesBothTiers(t *testing.T) {
+	l2 := NewMemoryCache(&config.CacheConfig{Shards: 1})
+	tiered := newTestTieredCache(l2, TieredOptions{L1MaxEntries: 10, L1TTL: time.Minute})
+
+	assert.NoError(t, tiered.Set(context.Background(), "answer", 42, time.Hour))
+
+	value, err := l2.GetValue(context.Background(), "answer")
+	assert.NoError(t, err)
+	assert.Equal(t, "42", value)
+
+	hit, err := tiered.Get(context.Background(), "answer")
+	assert.NoError(t, err)
+	assert.Equal(t, TierL1, hit.Tier)
+}
+
+func TestTieredCache_L1TTLNeverExceedsL2(t *testing.T) {
+	l2 := NewMemoryCache(&config.CacheConfig{Shards: 1})
+	tiered := newTestTieredCache(l2, TieredOptions{L1MaxEntries: 10, L1TTL: time.Minute})
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	tiered.l1.now = func() time.Time { return now }
+	l2.store.now = func() time.Time { return now }
+
+	assert.NoError(t, tiered.Set(context.Background(), "short", 1, 10*time.Second))
+	assert.NoError(t, tiered.Set(context.Background(), "long", 2, time.Hour))
+
+	now = now.Add(11 * time.Second)
+	_, err := tiered.Get(context.Background(), "short")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	hit, err := tiered.Get(context.Background(), "long")
+	assert.NoError(t, err)
+	assert.Equal(t, TierL1, hit.Tier)
+
+	now = now.Add(time.Minute)
+	hit, err = tiered.Get(context.Background(), "long")
+	assert.NoError(t, err)
+	assert.Equal(t, TierL2, hit.Tier)
+}
+
+func TestTieredCache_StaleEntriesAreNotPromoted(t *testing.T) {
+	l2 := NewMemoryCache(&config.CacheConfig{Shards: 1})
+	tiered := newTestTieredCache(l2, TieredOptions{
+		L1MaxEntries: 10,
+		L1TTL:        time.Minute,
+		IsStale:      func(*Entry) bool { return true },
+	})
+
+	assert.NoError(t, l2.SetValue(context.Background(), "answer", "42"))
+
+	for i := 0; i < 2; i++ {
+		hit, err := tiered.Get(context.Background(), "answer")
+		assert.NoError(t, err)
+		assert.Equal(t, &Hit[int]{Value: 42, Tier: TierL2, Stale: true}, hit)
+	}
+}
+
+func TestTieredCache_Invalidate(t *testing.T) {
+	l2 := NewMemoryCache(&config.CacheConfig{Shards: 1})
+	tiered := newTestTieredCache(l2, TieredOptions{L1MaxEntries: 10, L1TTL: time.Minute})
+
+	assert.NoError(t, tiered.Set(context.Background(), "answer", 42, 0))
+
+	// Another replica overwrites L2; this replica is told to drop its copy.
+	assert.NoError(t, l2.SetValue(context.Background(), "answer", "43"))
+	tiered.Invalidate("answer")
+
+	hit, err := tiered.Get(context.Background(), "answer")
+	assert.NoError(t, err)
+	assert.Equal(t, &Hit[int]{Value: 43, Tier: TierL2}, hit)
+
+	assert.NoError(t, tiered.Delete(context.Background(), "answer"))
+	_, err = tiered.Get(context.Background(), "answer")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestTieredCache_CorruptEntry(t *testing.T) {
+	l2 := NewMemoryCache(&config.CacheConfig{Shards: 1})
+	tiered := newTestTieredCache(l2, TieredOptions{})
+
+	assert.NoError(t, l2.SetValue(context.Background(), "answer", "forty-two"))
+
+	_, err := tiered.Get(context.Background(), "answer")
+	assert.ErrorIs(t, err, ErrCorruptEntry)
+}
+
+func TestTieredCache_L2WriteFailureDropsL1(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	tiered := newTestTieredCache(&RedisCache{client: db}, TieredOptions{L1MaxEntries: 10, L1TTL: time.Minute})
+
+	mock.ExpectSet("answer", "42", time.Duration(0)).SetVal("OK")
+	mock.ExpectSet("answer", "43", time.Duration(0)).SetErr(errors.New("connection refused"))
+
+	assert.NoError(t, tiered.Set(context.Background(), "answer", 42, 0))
+	assert.Error(t, tiered.Set(context.Background(), "answer", 43, 0))
+
+	_, _, ok := tiered.l1.get("answer")
+	assert.False(t, ok, "Expected L1 not to keep a value L2 did not accept")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTieredCache_RecordsMetrics(t *testing.T) {
+	m := NewMetrics()
+	l2 := NewMemoryCache(&config.CacheConfig{Shards: 1})
+	tiered := newTestTieredCache(l2, TieredOptions{
+		L1MaxEntries: 10,
+		L1TTL:        time.Minute,
+		IsStale:      func(*Entry) bool { return true },
+		Metrics:      m,
+	})
+	key := MovieKey("1", DefaultLanguage)
+
+	assert.NoError(t, tiered.Set(context.Background(), key, 42, time.Hour))
+	_, err := tiered.Get(context.Background(), key)
+	assert.NoError(t, err)
+	tiered.Invalidate(key)
+	hit, err := tiered.Get(context.Background(), key)
+	assert.NoError(t, err)
+	assert.True(t, hit.Stale)
+
+	stats := m.Snapshot(context.Background()).Classes[KeyClassMovie]
+	assert.Equal(t, uint64(1), stats.Hits, "only the L1 hit is recorded; L2 lookups are recorded by InstrumentedCache")
+	assert.Equal(t, uint64(1), stats.Stale)
+}
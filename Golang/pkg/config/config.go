@@ -2,31 +2,282 @@ package config
 
 import (
 	"log"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Supported values for CacheConfig.Backend.
+const (
+	CacheBackendRedis  = "redis"
+	CacheBackendMemory = "memory"
+)
+
+// Supported values for CacheConfig.Codec.
+const (
+	CacheCodecJSON    = "json"
+	CacheCodecMsgpack = "msgpack"
+)
+
+// Supported values for CacheConfig.Compression.
+const (
+	CacheCompressionNone = "none"
+	CacheCompressionGzip = "gzip"
+	CacheCompressionZstd = "zstd"
+)
+
+// Supported values for RedisConfig.Mode.
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
 // RedisConfig holds the configuration settings for connecting to Redis.
 type RedisConfig struct {
-	Addr     string
-	Password string
-	DB       int
+	// Mode is RedisModeStandalone, RedisModeSentinel or RedisModeCluster.
+	Mode string
+	// Addr is the server of standalone mode. Addrs lists the sentinels in
+	// sentinel mode and seed nodes in cluster mode; it defaults to Addr.
+	Addr  string
+	Addrs []string
+	// MasterName is the name of the master monitored by the sentinels.
+	MasterName string
+	Username   string
+	Password   string
+	// SentinelUsername and SentinelPassword authenticate to the sentinels
+	// themselves, when they require it.
+	SentinelUsername string
+	SentinelPassword string
+	// DB is ignored in cluster mode, which only has database 0.
+	DB int
+	// TLS enables TLS, verified against the system roots or TLSCAFile.
+	TLS                   bool
+	TLSCAFile             string
+	TLSInsecureSkipVerify bool
+	// PoolSize and MinIdleConns size the connection pool of each node. Zero
+	// values and timeouts keep the defaults of the Redis client.
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolTimeout  time.Duration
+}
+
+// CacheConfig holds the settings used to select and size the cache backend.
+type CacheConfig struct {
+	// Backend is either CacheBackendRedis or CacheBackendMemory.
+	Backend string
+	// MaxEntries and MaxBytes bound the in-memory backend; zero means unlimited.
+	MaxEntries int
+	MaxBytes   int
+	// Shards is the number of independently locked partitions of the in-memory backend.
+	Shards int
+	// MovieTTL, DiscoverTTL, SearchTTL and NotFoundTTL control how long upstream
+	// movie details, discover pages, search results and negative results are
+	// cached. Zero means no expiry, except for NotFoundTTL where it disables
+	// negative caching.
+	MovieTTL    time.Duration
+	DiscoverTTL time.Duration
+	SearchTTL   time.Duration
+	NotFoundTTL time.Duration
+	// MovieSoftTTL is the age after which cached movie details are served stale
+	// while being refreshed in the background.
+	MovieSoftTTL time.Duration
+	// L1MaxEntries and L1TTL size the process-local tier kept in front of the
+	// shared backend. Either set to zero disables it.
+	L1MaxEntries int
+	L1TTL        time.Duration
+	// InvalidationChannel is the Redis pub/sub channel used to tell other replicas
+	// to drop their local copies of changed keys. Empty disables it.
+	InvalidationChannel string
+	// LegacyKeyFallback makes readers look up movies under the bare-ID keys used
+	// before keys were namespaced and copy them to the new keys. Meant to be
+	// turned off once every replica writes namespaced keys and old entries expired.
+	LegacyKeyFallback bool
+	// Codec is the format values are written in, CacheCodecJSON or
+	// CacheCodecMsgpack. Values in any format are read.
+	Codec string
+	// Compression is applied to values of at least CompressionMinBytes:
+	// CacheCompressionNone, CacheCompressionGzip or CacheCompressionZstd.
+	Compression         string
+	CompressionMinBytes int
+	// SnapshotMaxBytes bounds the snapshots imported through the admin API, both
+	// as uploaded and once decompressed; zero means unlimited.
+	SnapshotMaxBytes int
+}
+
+// WarmupConfig holds the settings of the cache warm-up job.
+type WarmupConfig struct {
+	// Pages is the number of discover pages warmed, starting at page 1. Zero
+	// disables the job.
+	Pages int
+	// Interval is the time between runs after the one at startup. Zero runs
+	// the job at startup only.
+	Interval time.Duration
+	// Concurrency bounds the number of pages or movies warmed at once.
+	Concurrency int
+	// RatePerSecond and Burst limit the requests the job makes to the upstream
+	// API. A rate of zero or less removes the limit.
+	RatePerSecond float64
+	Burst         int
+}
+
+// DefaultUpstreamURL is the root of the TMDB API.
+const DefaultUpstreamURL = "https://api.themoviedb.org/3"
+
+// DefaultUpstreamTimeout bounds upstream requests when no timeout is configured.
+const DefaultUpstreamTimeout = 10 * time.Second
+
+// UpstreamConfig holds the settings used to reach the TMDB API or a
+// compatible service.
+type UpstreamConfig struct {
+	// BaseURL is the API root, without a trailing slash.
+	BaseURL string
+	// Token is sent as a bearer token with every request.
+	Token string
+	// Timeout bounds a whole request, including reading the body. Zero means
+	// no limit.
+	Timeout time.Duration
+	// DialTimeout and ResponseHeaderTimeout bound connecting and waiting for
+	// the response headers. Zero means no limit.
+	DialTimeout           time.Duration
+	ResponseHeaderTimeout time.Duration
+	// MaxIdleConnsPerHost is the number of keep-alive connections kept open.
+	MaxIdleConnsPerHost int
+	// ProxyURL routes requests through an HTTP proxy. When nil the standard
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables apply.
+	ProxyURL *url.URL
+	// MaxRetries is the number of times a failed GET is retried; zero disables
+	// retries. Delays grow exponentially from RetryBaseDelay up to
+	// RetryMaxDelay, with jitter, unless the API asks for a delay with
+	// Retry-After. RetryBudget bounds the total delay of a request's retries.
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	RetryBudget    time.Duration
+	// BreakerFailureRate is the share of failed requests, between 0 and 1,
+	// that opens the circuit breaker once BreakerMinRequests requests were
+	// made within BreakerWindow; zero disables the breaker. An open breaker
+	// fails requests fast for BreakerOpenTimeout, then lets
+	// BreakerHalfOpenProbes requests through to probe recovery.
+	BreakerFailureRate    float64
+	BreakerMinRequests    int
+	BreakerWindow         time.Duration
+	BreakerOpenTimeout    time.Duration
+	BreakerHalfOpenProbes int
+	// RateLimit is the number of requests per second allowed, in bursts of up
+	// to RateBurst; zero disables rate limiting. A request waits at most
+	// RateMaxWait for its turn. When RateLimitShared is set, the budget is
+	// kept in Redis and shared by every replica.
+	RateLimit       float64
+	RateBurst       int
+	RateMaxWait     time.Duration
+	RateLimitShared bool
 }
 
 // LoadConfig loads environment variables and returns a RedisConfig struct
 func LoadConfig() *RedisConfig {
+	loadEnvFile()
+
+	return &RedisConfig{
+		Mode:                  getEnv("REDIS_MODE", RedisModeStandalone),
+		Addr:                  getEnv("REDIS_ADDR", "localhost:6379"),
+		Addrs:                 getEnvAsList("REDIS_ADDRS"),
+		MasterName:            getEnv("REDIS_MASTER_NAME", ""),
+		Username:              getEnv("REDIS_USERNAME", ""),
+		Password:              getEnv("REDIS_PASSWORD", ""),
+		SentinelUsername:      getEnv("REDIS_SENTINEL_USERNAME", ""),
+		SentinelPassword:      getEnv("REDIS_SENTINEL_PASSWORD", ""),
+		DB:                    getEnvAsInt("REDIS_DB", 0),
+		TLS:                   getEnvAsBool("REDIS_TLS", false),
+		TLSCAFile:             getEnv("REDIS_TLS_CA_FILE", ""),
+		TLSInsecureSkipVerify: getEnvAsBool("REDIS_TLS_INSECURE_SKIP_VERIFY", false),
+		PoolSize:              getEnvAsInt("REDIS_POOL_SIZE", 0),
+		MinIdleConns:          getEnvAsInt("REDIS_MIN_IDLE_CONNS", 0),
+		DialTimeout:           getEnvAsDuration("REDIS_DIAL_TIMEOUT", 0),
+		ReadTimeout:           getEnvAsDuration("REDIS_READ_TIMEOUT", 0),
+		WriteTimeout:          getEnvAsDuration("REDIS_WRITE_TIMEOUT", 0),
+		PoolTimeout:           getEnvAsDuration("REDIS_POOL_TIMEOUT", 0),
+	}
+}
+
+// LoadCacheConfig loads environment variables and returns a CacheConfig struct
+func LoadCacheConfig() *CacheConfig {
+	loadEnvFile()
+
+	return &CacheConfig{
+		Backend:             getEnv("CACHE_BACKEND", CacheBackendRedis),
+		MaxEntries:          getEnvAsInt("CACHE_MAX_ENTRIES", 10000),
+		MaxBytes:            getEnvAsInt("CACHE_MAX_BYTES", 64<<20),
+		Shards:              getEnvAsInt("CACHE_SHARDS", 16),
+		MovieTTL:            getEnvAsDuration("CACHE_TTL_MOVIE", 24*time.Hour),
+		MovieSoftTTL:        getEnvAsDuration("CACHE_TTL_MOVIE_SOFT", time.Hour),
+		DiscoverTTL:         getEnvAsDuration("CACHE_TTL_DISCOVER", time.Hour),
+		SearchTTL:           getEnvAsDuration("CACHE_TTL_SEARCH", 15*time.Minute),
+		NotFoundTTL:         getEnvAsDuration("CACHE_TTL_NOT_FOUND", 5*time.Minute),
+		L1MaxEntries:        getEnvAsInt("CACHE_L1_MAX_ENTRIES", 1000),
+		L1TTL:               getEnvAsDuration("CACHE_L1_TTL", 30*time.Second),
+		InvalidationChannel: getEnv("CACHE_INVALIDATION_CHANNEL", "movies:invalidate"),
+		LegacyKeyFallback:   getEnvAsBool("CACHE_LEGACY_KEY_FALLBACK", true),
+		Codec:               getEnv("CACHE_CODEC", CacheCodecJSON),
+		Compression:         getEnv("CACHE_COMPRESSION", CacheCompressionNone),
+		CompressionMinBytes: getEnvAsInt("CACHE_COMPRESSION_MIN_BYTES", 1024),
+		SnapshotMaxBytes:    getEnvAsInt("CACHE_SNAPSHOT_MAX_BYTES", 256<<20),
+	}
+}
+
+// LoadUpstreamConfig loads environment variables and returns an UpstreamConfig struct
+func LoadUpstreamConfig() *UpstreamConfig {
+	loadEnvFile()
+
+	return &UpstreamConfig{
+		BaseURL:               strings.TrimRight(getEnv("API_URL", DefaultUpstreamURL), "/"),
+		Token:                 getEnv("TOKEN", ""),
+		Timeout:               getEnvAsDuration("UPSTREAM_TIMEOUT", DefaultUpstreamTimeout),
+		DialTimeout:           getEnvAsDuration("UPSTREAM_DIAL_TIMEOUT", 5*time.Second),
+		ResponseHeaderTimeout: getEnvAsDuration("UPSTREAM_RESPONSE_HEADER_TIMEOUT", 5*time.Second),
+		MaxIdleConnsPerHost:   getEnvAsInt("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", 16),
+		ProxyURL:              getEnvAsURL("UPSTREAM_PROXY_URL"),
+		MaxRetries:            getEnvAsInt("UPSTREAM_MAX_RETRIES", 2),
+		RetryBaseDelay:        getEnvAsDuration("UPSTREAM_RETRY_BASE_DELAY", 200*time.Millisecond),
+		RetryMaxDelay:         getEnvAsDuration("UPSTREAM_RETRY_MAX_DELAY", 2*time.Second),
+		RetryBudget:           getEnvAsDuration("UPSTREAM_RETRY_BUDGET", 3*time.Second),
+		BreakerFailureRate:    getEnvAsFloat("UPSTREAM_BREAKER_FAILURE_RATE", 0.5),
+		BreakerMinRequests:    getEnvAsInt("UPSTREAM_BREAKER_MIN_REQUESTS", 10),
+		BreakerWindow:         getEnvAsDuration("UPSTREAM_BREAKER_WINDOW", 30*time.Second),
+		BreakerOpenTimeout:    getEnvAsDuration("UPSTREAM_BREAKER_OPEN_TIMEOUT", 15*time.Second),
+		BreakerHalfOpenProbes: getEnvAsInt("UPSTREAM_BREAKER_HALF_OPEN_PROBES", 1),
+		RateLimit:             getEnvAsFloat("UPSTREAM_RATE_LIMIT", 40),
+		RateBurst:             getEnvAsInt("UPSTREAM_RATE_BURST", 20),
+		RateMaxWait:           getEnvAsDuration("UPSTREAM_RATE_MAX_WAIT", time.Second),
+		RateLimitShared:       getEnvAsBool("UPSTREAM_RATE_LIMIT_SHARED", false),
+	}
+}
+
+// LoadWarmupConfig loads environment variables and returns a WarmupConfig struct
+func LoadWarmupConfig() *WarmupConfig {
+	loadEnvFile()
+
+	return &WarmupConfig{
+		Pages:         getEnvAsInt("WARMUP_PAGES", 0),
+		Interval:      getEnvAsDuration("WARMUP_INTERVAL", 0),
+		Concurrency:   getEnvAsInt("WARMUP_CONCURRENCY", 4),
+		RatePerSecond: getEnvAsFloat("WARMUP_RATE", 10),
+		Burst:         getEnvAsInt("WARMUP_BURST", 4),
+	}
+}
+
+func loadEnvFile() {
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("No .env file found")
 	}
-
-	return &RedisConfig{
-		Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
-		Password: getEnv("REDIS_PASSWORD", ""),
-		DB:       getEnvAsInt("REDIS_DB", 0),
-	}
 }
 
 // Helper functions to read environment variables
@@ -44,3 +295,53 @@ func getEnvAsInt(name string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsDuration(name string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(name, "")
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(name string, defaultValue bool) bool {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(name string, defaultValue float64) float64 {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvAsList splits a comma-separated variable, dropping empty items.
+func getEnvAsList(name string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(name, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvAsURL parses an absolute URL, returning nil when the variable is unset
+// or invalid.
+func getEnvAsURL(name string) *url.URL {
+	valueStr := getEnv(name, "")
+	if valueStr == "" {
+		return nil
+	}
+	value, err := url.Parse(valueStr)
+	if err != nil || value.Scheme == "" || value.Host == "" {
+		log.Printf("Ignoring invalid URL in %s: %q", name, valueStr)
+		return nil
+	}
+	return value
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,9 +34,51 @@ func TestLoadConfig_WithDefaultValues(t *testing.T) {
 	config := LoadConfig()
 
 	// Assertions
+	assert.Equal(t, RedisModeStandalone, config.Mode, "Expected default Redis mode to be standalone")
 	assert.Equal(t, "localhost:6379", config.Addr, "Expected default Redis Addr to be localhost:6379")
+	assert.Empty(t, config.Addrs, "Expected no default Redis Addrs")
 	assert.Equal(t, "", config.Password, "Expected default Redis Password to be an empty string")
 	assert.Equal(t, 0, config.DB, "Expected default Redis DB to be 0")
+	assert.False(t, config.TLS, "Expected TLS to be disabled by default")
+	assert.Equal(t, 0, config.PoolSize, "Expected default pool size to be left to the client")
+}
+
+func TestLoadConfig_Sentinel(t *testing.T) {
+	os.Setenv("REDIS_MODE", "sentinel")
+	os.Setenv("REDIS_ADDRS", "sentinel-1:26379, sentinel-2:26379,")
+	os.Setenv("REDIS_MASTER_NAME", "movies")
+	os.Setenv("REDIS_USERNAME", "api")
+	os.Setenv("REDIS_SENTINEL_PASSWORD", "sentinel-secret")
+	os.Setenv("REDIS_TLS", "true")
+	os.Setenv("REDIS_TLS_CA_FILE", "/etc/redis/ca.pem")
+	os.Setenv("REDIS_POOL_SIZE", "20")
+	os.Setenv("REDIS_MIN_IDLE_CONNS", "5")
+	os.Setenv("REDIS_DIAL_TIMEOUT", "2s")
+	os.Setenv("REDIS_READ_TIMEOUT", "500ms")
+	os.Setenv("REDIS_WRITE_TIMEOUT", "750ms")
+	os.Setenv("REDIS_POOL_TIMEOUT", "1s")
+
+	config := LoadConfig()
+
+	assert.Equal(t, RedisModeSentinel, config.Mode, "Expected Redis mode to be sentinel")
+	assert.Equal(t, []string{"sentinel-1:26379", "sentinel-2:26379"}, config.Addrs, "Expected two sentinel addresses")
+	assert.Equal(t, "movies", config.MasterName, "Expected master name to be movies")
+	assert.Equal(t, "api", config.Username, "Expected Redis username to be api")
+	assert.Equal(t, "sentinel-secret", config.SentinelPassword, "Expected sentinel password to be sentinel-secret")
+	assert.True(t, config.TLS, "Expected TLS to be enabled")
+	assert.Equal(t, "/etc/redis/ca.pem", config.TLSCAFile, "Expected CA file to be /etc/redis/ca.pem")
+	assert.Equal(t, 20, config.PoolSize, "Expected pool size to be 20")
+	assert.Equal(t, 5, config.MinIdleConns, "Expected min idle connections to be 5")
+	assert.Equal(t, 2*time.Second, config.DialTimeout, "Expected dial timeout to be 2s")
+	assert.Equal(t, 500*time.Millisecond, config.ReadTimeout, "Expected read timeout to be 500ms")
+	assert.Equal(t, 750*time.Millisecond, config.WriteTimeout, "Expected write timeout to be 750ms")
+	assert.Equal(t, time.Second, config.PoolTimeout, "Expected pool timeout to be 1s")
+
+	for _, name := range []string{"REDIS_MODE", "REDIS_ADDRS", "REDIS_MASTER_NAME", "REDIS_USERNAME", "REDIS_SENTINEL_PASSWORD",
+		"REDIS_TLS", "REDIS_TLS_CA_FILE", "REDIS_POOL_SIZE", "REDIS_MIN_IDLE_CONNS", "REDIS_DIAL_TIMEOUT",
+		"REDIS_READ_TIMEOUT", "REDIS_WRITE_TIMEOUT", "REDIS_POOL_TIMEOUT"} {
+		os.Unsetenv(name)
+	}
 }
 
 func TestGetEnv(t *testing.T) {
@@ -66,3 +109,263 @@ func TestGetEnvAsInt(t *testing.T) {
 	os.Unsetenv("TEST_INT_ENV")
 	os.Unsetenv("TEST_INVALID_INT_ENV")
 }
+
+func TestLoadCacheConfig_WithEnvVars(t *testing.T) {
+	os.Setenv("CACHE_BACKEND", "memory")
+	os.Setenv("CACHE_MAX_ENTRIES", "500")
+	os.Setenv("CACHE_MAX_BYTES", "1048576")
+	os.Setenv("CACHE_SHARDS", "4")
+	os.Setenv("CACHE_TTL_MOVIE", "2h")
+	os.Setenv("CACHE_TTL_MOVIE_SOFT", "20m")
+	os.Setenv("CACHE_TTL_DISCOVER", "15m")
+	os.Setenv("CACHE_TTL_SEARCH", "2m")
+	os.Setenv("CACHE_TTL_NOT_FOUND", "30s")
+	os.Setenv("CACHE_L1_MAX_ENTRIES", "50")
+	os.Setenv("CACHE_L1_TTL", "5s")
+	os.Setenv("CACHE_INVALIDATION_CHANNEL", "staging:invalidate")
+	os.Setenv("CACHE_LEGACY_KEY_FALLBACK", "false")
+	os.Setenv("CACHE_CODEC", "msgpack")
+	os.Setenv("CACHE_COMPRESSION", "zstd")
+	os.Setenv("CACHE_COMPRESSION_MIN_BYTES", "256")
+	os.Setenv("CACHE_SNAPSHOT_MAX_BYTES", "4096")
+
+	config := LoadCacheConfig()
+
+	assert.Equal(t, CacheBackendMemory, config.Backend, "Expected cache backend to be memory")
+	assert.Equal(t, 500, config.MaxEntries, "Expected cache max entries to be 500")
+	assert.Equal(t, 1048576, config.MaxBytes, "Expected cache max bytes to be 1048576")
+	assert.Equal(t, 4, config.Shards, "Expected cache shards to be 4")
+	assert.Equal(t, 2*time.Hour, config.MovieTTL, "Expected movie TTL to be 2h")
+	assert.Equal(t, 20*time.Minute, config.MovieSoftTTL, "Expected movie soft TTL to be 20m")
+	assert.Equal(t, 15*time.Minute, config.DiscoverTTL, "Expected discover TTL to be 15m")
+	assert.Equal(t, 2*time.Minute, config.SearchTTL, "Expected search TTL to be 2m")
+	assert.Equal(t, 30*time.Second, config.NotFoundTTL, "Expected not found TTL to be 30s")
+	assert.Equal(t, 50, config.L1MaxEntries, "Expected L1 max entries to be 50")
+	assert.Equal(t, 5*time.Second, config.L1TTL, "Expected L1 TTL to be 5s")
+	assert.Equal(t, "staging:invalidate", config.InvalidationChannel, "Expected invalidation channel to be staging:invalidate")
+	assert.False(t, config.LegacyKeyFallback, "Expected legacy key fallback to be disabled")
+	assert.Equal(t, CacheCodecMsgpack, config.Codec, "Expected cache codec to be msgpack")
+	assert.Equal(t, CacheCompressionZstd, config.Compression, "Expected cache compression to be zstd")
+	assert.Equal(t, 256, config.CompressionMinBytes, "Expected compression threshold to be 256")
+	assert.Equal(t, 4096, config.SnapshotMaxBytes, "Expected snapshot max bytes to be 4096")
+
+	os.Unsetenv("CACHE_BACKEND")
+	os.Unsetenv("CACHE_MAX_ENTRIES")
+	os.Unsetenv("CACHE_MAX_BYTES")
+	os.Unsetenv("CACHE_SHARDS")
+	os.Unsetenv("CACHE_TTL_MOVIE")
+	os.Unsetenv("CACHE_TTL_MOVIE_SOFT")
+	os.Unsetenv("CACHE_TTL_DISCOVER")
+	os.Unsetenv("CACHE_TTL_SEARCH")
+	os.Unsetenv("CACHE_TTL_NOT_FOUND")
+	os.Unsetenv("CACHE_L1_MAX_ENTRIES")
+	os.Unsetenv("CACHE_L1_TTL")
+	os.Unsetenv("CACHE_INVALIDATION_CHANNEL")
+	os.Unsetenv("CACHE_LEGACY_KEY_FALLBACK")
+	os.Unsetenv("CACHE_CODEC")
+	os.Unsetenv("CACHE_COMPRESSION")
+	os.Unsetenv("CACHE_COMPRESSION_MIN_BYTES")
+	os.Unsetenv("CACHE_SNAPSHOT_MAX_BYTES")
+}
+
+func TestLoadCacheConfig_WithDefaultValues(t *testing.T) {
+	os.Unsetenv("CACHE_BACKEND")
+	os.Unsetenv("CACHE_MAX_ENTRIES")
+	os.Unsetenv("CACHE_MAX_BYTES")
+	os.Unsetenv("CACHE_SHARDS")
+
+	config := LoadCacheConfig()
+
+	assert.Equal(t, CacheBackendRedis, config.Backend, "Expected default cache backend to be redis")
+	assert.Equal(t, 10000, config.MaxEntries, "Expected default cache max entries to be 10000")
+	assert.Equal(t, 64<<20, config.MaxBytes, "Expected default cache max bytes to be 64MiB")
+	assert.Equal(t, 16, config.Shards, "Expected default cache shards to be 16")
+	assert.Equal(t, 24*time.Hour, config.MovieTTL, "Expected default movie TTL to be 24h")
+	assert.Equal(t, time.Hour, config.MovieSoftTTL, "Expected default movie soft TTL to be 1h")
+	assert.Equal(t, time.Hour, config.DiscoverTTL, "Expected default discover TTL to be 1h")
+	assert.Equal(t, 15*time.Minute, config.SearchTTL, "Expected default search TTL to be 15m")
+	assert.Equal(t, 5*time.Minute, config.NotFoundTTL, "Expected default not found TTL to be 5m")
+	assert.Equal(t, 1000, config.L1MaxEntries, "Expected default L1 max entries to be 1000")
+	assert.Equal(t, 30*time.Second, config.L1TTL, "Expected default L1 TTL to be 30s")
+	assert.Equal(t, "movies:invalidate", config.InvalidationChannel, "Expected default invalidation channel to be movies:invalidate")
+	assert.True(t, config.LegacyKeyFallback, "Expected legacy key fallback to be enabled by default")
+	assert.Equal(t, CacheCodecJSON, config.Codec, "Expected default cache codec to be json")
+	assert.Equal(t, CacheCompressionNone, config.Compression, "Expected no compression by default")
+	assert.Equal(t, 1024, config.CompressionMinBytes, "Expected default compression threshold to be 1024")
+	assert.Equal(t, 256<<20, config.SnapshotMaxBytes, "Expected default snapshot max bytes to be 256MiB")
+}
+
+func TestGetEnvAsDuration(t *testing.T) {
+	os.Setenv("TEST_DURATION_ENV", "90s")
+
+	result := getEnvAsDuration("TEST_DURATION_ENV", time.Minute)
+	assert.Equal(t, 90*time.Second, result, "Expected getEnvAsDuration to parse TEST_DURATION_ENV")
+
+	result = getEnvAsDuration("NON_EXISTENT_DURATION_ENV", time.Minute)
+	assert.Equal(t, time.Minute, result, "Expected getEnvAsDuration to return the default value when the env var is not set")
+
+	os.Setenv("TEST_INVALID_DURATION_ENV", "soon")
+	result = getEnvAsDuration("TEST_INVALID_DURATION_ENV", time.Minute)
+	assert.Equal(t, time.Minute, result, "Expected getEnvAsDuration to return the default value when the env var is not a duration")
+
+	os.Unsetenv("TEST_DURATION_ENV")
+	os.Unsetenv("TEST_INVALID_DURATION_ENV")
+}
+
+func TestGetEnvAsBool(t *testing.T) {
+	os.Setenv("TEST_BOOL_ENV", "false")
+
+	result := getEnvAsBool("TEST_BOOL_ENV", true)
+	assert.False(t, result, "Expected getEnvAsBool to parse TEST_BOOL_ENV")
+
+	result = getEnvAsBool("NON_EXISTENT_BOOL_ENV", true)
+	assert.True(t, result, "Expected getEnvAsBool to return the default value when the env var is not set")
+
+	os.Setenv("TEST_INVALID_BOOL_ENV", "maybe")
+	result = getEnvAsBool("TEST_INVALID_BOOL_ENV", true)
+	assert.True(t, result, "Expected getEnvAsBool to return the default value when the env var is not a boolean")
+
+	os.Unsetenv("TEST_BOOL_ENV")
+	os.Unsetenv("TEST_INVALID_BOOL_ENV")
+}
+
+func TestLoadWarmupConfig_WithEnvVars(t *testing.T) {
+	os.Setenv("WARMUP_PAGES", "5")
+	os.Setenv("WARMUP_INTERVAL", "30m")
+	os.Setenv("WARMUP_CONCURRENCY", "8")
+	os.Setenv("WARMUP_RATE", "2.5")
+	os.Setenv("WARMUP_BURST", "1")
+
+	config := LoadWarmupConfig()
+
+	assert.Equal(t, 5, config.Pages, "Expected warm-up pages to be 5")
+	assert.Equal(t, 30*time.Minute, config.Interval, "Expected warm-up interval to be 30m")
+	assert.Equal(t, 8, config.Concurrency, "Expected warm-up concurrency to be 8")
+	assert.Equal(t, 2.5, config.RatePerSecond, "Expected warm-up rate to be 2.5")
+	assert.Equal(t, 1, config.Burst, "Expected warm-up burst to be 1")
+
+	os.Unsetenv("WARMUP_PAGES")
+	os.Unsetenv("WARMUP_INTERVAL")
+	os.Unsetenv("WARMUP_CONCURRENCY")
+	os.Unsetenv("WARMUP_RATE")
+	os.Unsetenv("WARMUP_BURST")
+}
+
+func TestLoadWarmupConfig_WithDefaultValues(t *testing.T) {
+	config := LoadWarmupConfig()
+
+	assert.Equal(t, 0, config.Pages, "Expected warm-up to be disabled by default")
+	assert.Equal(t, time.Duration(0), config.Interval, "Expected warm-up to run at startup only by default")
+	assert.Equal(t, 4, config.Concurrency, "Expected default warm-up concurrency to be 4")
+	assert.Equal(t, 10.0, config.RatePerSecond, "Expected default warm-up rate to be 10")
+	assert.Equal(t, 4, config.Burst, "Expected default warm-up burst to be 4")
+}
+
+func TestGetEnvAsFloat(t *testing.T) {
+	os.Setenv("TEST_FLOAT_ENV", "0.5")
+
+	result := getEnvAsFloat("TEST_FLOAT_ENV", 1)
+	assert.Equal(t, 0.5, result, "Expected getEnvAsFloat to parse TEST_FLOAT_ENV")
+
+	result = getEnvAsFloat("NON_EXISTENT_FLOAT_ENV", 1)
+	assert.Equal(t, 1.0, result, "Expected getEnvAsFloat to return the default value when the env var is not set")
+
+	os.Unsetenv("TEST_FLOAT_ENV")
+}
+
+func TestLoadUpstreamConfig_WithEnvVars(t *testing.T) {
+	os.Setenv("API_URL", "http://tmdb-mirror.internal/3/")
+	os.Setenv("TOKEN", "mirror-token")
+	os.Setenv("UPSTREAM_TIMEOUT", "3s")
+	os.Setenv("UPSTREAM_DIAL_TIMEOUT", "1s")
+	os.Setenv("UPSTREAM_RESPONSE_HEADER_TIMEOUT", "2s")
+	os.Setenv("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", "32")
+	os.Setenv("UPSTREAM_PROXY_URL", "http://proxy.corp:3128")
+
+	config := LoadUpstreamConfig()
+
+	assert.Equal(t, "http://tmdb-mirror.internal/3", config.BaseURL, "Expected the trailing slash to be trimmed")
+	assert.Equal(t, "mirror-token", config.Token, "Expected token to be mirror-token")
+	assert.Equal(t, 3*time.Second, config.Timeout, "Expected timeout to be 3s")
+	assert.Equal(t, time.Second, config.DialTimeout, "Expected dial timeout to be 1s")
+	assert.Equal(t, 2*time.Second, config.ResponseHeaderTimeout, "Expected response header timeout to be 2s")
+	assert.Equal(t, 32, config.MaxIdleConnsPerHost, "Expected max idle connections per host to be 32")
+	assert.Equal(t, "http://proxy.corp:3128", config.ProxyURL.String(), "Expected proxy to be http://proxy.corp:3128")
+
+	os.Unsetenv("API_URL")
+	os.Unsetenv("TOKEN")
+	os.Unsetenv("UPSTREAM_TIMEOUT")
+	os.Unsetenv("UPSTREAM_DIAL_TIMEOUT")
+	os.Unsetenv("UPSTREAM_RESPONSE_HEADER_TIMEOUT")
+	os.Unsetenv("UPSTREAM_MAX_IDLE_CONNS_PER_HOST")
+	os.Unsetenv("UPSTREAM_PROXY_URL")
+}
+
+func TestLoadUpstreamConfig_WithDefaultValues(t *testing.T) {
+	os.Unsetenv("API_URL")
+	os.Unsetenv("UPSTREAM_TIMEOUT")
+	os.Setenv("UPSTREAM_PROXY_URL", "proxy.corp:3128")
+
+	config := LoadUpstreamConfig()
+
+	assert.Equal(t, DefaultUpstreamURL, config.BaseURL, "Expected default base URL to be TMDB")
+	assert.Equal(t, 10*time.Second, config.Timeout, "Expected default timeout to be 10s")
+	assert.Equal(t, 16, config.MaxIdleConnsPerHost, "Expected default max idle connections per host to be 16")
+	assert.Nil(t, config.ProxyURL, "Expected a proxy URL without scheme to be ignored")
+	assert.Equal(t, 2, config.MaxRetries, "Expected default max retries to be 2")
+	assert.Equal(t, 200*time.Millisecond, config.RetryBaseDelay, "Expected default retry base delay to be 200ms")
+	assert.Equal(t, 2*time.Second, config.RetryMaxDelay, "Expected default retry max delay to be 2s")
+	assert.Equal(t, 3*time.Second, config.RetryBudget, "Expected default retry budget to be 3s")
+	assert.Equal(t, 0.5, config.BreakerFailureRate, "Expected default breaker failure rate to be 0.5")
+	assert.Equal(t, 10, config.BreakerMinRequests, "Expected default breaker min requests to be 10")
+	assert.Equal(t, 30*time.Second, config.BreakerWindow, "Expected default breaker window to be 30s")
+	assert.Equal(t, 15*time.Second, config.BreakerOpenTimeout, "Expected default breaker open timeout to be 15s")
+	assert.Equal(t, 1, config.BreakerHalfOpenProbes, "Expected default breaker half-open probes to be 1")
+	assert.Equal(t, 40.0, config.RateLimit, "Expected default rate limit to be 40")
+	assert.Equal(t, 20, config.RateBurst, "Expected default rate burst to be 20")
+	assert.Equal(t, time.Second, config.RateMaxWait, "Expected default rate max wait to be 1s")
+	assert.False(t, config.RateLimitShared, "Expected the rate limit not to be shared by default")
+
+	os.Unsetenv("UPSTREAM_PROXY_URL")
+}
+
+func TestLoadUpstreamConfig_Breaker(t *testing.T) {
+	os.Setenv("UPSTREAM_BREAKER_FAILURE_RATE", "0.25")
+	os.Setenv("UPSTREAM_BREAKER_MIN_REQUESTS", "20")
+	os.Setenv("UPSTREAM_BREAKER_WINDOW", "1m")
+	os.Setenv("UPSTREAM_BREAKER_OPEN_TIMEOUT", "5s")
+	os.Setenv("UPSTREAM_BREAKER_HALF_OPEN_PROBES", "3")
+
+	config := LoadUpstreamConfig()
+
+	assert.Equal(t, 0.25, config.BreakerFailureRate, "Expected breaker failure rate to be 0.25")
+	assert.Equal(t, 20, config.BreakerMinRequests, "Expected breaker min requests to be 20")
+	assert.Equal(t, time.Minute, config.BreakerWindow, "Expected breaker window to be 1m")
+	assert.Equal(t, 5*time.Second, config.BreakerOpenTimeout, "Expected breaker open timeout to be 5s")
+	assert.Equal(t, 3, config.BreakerHalfOpenProbes, "Expected breaker half-open probes to be 3")
+
+	os.Unsetenv("UPSTREAM_BREAKER_FAILURE_RATE")
+	os.Unsetenv("UPSTREAM_BREAKER_MIN_REQUESTS")
+	os.Unsetenv("UPSTREAM_BREAKER_WINDOW")
+	os.Unsetenv("UPSTREAM_BREAKER_OPEN_TIMEOUT")
+	os.Unsetenv("UPSTREAM_BREAKER_HALF_OPEN_PROBES")
+}
+
+func TestLoadUpstreamConfig_RateLimit(t *testing.T) {
+	os.Setenv("UPSTREAM_RATE_LIMIT", "12.5")
+	os.Setenv("UPSTREAM_RATE_BURST", "5")
+	os.Setenv("UPSTREAM_RATE_MAX_WAIT", "250ms")
+	os.Setenv("UPSTREAM_RATE_LIMIT_SHARED", "true")
+
+	config := LoadUpstreamConfig()
+
+	assert.Equal(t, 12.5, config.RateLimit, "Expected rate limit to be 12.5")
+	assert.Equal(t, 5, config.RateBurst, "Expected rate burst to be 5")
+	assert.Equal(t, 250*time.Millisecond, config.RateMaxWait, "Expected rate max wait to be 250ms")
+	assert.True(t, config.RateLimitShared, "Expected the rate limit to be shared")
+
+	os.Unsetenv("UPSTREAM_RATE_LIMIT")
+	os.Unsetenv("UPSTREAM_RATE_BURST")
+	os.Unsetenv("UPSTREAM_RATE_MAX_WAIT")
+	os.Unsetenv("UPSTREAM_RATE_LIMIT_SHARED")
+}
@@ -1,5 +1,7 @@
 package models
 
+import "encoding/json"
+
 // Genre represents a movie genre with an ID and a name.
 type Genre struct {
 	ID   int    `json:"id"`
@@ -54,8 +56,53 @@ type Movie struct {
 
 // MovieList represents a paginated list of movies.
 type MovieList struct {
-	Page    int     `json:"page"`
-	Results []Movie `json:"results"`
+	Page         int     `json:"page"`
+	Results      []Movie `json:"results"`
+	TotalPages   int     `json:"total_pages"`
+	TotalResults int     `json:"total_results"`
+	// PageSize, Next and Previous are set by the API for its clients; they
+	// are not part of the upstream response. Next and Previous are relative
+	// links to the neighbouring pages, empty on the last and first page.
+	PageSize int    `json:"page_size,omitempty"`
+	Next     string `json:"next,omitempty"`
+	Previous string `json:"previous,omitempty"`
+}
+
+// SearchQuery holds the parameters of a movie search. Zero values are left
+// out of the search: any year, the configured language and no adult movies.
+type SearchQuery struct {
+	Query        string
+	Year         int
+	Page         int
+	Language     string
+	IncludeAdult bool
+}
+
+// DiscoverQuery holds the page and filters of a discover request. Zero values
+// are left out of the request, so a query with only a page asks for the same
+// page as before filters existed.
+type DiscoverQuery struct {
+	Page int
+	// GenreIDs keeps movies having every listed genre.
+	GenreIDs []int
+	// Year is the primary release year.
+	Year int
+	// ReleaseDateFrom and ReleaseDateTo bound the primary release date, as
+	// YYYY-MM-DD.
+	ReleaseDateFrom string
+	ReleaseDateTo   string
+	MinVoteAverage  float64
+	MinVoteCount    int
+	// MinRuntime and MaxRuntime bound the runtime in minutes.
+	MinRuntime int
+	MaxRuntime int
+	// OriginalLanguage is an ISO 639-1 code, e.g. "es".
+	OriginalLanguage string
+	// Region is an ISO 3166-1 code, e.g. "MX", used to pick release dates.
+	Region string
+	// SortBy is a TMDB sort order, e.g. "popularity.desc".
+	SortBy       string
+	IncludeAdult bool
 }
 
 // ErrorResponse represents a generic error response
@@ -67,3 +114,34 @@ type ErrorResponse struct {
 type SuccessResponse struct {
 	Message string `json:"message"`
 }
+
+// HealthResponse reports the state of the service and of its dependencies
+type HealthResponse struct {
+	Status string `json:"status"`
+	Cache  string `json:"cache"`
+	// Upstream and Circuit are only reported when the upstream API is called
+	// through a circuit breaker.
+	Upstream string `json:"upstream,omitempty"`
+	Circuit  string `json:"circuit,omitempty"`
+}
+
+// CacheEntry describes the raw cached entry of a movie
+type CacheEntry struct {
+	Key string `json:"key"`
+	// Source is "saved" for movies created through the API, "tmdb" for details
+	// fetched from TMDB, "legacy" for entries under a pre-namespacing key and
+	// "tombstone" for a cached not-found result.
+	Source string `json:"source"`
+	// TTLSeconds is the remaining lifetime of the entry, or 0 if it never expires.
+	TTLSeconds int64 `json:"ttl_seconds"`
+	Stale      bool  `json:"stale"`
+	// Value is the stored entry as JSON, whatever codec wrote it; it is absent
+	// for tombstones.
+	Value json.RawMessage `json:"value,omitempty" swaggertype:"object"`
+}
+
+// PurgeResponse reports how many cache keys a purge removed
+type PurgeResponse struct {
+	Pattern string `json:"pattern"`
+	Deleted int    `json:"deleted"`
+}
@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+// CacheAdminRepository defines the operations cache operators run on the
+// movie cache.
+type CacheAdminRepository interface {
+	// InspectMovie returns the raw cache entry of a movie, or ErrNotFound.
+	InspectMovie(ctx context.Context, id string) (*models.CacheEntry, error)
+	// EvictMovie removes every cache entry of a movie.
+	EvictMovie(ctx context.Context, id string) error
+	// PurgeCache removes every cache key of this service matching pattern.
+	PurgeCache(ctx context.Context, pattern string) (int, error)
+	// RefreshMovie fetches a movie from the upstream API, replacing its cache entry.
+	RefreshMovie(ctx context.Context, id string) (*models.Movie, error)
+	// ExportSnapshot writes the cached movies, discover pages and saved movies to w.
+	ExportSnapshot(ctx context.Context, w io.Writer, compress bool) (cache.SnapshotResult, error)
+	// ImportSnapshot loads a snapshot written by ExportSnapshot into the cache.
+	ImportSnapshot(ctx context.Context, r io.Reader, overwrite bool) (cache.SnapshotResult, error)
+}
+
+// Sources reported by InspectMovie.
+const (
+	SourceSaved     = "saved"
+	SourceTMDB      = "tmdb"
+	SourceLegacy    = "legacy"
+	SourceTombstone = "tombstone"
+)
+
+// InspectMovie returns the entry stored for the movie in the shared cache,
+// bypassing the process-local tier. The namespaced key is looked up first, then
+// the legacy key if the fallback is enabled, then the not-found tombstone.
+func (r *movieRepositoryImpl) InspectMovie(ctx context.Context, id string) (*models.CacheEntry, error) {
+	if err := validateMovieID(id); err != nil {
+		return nil, err
+	}
+	key := cache.MovieKey(id, r.language)
+	entry, err := r.lookupEntry(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		saved, err := r.isSaved(ctx, id, r.language)
+		if err != nil {
+			return nil, err
+		}
+		source := SourceTMDB
+		if saved {
+			source = SourceSaved
+		}
+		return r.newCacheEntry(key, source, entry, r.ttl.IsMovieStale(entry)), nil
+	}
+
+	if r.legacyKeys && r.language == cache.DefaultLanguage {
+		key = cache.LegacyMovieKey(id)
+		if entry, err = r.lookupEntry(ctx, key); err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			return r.newCacheEntry(key, SourceLegacy, entry, r.ttl.IsMovieStale(entry)), nil
+		}
+	}
+
+	key = cache.MovieTombstoneKey(id, r.language)
+	if entry, err = r.lookupEntry(ctx, key); err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		tombstone := r.newCacheEntry(key, SourceTombstone, entry, false)
+		tombstone.Value = nil
+		return tombstone, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// lookupEntry returns the entry stored under key, or nil if there is none.
+func (r *movieRepositoryImpl) lookupEntry(ctx context.Context, key string) (*cache.Entry, error) {
+	entry, err := r.cache.GetEntry(ctx, key)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return nil, nil
+	}
+	return entry, err
+}
+
+// newCacheEntry describes entry, with its value converted to JSON whatever
+// codec wrote it.
+func (r *movieRepositoryImpl) newCacheEntry(key, source string, entry *cache.Entry, stale bool) *models.CacheEntry {
+	cached := &models.CacheEntry{
+		Key:        key,
+		Source:     source,
+		TTLSeconds: int64(entry.ExpiresIn.Seconds()),
+		Stale:      stale,
+	}
+	if value, err := r.values.ToJSON(entry.Value); err == nil {
+		cached.Value = value
+	} else {
+		// Keep corrupt entries inspectable.
+		cached.Value, _ = json.Marshal(entry.Value)
+	}
+	return cached
+}
+
+// EvictMovie removes the movie, its not-found tombstone, its saved copy and
+// its legacy entry from both cache tiers. Other replicas drop their local copies when the cache
+// announces deletions.
+func (r *movieRepositoryImpl) EvictMovie(ctx context.Context, id string) error {
+	if err := validateMovieID(id); err != nil {
+		return err
+	}
+	if err := r.movies.Delete(ctx, cache.MovieKey(id, r.language)); err != nil {
+		log.Printf("Failed to evict movie with ID %s: %v", id, err)
+		return err
+	}
+	if err := r.cache.DeleteField(ctx, cache.SavedMoviesKey(r.language), id); err != nil {
+		log.Printf("Failed to remove movie with ID %s from the saved movies: %v", id, err)
+		return err
+	}
+	keys := []string{cache.MovieTombstoneKey(id, r.language)}
+	if r.legacyKeys && r.language == cache.DefaultLanguage {
+		keys = append(keys, cache.LegacyMovieKey(id))
+	}
+	for _, key := range keys {
+		if err := r.cache.Delete(ctx, key); err != nil {
+			log.Printf("Failed to evict key %s: %v", key, err)
+			return err
+		}
+	}
+	log.Printf("Movie with ID %s evicted from cache", id)
+	return nil
+}
+
+// PurgeCache removes every key matching pattern from the shared cache and
+// clears the process-local tier. The pattern must start with the key namespace
+// so that a purge never touches keys of other applications sharing the backend;
+// legacy bare-ID keys can only be removed one by one through EvictMovie.
+func (r *movieRepositoryImpl) PurgeCache(ctx context.Context, pattern string) (int, error) {
+	if !strings.HasPrefix(pattern, cache.KeyNamespace+":") {
+		return 0, fmt.Errorf("%w: %q must start with %q", cache.ErrInvalidPattern, pattern, cache.KeyNamespace+":")
+	}
+	n, err := r.cache.DeleteMatching(ctx, pattern)
+	// Some keys may be gone even if the purge failed halfway.
+	r.movies.InvalidateAll()
+	r.pages.InvalidateAll()
+	if err != nil {
+		log.Printf("Failed to purge keys matching %s after deleting %d: %v", pattern, n, err)
+		return n, err
+	}
+	log.Printf("Purged %d keys matching %s", n, pattern)
+	return n, nil
+}
+
+// RefreshMovie fetches the movie from the upstream API and caches it, whether
+// or not a fresh copy is cached. A movie saved through the API is replaced by
+// the upstream version. It joins a fetch already in flight for the same ID.
+func (r *movieRepositoryImpl) RefreshMovie(ctx context.Context, id string) (*models.Movie, error) {
+	if err := validateMovieID(id); err != nil {
+		return nil, err
+	}
+	log.Printf("Forcing refresh of movie ID %s from API...", id)
+	return r.fetchMovieOnce(ctx, id)
+}
+
+// ExportSnapshot writes every cached movie, discover page and saved movie of
+// the shared cache to w.
+func (r *movieRepositoryImpl) ExportSnapshot(ctx context.Context, w io.Writer, compress bool) (cache.SnapshotResult, error) {
+	result, err := cache.ExportSnapshot(ctx, r.cache, w, compress)
+	if err != nil {
+		log.Printf("Failed to export cache snapshot after %d entries: %v", result.Written, err)
+	}
+	return result, err
+}
+
+// ImportSnapshot loads a snapshot into the shared cache and clears the
+// process-local tier, so that imported entries are served right away.
+func (r *movieRepositoryImpl) ImportSnapshot(ctx context.Context, rd io.Reader, overwrite bool) (cache.SnapshotResult, error) {
+	result, err := cache.ImportSnapshot(ctx, r.cache, rd, overwrite, r.snapshotMaxBytes)
+	// Some entries may be stored even if the import failed halfway.
+	r.movies.InvalidateAll()
+	r.pages.InvalidateAll()
+	if err != nil {
+		log.Printf("Failed to import cache snapshot after %d entries: %v", result.Written, err)
+	}
+	return result, err
+}
@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+func TestInspectMovie(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := NewMovieRepository("dummy-auth-token", memoryCache, WithLegacyKeyFallback(true))
+
+	_, err := repo.InspectMovie(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, memoryCache.SetValueWithTTL(context.Background(), cache.MovieTombstoneKey("1", cache.DefaultLanguage), tombstoneValue, time.Minute))
+	entry, err := repo.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceTombstone, entry.Source)
+	assert.Nil(t, entry.Value)
+
+	assert.NoError(t, memoryCache.SetValueWithTTL(context.Background(), cache.LegacyMovieKey("1"), `{"id":1}`, time.Hour))
+	entry, err = repo.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceLegacy, entry.Source)
+	assert.Equal(t, "1", entry.Key)
+
+	assert.NoError(t, memoryCache.SetValueWithTTL(context.Background(), cache.MovieKey("1", cache.DefaultLanguage), `{"id":1}`, 2*time.Hour))
+	entry, err = repo.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceTMDB, entry.Source)
+	assert.Equal(t, "movies:v2:movie:1:en-US", entry.Key)
+	assert.InDelta(t, 7200, entry.TTLSeconds, 1)
+	assert.True(t, entry.Stale, "Expected an entry past the default soft TTL to be stale")
+	assert.JSONEq(t, `{"id":1}`, string(entry.Value))
+
+	assert.NoError(t, repo.SaveMovie(context.Background(), &models.Movie{ID: 1}))
+	entry, err = repo.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceSaved, entry.Source)
+	assert.Equal(t, int64(0), entry.TTLSeconds)
+}
+
+func TestInspectMovie_SourceWithoutExpiry(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := NewMovieRepository("dummy-auth-token", memoryCache, WithTTLPolicy(cache.TTLPolicy{}))
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/1").
+		Times(2).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"id": 1, "title": "From TMDB"})
+
+	// Movies fetched from TMDB never expire either, yet are reported as such.
+	_, _, err := repo.GetMovieByID(context.Background(), "1")
+	assert.NoError(t, err)
+	entry, err := repo.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceTMDB, entry.Source)
+	assert.Equal(t, int64(0), entry.TTLSeconds)
+
+	assert.NoError(t, repo.SaveMovie(context.Background(), &models.Movie{ID: 1, Title: "Saved"}))
+	entry, err = repo.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceSaved, entry.Source)
+
+	_, err = repo.RefreshMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	entry, err = repo.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceTMDB, entry.Source)
+	assert.True(t, gock.IsDone())
+}
+
+func TestInspectMovie_CorruptEntry(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := NewMovieRepository("dummy-auth-token", memoryCache)
+
+	assert.NoError(t, memoryCache.SetValue(context.Background(), cache.MovieKey("1", cache.DefaultLanguage), "not json"))
+
+	entry, err := repo.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `"not json"`, string(entry.Value))
+}
+
+func TestInspectAndEvictMovie_InvalidID(t *testing.T) {
+	repo := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+
+	for _, id := range []string{"abc", "0", "-3", ""} {
+		_, err := repo.InspectMovie(context.Background(), id)
+		assert.ErrorIs(t, err, ErrInvalidInput, id)
+		assert.ErrorIs(t, repo.EvictMovie(context.Background(), id), ErrInvalidInput, id)
+	}
+}
+
+func TestEvictMovie(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := NewMovieRepository("dummy-auth-token", memoryCache, WithLocalCache(10, time.Minute), WithLegacyKeyFallback(true))
+
+	assert.NoError(t, repo.SaveMovie(context.Background(), &models.Movie{ID: 1, Title: "Saved"}))
+	assert.NoError(t, memoryCache.SetValue(context.Background(), cache.LegacyMovieKey("1"), `{"id":1}`))
+	assert.NoError(t, memoryCache.SetValue(context.Background(), cache.MovieTombstoneKey("1", cache.DefaultLanguage), tombstoneValue))
+
+	assert.NoError(t, repo.EvictMovie(context.Background(), "1"))
+
+	assert.Equal(t, 0, memoryCache.Len())
+	_, err := repo.InspectMovie(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPurgeCache(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := NewMovieRepository("dummy-auth-token", memoryCache, WithLocalCache(10, time.Minute))
+
+	assert.NoError(t, repo.SaveMovie(context.Background(), &models.Movie{ID: 1, Title: "Saved"}))
+	assert.NoError(t, memoryCache.SetValue(context.Background(), "unrelated", "keep"))
+
+	_, err := repo.PurgeCache(context.Background(), "*")
+	assert.ErrorIs(t, err, cache.ErrInvalidPattern)
+
+	n, err := repo.PurgeCache(context.Background(), "movies:v2:movie:*")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 2, memoryCache.Len(), "Expected the unrelated key and the saved marker to be kept")
+
+	// The local copy is gone too, so the next read goes to the upstream API.
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/1").
+		Reply(http.StatusNotFound)
+
+	_, _, err = repo.GetMovieByID(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.True(t, gock.IsDone())
+}
+
+func TestRefreshMovie(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := NewMovieRepository("dummy-auth-token", memoryCache, WithLocalCache(10, time.Minute))
+
+	assert.NoError(t, repo.SaveMovie(context.Background(), &models.Movie{ID: 1, Title: "Saved"}))
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/1").
+		Reply(http.StatusOK).
+		JSON(&models.Movie{ID: 1, Title: "From TMDB"})
+
+	movie, err := repo.RefreshMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "From TMDB", movie.Title)
+
+	cached, status, err := repo.GetMovieByID(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, cache.StatusHit, status)
+	assert.Equal(t, "From TMDB", cached.Title)
+
+	entry, err := repo.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceTMDB, entry.Source)
+	var stored models.Movie
+	assert.NoError(t, json.Unmarshal(entry.Value, &stored))
+	assert.Equal(t, "From TMDB", stored.Title)
+}
+
+func TestSnapshot_ExportAndImport(t *testing.T) {
+	source := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+	assert.NoError(t, source.SaveMovie(context.Background(), &models.Movie{ID: 1, Title: "Saved"}))
+
+	var buf bytes.Buffer
+	result, err := source.ExportSnapshot(context.Background(), &buf, true)
+	assert.NoError(t, err)
+	assert.Equal(t, cache.SnapshotResult{Written: 2}, result)
+
+	targetCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	target := NewMovieRepository("dummy-auth-token", targetCache, WithLocalCache(10, time.Minute))
+	assert.NoError(t, targetCache.SetValue(context.Background(), cache.MovieKey("1", cache.DefaultLanguage), `{"id":1,"title":"Old"}`))
+	movie, _, err := target.GetMovieByID(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Old", movie.Title)
+
+	result, err = target.ImportSnapshot(context.Background(), &buf, true)
+	assert.NoError(t, err)
+	assert.Equal(t, cache.SnapshotResult{Written: 2}, result)
+
+	// The local tier is cleared, so the imported entry is served right away.
+	movie, _, err = target.GetMovieByID(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Saved", movie.Title)
+	entry, err := target.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceSaved, entry.Source)
+}
+
+func TestSnapshot_ImportSkipsSavedMovieNotImported(t *testing.T) {
+	source := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+	assert.NoError(t, source.SaveMovie(context.Background(), &models.Movie{ID: 1, Title: "Saved"}))
+	var buf bytes.Buffer
+	_, err := source.ExportSnapshot(context.Background(), &buf, false)
+	assert.NoError(t, err)
+
+	targetCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	target := NewMovieRepository("dummy-auth-token", targetCache)
+	assert.NoError(t, targetCache.SetValue(context.Background(), cache.MovieKey("1", cache.DefaultLanguage), `{"id":1,"title":"Bad Boys"}`))
+
+	result, err := target.ImportSnapshot(context.Background(), &buf, false)
+	assert.NoError(t, err)
+	assert.Equal(t, cache.SnapshotResult{Skipped: 2}, result)
+
+	entry, err := target.InspectMovie(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, SourceTMDB, entry.Source)
+}
+
+func TestSnapshot_ImportLimit(t *testing.T) {
+	source := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+	assert.NoError(t, source.SaveMovie(context.Background(), &models.Movie{ID: 1, Title: "Saved"}))
+	var buf bytes.Buffer
+	_, err := source.ExportSnapshot(context.Background(), &buf, true)
+	assert.NoError(t, err)
+
+	targetCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	target := NewMovieRepository("dummy-auth-token", targetCache, WithSnapshotLimit(32))
+	_, err = target.ImportSnapshot(context.Background(), &buf, true)
+
+	assert.ErrorIs(t, err, cache.ErrSnapshotTooLarge)
+	assert.Equal(t, 0, targetCache.Len())
+}
@@ -0,0 +1,262 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState string
+
+// States of a CircuitBreaker.
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+var circuitStates = []CircuitState{CircuitClosed, CircuitOpen, CircuitHalfOpen}
+
+// breakerBuckets is the number of buckets the failure-rate window is split into.
+const breakerBuckets = 10
+
+// callResult is the outcome of an upstream call let through by the breaker.
+type callResult int
+
+const (
+	callSucceeded callResult = iota
+	callFailed
+	// callIgnored is a call abandoned by its caller; it says nothing about
+	// the health of the upstream API.
+	callIgnored
+)
+
+type breakerBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// CircuitBreaker stops calling the upstream API once too many calls fail.
+// While closed it tracks the failure rate over a rolling window and opens when
+// the rate is reached. While open every call fails fast with
+// ErrUpstreamUnavailable. Once the open timeout elapses it half-opens and lets
+// a few probe calls through: it closes when they all succeed and opens again
+// on the first failure. It is safe for concurrent use and implements
+// prometheus.Collector.
+type CircuitBreaker struct {
+	failureRate float64
+	minRequests int
+	bucketWidth time.Duration
+	openTimeout time.Duration
+	probes      int
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    CircuitState
+	buckets  [breakerBuckets]breakerBucket
+	openedAt time.Time
+	// generation changes with every transition so that calls let through in
+	// an earlier state do not count towards the current one.
+	generation     uint64
+	probing        int
+	probeSuccesses int
+	rejected       uint64
+	opened         uint64
+
+	stateDesc    *prometheus.Desc
+	rejectedDesc *prometheus.Desc
+	openedDesc   *prometheus.Desc
+}
+
+// NewCircuitBreaker creates a closed CircuitBreaker with the breaker settings of cfg.
+func NewCircuitBreaker(cfg *config.UpstreamConfig) *CircuitBreaker {
+	window := cfg.BreakerWindow
+	if window <= 0 {
+		window = 30 * time.Second
+	}
+	probes := cfg.BreakerHalfOpenProbes
+	if probes < 1 {
+		probes = 1
+	}
+	return &CircuitBreaker{
+		failureRate: cfg.BreakerFailureRate,
+		minRequests: cfg.BreakerMinRequests,
+		bucketWidth: window / breakerBuckets,
+		openTimeout: cfg.BreakerOpenTimeout,
+		probes:      probes,
+		now:         time.Now,
+		state:       CircuitClosed,
+		stateDesc: prometheus.NewDesc("movies_upstream_circuit_state",
+			"Current state of the upstream circuit breaker, 1 for the active state.",
+			[]string{"state"}, nil),
+		rejectedDesc: prometheus.NewDesc("movies_upstream_circuit_rejected_total",
+			"Upstream calls failed fast by the circuit breaker.", nil, nil),
+		openedDesc: prometheus.NewDesc("movies_upstream_circuit_opened_total",
+			"Times the upstream circuit breaker opened.", nil, nil),
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.halfOpenIfDue()
+	return b.state
+}
+
+// allow reports whether a call may be made. When it may, the returned function
+// must be called with the outcome of the call.
+func (b *CircuitBreaker) allow() (func(callResult), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.halfOpenIfDue()
+	switch {
+	case b.state == CircuitOpen,
+		b.state == CircuitHalfOpen && b.probing >= b.probes:
+		b.rejected++
+		return nil, ErrUpstreamUnavailable
+	case b.state == CircuitHalfOpen:
+		b.probing++
+	}
+	generation := b.generation
+	var once sync.Once
+	return func(result callResult) {
+		once.Do(func() { b.record(generation, result) })
+	}, nil
+}
+
+func (b *CircuitBreaker) record(generation uint64, result callResult) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	switch b.state {
+	case CircuitClosed:
+		if result == callIgnored {
+			return
+		}
+		bucket := b.bucket()
+		if result == callFailed {
+			bucket.failures++
+			b.openIfTripped()
+		} else {
+			bucket.successes++
+		}
+	case CircuitHalfOpen:
+		b.probing--
+		switch result {
+		case callFailed:
+			b.setState(CircuitOpen)
+		case callSucceeded:
+			b.probeSuccesses++
+			if b.probeSuccesses >= b.probes {
+				b.setState(CircuitClosed)
+			}
+		}
+	}
+}
+
+// bucket returns the bucket of the current time, resetting it when it last
+// counted an earlier period.
+func (b *CircuitBreaker) bucket() *breakerBucket {
+	start := b.now().Truncate(b.bucketWidth)
+	bucket := &b.buckets[int(start.UnixNano()/int64(b.bucketWidth))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+// openIfTripped opens the breaker when the failure rate over the window
+// reached the threshold.
+func (b *CircuitBreaker) openIfTripped() {
+	oldest := b.now().Truncate(b.bucketWidth).Add(-b.bucketWidth * (breakerBuckets - 1))
+	var successes, failures int
+	for _, bucket := range b.buckets {
+		if !bucket.start.Before(oldest) {
+			successes += bucket.successes
+			failures += bucket.failures
+		}
+	}
+	total := successes + failures
+	if total < b.minRequests || total == 0 {
+		return
+	}
+	if float64(failures)/float64(total) >= b.failureRate {
+		log.Printf("Upstream failure rate %d/%d reached the threshold", failures, total)
+		b.setState(CircuitOpen)
+	}
+}
+
+func (b *CircuitBreaker) halfOpenIfDue() {
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		b.setState(CircuitHalfOpen)
+	}
+}
+
+func (b *CircuitBreaker) setState(state CircuitState) {
+	log.Printf("Upstream circuit breaker %s -> %s", b.state, state)
+	b.state = state
+	b.generation++
+	b.probing = 0
+	b.probeSuccesses = 0
+	switch state {
+	case CircuitOpen:
+		b.openedAt = b.now()
+		b.opened++
+	case CircuitClosed:
+		b.buckets = [breakerBuckets]breakerBucket{}
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (b *CircuitBreaker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.stateDesc
+	ch <- b.rejectedDesc
+	ch <- b.openedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (b *CircuitBreaker) Collect(ch chan<- prometheus.Metric) {
+	b.mu.Lock()
+	b.halfOpenIfDue()
+	current, rejected, opened := b.state, b.rejected, b.opened
+	b.mu.Unlock()
+
+	for _, state := range circuitStates {
+		value := 0.0
+		if state == current {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(b.stateDesc, prometheus.GaugeValue, value, string(state))
+	}
+	ch <- prometheus.MustNewConstMetric(b.rejectedDesc, prometheus.CounterValue, float64(rejected))
+	ch <- prometheus.MustNewConstMetric(b.openedDesc, prometheus.CounterValue, float64(opened))
+}
+
+// upstreamResult classifies the outcome of an upstream call. Server errors and
+// rate limiting by the API count as failures; other responses, including 404,
+// show that the API is answering. Calls held back by our own rate limiter did
+// not reach the API.
+func upstreamResult(ctx context.Context, resp *http.Response, err error) callResult {
+	switch {
+	case err != nil && (ctx.Err() != nil || errors.Is(err, ErrRateLimited)):
+		return callIgnored
+	case err != nil:
+		return callFailed
+	case resp.StatusCode >= http.StatusInternalServerError, resp.StatusCode == http.StatusTooManyRequests:
+		return callFailed
+	default:
+		return callSucceeded
+	}
+}
@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+// testClock is a manually advanced clock.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestBreaker(minRequests, probes int) (*CircuitBreaker, *testClock) {
+	clock := &testClock{now: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)}
+	b := NewCircuitBreaker(&config.UpstreamConfig{
+		BreakerFailureRate:    0.5,
+		BreakerMinRequests:    minRequests,
+		BreakerWindow:         10 * time.Second,
+		BreakerOpenTimeout:    5 * time.Second,
+		BreakerHalfOpenProbes: probes,
+	})
+	b.now = clock.Now
+	return b, clock
+}
+
+func call(t *testing.T, b *CircuitBreaker, result callResult) {
+	t.Helper()
+	done, err := b.allow()
+	if assert.NoError(t, err) {
+		done(result)
+	}
+}
+
+func TestCircuitBreaker_OpensAtFailureRate(t *testing.T) {
+	b, _ := newTestBreaker(4, 1)
+
+	call(t, b, callSucceeded)
+	call(t, b, callFailed)
+	call(t, b, callSucceeded)
+	assert.Equal(t, CircuitClosed, b.State(), "Expected the breaker to stay closed below the minimum number of requests")
+
+	call(t, b, callFailed)
+	assert.Equal(t, CircuitOpen, b.State())
+
+	_, err := b.allow()
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+}
+
+func TestCircuitBreaker_StaysClosedBelowFailureRate(t *testing.T) {
+	b, _ := newTestBreaker(4, 1)
+
+	for i := 0; i < 3; i++ {
+		call(t, b, callSucceeded)
+	}
+	call(t, b, callFailed)
+
+	assert.Equal(t, CircuitClosed, b.State())
+}
+
+func TestCircuitBreaker_ForgetsFailuresOutsideWindow(t *testing.T) {
+	b, clock := newTestBreaker(2, 1)
+
+	call(t, b, callFailed)
+	clock.Advance(11 * time.Second)
+	call(t, b, callSucceeded)
+	call(t, b, callSucceeded)
+	call(t, b, callFailed)
+
+	assert.Equal(t, CircuitClosed, b.State(), "Expected failures older than the window to be ignored")
+}
+
+func TestCircuitBreaker_IgnoresAbandonedCalls(t *testing.T) {
+	b, _ := newTestBreaker(1, 1)
+
+	call(t, b, callIgnored)
+
+	assert.Equal(t, CircuitClosed, b.State())
+}
+
+func TestCircuitBreaker_HalfOpenProbes(t *testing.T) {
+	b, clock := newTestBreaker(1, 2)
+	call(t, b, callFailed)
+	assert.Equal(t, CircuitOpen, b.State())
+
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, CircuitHalfOpen, b.State())
+
+	first, err := b.allow()
+	assert.NoError(t, err)
+	second, err := b.allow()
+	assert.NoError(t, err)
+	_, err = b.allow()
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable, "Expected calls beyond the probes to fail fast")
+
+	first(callSucceeded)
+	assert.Equal(t, CircuitHalfOpen, b.State())
+	second(callSucceeded)
+	assert.Equal(t, CircuitClosed, b.State())
+}
+
+func TestCircuitBreaker_ReopensOnFailedProbe(t *testing.T) {
+	b, clock := newTestBreaker(1, 1)
+	call(t, b, callFailed)
+	clock.Advance(5 * time.Second)
+
+	call(t, b, callFailed)
+
+	assert.Equal(t, CircuitOpen, b.State())
+	clock.Advance(4 * time.Second)
+	assert.Equal(t, CircuitOpen, b.State(), "Expected the open timeout to restart")
+}
+
+func TestCircuitBreaker_IgnoresCallsFromEarlierState(t *testing.T) {
+	b, clock := newTestBreaker(2, 1)
+	late, err := b.allow()
+	assert.NoError(t, err)
+	call(t, b, callFailed)
+	call(t, b, callFailed)
+	clock.Advance(5 * time.Second)
+	call(t, b, callSucceeded)
+	assert.Equal(t, CircuitClosed, b.State())
+
+	late(callFailed)
+	call(t, b, callFailed)
+
+	assert.Equal(t, CircuitClosed, b.State(), "Expected a call let through before the breaker opened not to count")
+}
+
+func TestCircuitBreaker_Collect(t *testing.T) {
+	b, _ := newTestBreaker(1, 1)
+	call(t, b, callFailed)
+	_, _ = b.allow()
+
+	expected := `
+# HELP movies_upstream_circuit_opened_total Times the upstream circuit breaker opened.
+# TYPE movies_upstream_circuit_opened_total counter
+movies_upstream_circuit_opened_total 1
+# HELP movies_upstream_circuit_rejected_total Upstream calls failed fast by the circuit breaker.
+# TYPE movies_upstream_circuit_rejected_total counter
+movies_upstream_circuit_rejected_total 1
+# HELP movies_upstream_circuit_state Current state of the upstream circuit breaker, 1 for the active state.
+# TYPE movies_upstream_circuit_state gauge
+movies_upstream_circuit_state{state="closed"} 0
+movies_upstream_circuit_state{state="half-open"} 0
+movies_upstream_circuit_state{state="open"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(b, strings.NewReader(expected)))
+}
+
+func TestUpstreamResult(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	failure := errors.New("connection refused")
+
+	assert.Equal(t, callIgnored, upstreamResult(cancelled, nil, failure))
+	assert.Equal(t, callFailed, upstreamResult(context.Background(), nil, failure))
+	assert.Equal(t, callFailed, upstreamResult(context.Background(), &http.Response{StatusCode: http.StatusServiceUnavailable}, nil))
+	assert.Equal(t, callFailed, upstreamResult(context.Background(), &http.Response{StatusCode: http.StatusTooManyRequests}, nil))
+	assert.Equal(t, callSucceeded, upstreamResult(context.Background(), &http.Response{StatusCode: http.StatusNotFound}, nil))
+}
+
+func TestGetMovieByID_CircuitOpen(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusBadGateway, nil)
+	movieCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, movieCache)
+	breaker, clock := newTestBreaker(1, 1)
+	repo.breaker = breaker
+
+	_, _, err := repo.GetMovieByID(context.Background(), "573435")
+	assert.Error(t, err)
+	assert.Equal(t, CircuitOpen, breaker.State())
+
+	_, _, err = repo.GetMovieByID(context.Background(), "573435")
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	assert.Equal(t, int32(1), calls.Load(), "Expected the open breaker to fail fast")
+
+	clock.Advance(5 * time.Second)
+	movie, _, err := repo.GetMovieByID(context.Background(), "573435")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Movie{ID: 573435}, movie)
+	assert.Equal(t, CircuitClosed, breaker.State())
+}
+
+func TestGetMovieByID_CircuitOpenServesStale(t *testing.T) {
+	srv, calls := flakyServer(t, 0, http.StatusOK, nil)
+	movieCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, movieCache)
+	breaker, _ := newTestBreaker(1, 1)
+	repo.breaker = breaker
+	done, _ := breaker.allow()
+	done(callFailed)
+
+	// Two hours old under the default 1h soft TTL and 24h hard TTL.
+	stale := &models.Movie{ID: 573435, Title: "Bad Boys 4"}
+	assert.NoError(t, repo.movies.Set(context.Background(), cache.MovieKey("573435", cache.DefaultLanguage), stale, 22*time.Hour))
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+	repo.refreshes.Wait()
+
+	assert.NoError(t, err)
+	assert.Equal(t, stale, movie)
+	assert.Equal(t, cache.StatusStale, status)
+	assert.Equal(t, int32(0), calls.Load())
+}
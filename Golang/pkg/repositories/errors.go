@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Errors returned by the repository. Callers match them with errors.Is.
+var (
+	// ErrNotFound is returned when the upstream API does not know the requested movie.
+	ErrNotFound = errors.New("movie not found")
+	// ErrInvalidInput is returned for arguments the upstream API cannot answer,
+	// whether rejected by the repository or by the API.
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrUnauthorized is returned when the upstream API rejects the auth token.
+	ErrUnauthorized = errors.New("upstream API rejected the credentials")
+	// ErrUpstreamUnavailable is returned when the upstream API cannot be
+	// reached, says it is unavailable, or while the circuit breaker is open.
+	ErrUpstreamUnavailable = errors.New("upstream API unavailable")
+	// ErrRateLimited is returned when our request budget or the upstream API's
+	// rate limit does not allow the call.
+	ErrRateLimited = errors.New("upstream rate limit exceeded")
+	// ErrUpstream is matched by every error answer or unreadable response of
+	// the upstream API.
+	ErrUpstream = errors.New("upstream API error")
+)
+
+// UpstreamError is returned when the upstream API answers with an error
+// status. It matches ErrUpstream and the error its status stands for: 404
+// ErrNotFound, 400 and 422 ErrInvalidInput, 401 ErrUnauthorized, 429
+// ErrRateLimited, and 502, 503 and 504 ErrUpstreamUnavailable.
+type UpstreamError struct {
+	StatusCode int
+	// Code and Message are the status_code and status_message of the error
+	// body, when the API sent one.
+	Code    int
+	Message string
+	// RetryAfter is the delay asked for by a Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("upstream API returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("upstream API returned status %d: %s (code %d)", e.StatusCode, e.Message, e.Code)
+}
+
+// Is reports whether target is ErrUpstream or the error of e's status.
+func (e *UpstreamError) Is(target error) bool {
+	switch target {
+	case ErrUpstream:
+		return true
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrInvalidInput:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUpstreamUnavailable:
+		switch e.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// newUpstreamError reads the error answer resp of the upstream API. TMDB
+// describes errors with a body like {"status_code": 34, "status_message": "..."}.
+func newUpstreamError(resp *http.Response) *UpstreamError {
+	upstreamErr := &UpstreamError{StatusCode: resp.StatusCode}
+	var body struct {
+		StatusCode    int    `json:"status_code"`
+		StatusMessage string `json:"status_message"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, maxDrainBytes)).Decode(&body) == nil {
+		upstreamErr.Code, upstreamErr.Message = body.StatusCode, body.StatusMessage
+	}
+	if delay, ok := retryAfter(resp, time.Now()); ok {
+		upstreamErr.RetryAfter = delay
+	}
+	return upstreamErr
+}
+
+// transportError types an error returned by the HTTP client as
+// ErrUpstreamUnavailable. Errors that are already typed and cancellations by
+// the caller are returned unchanged.
+func transportError(ctx context.Context, err error) error {
+	if ctx.Err() != nil || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUpstreamUnavailable) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUpstreamUnavailable, err)
+}
+
+// RetryAfter returns how long a caller should wait before retrying after err,
+// when our rate limiter or the upstream API said so.
+func RetryAfter(err error) (time.Duration, bool) {
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) && rateLimited.RetryAfter > 0 {
+		return rateLimited.RetryAfter, true
+	}
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) && upstreamErr.RetryAfter > 0 {
+		return upstreamErr.RetryAfter, true
+	}
+	return 0, false
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpstreamError_Is(t *testing.T) {
+	tests := []struct {
+		status   int
+		expected error
+	}{
+		{status: http.StatusNotFound, expected: ErrNotFound},
+		{status: http.StatusBadRequest, expected: ErrInvalidInput},
+		{status: http.StatusUnprocessableEntity, expected: ErrInvalidInput},
+		{status: http.StatusUnauthorized, expected: ErrUnauthorized},
+		{status: http.StatusTooManyRequests, expected: ErrRateLimited},
+		{status: http.StatusServiceUnavailable, expected: ErrUpstreamUnavailable},
+		{status: http.StatusGatewayTimeout, expected: ErrUpstreamUnavailable},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("fetching: %w", &UpstreamError{StatusCode: tt.status})
+		assert.ErrorIs(t, err, tt.expected, "Expected status %d to match %v", tt.status, tt.expected)
+		assert.ErrorIs(t, err, ErrUpstream)
+	}
+
+	err := &UpstreamError{StatusCode: http.StatusInternalServerError}
+	for _, target := range []error{ErrNotFound, ErrInvalidInput, ErrUnauthorized, ErrRateLimited, ErrUpstreamUnavailable} {
+		assert.NotErrorIs(t, err, target)
+	}
+}
+
+func TestNewUpstreamError(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Retry-After", "3")
+	w.WriteHeader(http.StatusTooManyRequests)
+	_, _ = w.WriteString(`{"status_code": 25, "status_message": "Your request count (41) is over the allowed limit of 40."}`)
+
+	err := newUpstreamError(w.Result())
+
+	assert.Equal(t, &UpstreamError{
+		StatusCode: http.StatusTooManyRequests,
+		Code:       25,
+		Message:    "Your request count (41) is over the allowed limit of 40.",
+		RetryAfter: 3 * time.Second,
+	}, err)
+}
+
+func TestNewUpstreamError_WithoutBody(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.WriteHeader(http.StatusBadGateway)
+	_, _ = w.WriteString(`<html>Bad Gateway</html>`)
+
+	err := newUpstreamError(w.Result())
+
+	assert.EqualError(t, err, "upstream API returned status 502")
+}
+
+func TestTransportError(t *testing.T) {
+	refused := errors.New("connection refused")
+	err := transportError(context.Background(), refused)
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	assert.ErrorIs(t, err, refused)
+
+	rateLimited := &RateLimitError{RetryAfter: time.Second}
+	assert.Same(t, rateLimited, transportError(context.Background(), rateLimited))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotErrorIs(t, transportError(cancelled, context.Canceled), ErrUpstreamUnavailable)
+}
+
+func TestRetryAfter_Error(t *testing.T) {
+	delay, ok := RetryAfter(fmt.Errorf("wrapped: %w", &RateLimitError{RetryAfter: time.Second}))
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, delay)
+
+	delay, ok = RetryAfter(&UpstreamError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Second})
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, delay)
+
+	_, ok = RetryAfter(&UpstreamError{StatusCode: http.StatusInternalServerError})
+	assert.False(t, ok)
+}
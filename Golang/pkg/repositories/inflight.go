@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// sharedFetches runs a single upstream fetch per key for all concurrent callers.
+// Unlike a plain singleflight.Group, every caller stops waiting as soon as its
+// own context ends, and the fetch itself is cancelled once no caller is left
+// waiting for it.
+type sharedFetches struct {
+	group singleflight.Group
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is the context shared by the callers waiting on the same key.
+type flight struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// do calls fn for key unless a call for key is already in flight, in which case
+// it waits for that call's result. fn receives a context that keeps the values
+// of ctx but is only cancelled when every waiting caller has gone away. shared
+// reports whether the result was handed to more than one caller.
+func (s *sharedFetches) do(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (v interface{}, err error, shared bool) {
+	f := s.join(ctx, key)
+	defer s.leave(key, f)
+
+	ch := s.group.DoChan(key, func() (interface{}, error) {
+		return fn(f.ctx)
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err, res.Shared
+	case <-ctx.Done():
+		return nil, ctx.Err(), false
+	}
+}
+
+func (s *sharedFetches) join(ctx context.Context, key string) *flight {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.flights == nil {
+		s.flights = make(map[string]*flight)
+	}
+	f, ok := s.flights[key]
+	if !ok {
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{ctx: fetchCtx, cancel: cancel}
+		s.flights[key] = f
+	}
+	f.waiters++
+	return f
+}
+
+func (s *sharedFetches) leave(key string, f *flight) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	f.cancel()
+	delete(s.flights, key)
+	// A fetch that is still running was just cancelled; the next caller must
+	// start a new one instead of joining it.
+	s.group.Forget(key)
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedFetches_CallerLeavesOnCancel(t *testing.T) {
+	var fetches sharedFetches
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	type result struct {
+		v      interface{}
+		err    error
+		shared bool
+	}
+	stay := make(chan result, 1)
+	go func() {
+		v, err, shared := fetches.do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+			close(started)
+			select {
+			case <-release:
+				return "value", nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+		stay <- result{v, err, shared}
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err, _ := fetches.do(ctx, "key", func(context.Context) (interface{}, error) {
+		t.Error("Expected the in-flight fetch to be joined")
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The remaining caller still gets the result.
+	close(release)
+	res := <-stay
+	assert.NoError(t, res.err)
+	assert.Equal(t, "value", res.v)
+}
+
+func TestSharedFetches_CancelsFetchWhenAllCallersLeave(t *testing.T) {
+	var fetches sharedFetches
+	cancelled := make(chan struct{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err, _ := fetches.do(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the fetch to be cancelled once its only caller left")
+	}
+
+	// The cancelled fetch is not joined by later callers.
+	v, err, _ := fetches.do(context.Background(), "key", func(context.Context) (interface{}, error) {
+		return "fresh", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", v)
+}
@@ -1,106 +1,582 @@
 package repositories
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
 	"github.com/elberthcabrales/movies-api/pkg/models"
 )
 
+// tombstoneValue is stored under tombstone keys; only their presence matters.
+const tombstoneValue = "1"
+
 // MovieRepository defines the interface for movie-related operations
 type MovieRepository interface {
-	GetMovieByID(id string) (*models.Movie, error)
-	GetMovies(page int) (*models.MovieList, error)
-	SaveMovie(movie *models.Movie) error
+	GetMovieByID(ctx context.Context, id string) (*models.Movie, cache.Status, error)
+	// GetMovies returns a discover page matching the filters of query.
+	GetMovies(ctx context.Context, query models.DiscoverQuery) (*models.MovieList, error)
+	// SearchMovies returns a page of the movies matching query.
+	SearchMovies(ctx context.Context, query models.SearchQuery) (*models.MovieList, error)
+	SaveMovie(ctx context.Context, movie *models.Movie) error
+}
+
+// Repository is the repository built by NewMovieRepository, which serves
+// movies and administers their cache.
+type Repository interface {
+	MovieRepository
+	CacheAdminRepository
 }
 
 type movieRepositoryImpl struct {
-	cache     *cache.RedisCache
+	cache     cache.Cache
+	movies    *cache.TieredCache[*models.Movie]
+	pages     *cache.TieredCache[*models.MovieList]
+	ttl       cache.TTLPolicy
 	apiURL    string
 	client    *http.Client
 	authToken string
+	// breaker, when set, fails upstream calls fast while the API is failing.
+	breaker *CircuitBreaker
+	// upstream, when set, is used to build client once every option applied.
+	upstream *config.UpstreamConfig
+	limiter  RateLimiter
+
+	// inflight deduplicates concurrent upstream fetches for the same resource.
+	inflight sharedFetches
+
+	// refreshing holds the IDs with a background refresh in flight and
+	// refreshes tracks those goroutines.
+	refreshing sync.Map
+	refreshes  sync.WaitGroup
+
+	l1MaxEntries int
+	l1TTL        time.Duration
+	invalidator  cache.Invalidator
+	metrics      *cache.Metrics
+
+	// values serializes movies and discover pages; movieCodec is built on it.
+	values     *cache.ValueCodec
+	movieCodec cache.Codec[*models.Movie]
+
+	// language is requested from the upstream API and is part of every movie key.
+	language string
+	// legacyKeys enables reading movies stored under bare-ID keys.
+	legacyKeys bool
+	// snapshotMaxBytes bounds the decompressed size of imported snapshots.
+	snapshotMaxBytes int64
+}
+
+// Option configures optional behaviour of the movie repository.
+type Option func(*movieRepositoryImpl)
+
+// WithTTLPolicy sets the expiration used for entries fetched from the upstream API.
+func WithTTLPolicy(policy cache.TTLPolicy) Option {
+	return func(r *movieRepositoryImpl) {
+		r.ttl = policy
+	}
+}
+
+// WithLocalCache keeps up to maxEntries decoded movies in process memory for at
+// most ttl in front of the shared cache.
+func WithLocalCache(maxEntries int, ttl time.Duration) Option {
+	return func(r *movieRepositoryImpl) {
+		r.l1MaxEntries = maxEntries
+		r.l1TTL = ttl
+	}
+}
+
+// WithInvalidator drops movies from the local cache when other replicas announce
+// changes through invalidator.
+func WithInvalidator(invalidator cache.Invalidator) Option {
+	return func(r *movieRepositoryImpl) {
+		r.invalidator = invalidator
+	}
+}
+
+// WithMetrics records hits served from the local cache and stale serves in
+// metrics. Lookups in the shared cache are recorded by wrapping it in a
+// cache.InstrumentedCache using the same metrics.
+func WithMetrics(metrics *cache.Metrics) Option {
+	return func(r *movieRepositoryImpl) {
+		r.metrics = metrics
+	}
 }
 
-// NewMovieRepository creates a new instance of MovieRepository with the provided authentication token and cache.
-func NewMovieRepository(authToken string, cache *cache.RedisCache) MovieRepository {
-	return &movieRepositoryImpl{
-		cache:     cache,
-		apiURL:    "https://api.themoviedb.org/3",
-		client:    &http.Client{},
+// WithValueCodec sets how movies and discover pages are serialized in the
+// shared cache. Values written by any codec are read. Defaults to plain JSON.
+func WithValueCodec(codec *cache.ValueCodec) Option {
+	return func(r *movieRepositoryImpl) {
+		r.values = codec
+	}
+}
+
+// WithUpstream sets the base URL, HTTP client and, when not empty, the auth
+// token used to call the upstream API.
+func WithUpstream(cfg *config.UpstreamConfig) Option {
+	return func(r *movieRepositoryImpl) {
+		r.apiURL = cfg.BaseURL
+		r.upstream = cfg
+		r.client = nil
+		if cfg.Token != "" {
+			r.authToken = cfg.Token
+		}
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to call the upstream API, e.g. to
+// add instrumentation or route requests through a custom transport.
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *movieRepositoryImpl) {
+		r.client = client
+		r.upstream = nil
+	}
+}
+
+// WithRateLimiter makes every upstream request, retries included, wait for
+// limiter. Requests it holds back fail with a RateLimitError.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(r *movieRepositoryImpl) {
+		r.limiter = limiter
+	}
+}
+
+// WithCircuitBreaker routes every upstream call through breaker. While it is
+// open, cache misses fail with ErrUpstreamUnavailable and stale entries are
+// served without being refreshed.
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(r *movieRepositoryImpl) {
+		r.breaker = breaker
+	}
+}
+
+// WithLanguage sets the language movie details are requested in. An empty
+// language keeps the default, cache.DefaultLanguage.
+func WithLanguage(language string) Option {
+	return func(r *movieRepositoryImpl) {
+		if language != "" {
+			r.language = language
+		}
+	}
+}
+
+// WithLegacyKeyFallback makes cache misses look the movie up under the bare-ID
+// key used by earlier releases and migrate it to the namespaced key.
+func WithLegacyKeyFallback(enabled bool) Option {
+	return func(r *movieRepositoryImpl) {
+		r.legacyKeys = enabled
+	}
+}
+
+// WithSnapshotLimit rejects imported snapshots of more than maxBytes once
+// decompressed with cache.ErrSnapshotTooLarge. Zero means unlimited.
+func WithSnapshotLimit(maxBytes int64) Option {
+	return func(r *movieRepositoryImpl) {
+		r.snapshotMaxBytes = maxBytes
+	}
+}
+
+// NewMovieRepository creates a new instance of Repository with the provided authentication token and cache.
+func NewMovieRepository(authToken string, movieCache cache.Cache, opts ...Option) Repository {
+	r := &movieRepositoryImpl{
+		cache:     movieCache,
+		ttl:       cache.DefaultTTLPolicy(),
+		apiURL:    config.DefaultUpstreamURL,
+		client:    &http.Client{Timeout: config.DefaultUpstreamTimeout},
 		authToken: authToken,
+		language:  cache.DefaultLanguage,
+		values:    cache.JSONValueCodec(),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	switch {
+	case r.upstream != nil:
+		r.client = newUpstreamClient(r.upstream, r.limiter)
+	case r.limiter != nil:
+		r.client = withRateLimit(r.client, r.limiter)
+	}
+	r.movieCodec = cache.NewCodec[models.Movie](r.values)
+	r.movies = cache.NewTieredCache(movieCache, r.movieCodec, cache.TieredOptions{
+		L1MaxEntries: r.l1MaxEntries,
+		L1TTL:        r.l1TTL,
+		IsStale:      r.ttl.IsMovieStale,
+		Metrics:      r.metrics,
+	})
+	r.pages = cache.NewTieredCache(movieCache, cache.NewCodec[models.MovieList](r.values), cache.TieredOptions{
+		L1MaxEntries: r.l1MaxEntries,
+		L1TTL:        r.l1TTL,
+		Metrics:      r.metrics,
+	})
+	if r.invalidator != nil {
+		r.invalidator.Subscribe(r.movies)
+		r.invalidator.Subscribe(r.pages)
+	}
+	return r
 }
 
-func (r *movieRepositoryImpl) GetMovieByID(id string) (*models.Movie, error) {
+// GetMovieByID returns the movie from the cache when present. Entries older than
+// the soft TTL are returned as stale while a background refresh is started; only
+// a cache miss waits on the upstream API. An unreachable cache counts as a miss.
+func (r *movieRepositoryImpl) GetMovieByID(ctx context.Context, id string) (*models.Movie, cache.Status, error) {
+	if err := validateMovieID(id); err != nil {
+		return nil, cache.StatusMiss, err
+	}
 	// Check if the movie exists in the cache
+	key := cache.MovieKey(id, r.language)
 	log.Printf("Fetching movie with ID %s from cache...", id)
-	cachedMovie, err := r.cache.GetValue(id)
-	if err == nil && cachedMovie != "" {
-		log.Printf("Cache hit for movie ID %s", id)
-		var movie models.Movie
-		err = json.Unmarshal([]byte(cachedMovie), &movie)
-		if err != nil {
-			log.Printf("Failed to unmarshal cached movie data for ID %s: %v", id, err)
-			return nil, err
+	hit, err := r.movies.Get(ctx, key)
+	if err == nil {
+		if hit.Stale {
+			// A refresh may have learned that the upstream API no longer
+			// knows the movie; the stale copy is not served in that case.
+			if r.isTombstoned(ctx, id) {
+				log.Printf("Cached not-found result for stale movie ID %s", id)
+				return nil, cache.StatusHit, fmt.Errorf("%w: %s", ErrNotFound, id)
+			}
+			log.Printf("Stale cache hit for movie ID %s, refreshing in background", id)
+			r.refreshMovie(ctx, id)
+			return hit.Value, cache.StatusStale, nil
 		}
-		return &movie, nil
+		log.Printf("Cache hit for movie ID %s (%s)", id, hit.Tier)
+		return hit.Value, cache.StatusHit, nil
+	}
+	if errors.Is(err, cache.ErrCorruptEntry) {
+		r.dropCorruptEntry(ctx, key, err)
+	}
+	if movie, entry, ok := r.migrateLegacyMovie(ctx, id, key); ok {
+		if r.ttl.IsMovieStale(entry) {
+			if r.isTombstoned(ctx, id) {
+				return nil, cache.StatusHit, fmt.Errorf("%w: %s", ErrNotFound, id)
+			}
+			r.refreshMovie(ctx, id)
+			return movie, cache.StatusStale, nil
+		}
+		return movie, cache.StatusHit, nil
+	}
+	if r.isTombstoned(ctx, id) {
+		log.Printf("Cached not-found result for movie ID %s", id)
+		return nil, cache.StatusHit, fmt.Errorf("%w: %s", ErrNotFound, id)
 	}
 	log.Printf("Cache miss for movie ID %s. Fetching from API...", id)
-	url := fmt.Sprintf("%s/movie/%s", r.apiURL, id)
-	req, err := http.NewRequest("GET", url, nil)
+	movie, err := r.fetchMovieOnce(ctx, id)
+	return movie, cache.StatusMiss, err
+}
+
+// dropCorruptEntry deletes a cached value that cannot be decoded, so that the
+// caller can handle the lookup as a miss and fetch the value again.
+func (r *movieRepositoryImpl) dropCorruptEntry(ctx context.Context, key string, err error) {
+	log.Printf("Dropping corrupt cache entry: %v", err)
+	if err := r.cache.Delete(ctx, key); err != nil {
+		log.Printf("Failed to delete corrupt cache entry %s: %v", key, err)
+	}
+}
+
+// validateMovieID checks that id is a TMDB movie ID: a positive integer.
+func validateMovieID(id string) error {
+	if n, err := strconv.Atoi(id); err != nil || n <= 0 {
+		return fmt.Errorf("%w: movie ID %q", ErrInvalidInput, id)
+	}
+	return nil
+}
+
+// isTombstoned reports whether the upstream API recently answered that it does
+// not know the movie.
+func (r *movieRepositoryImpl) isTombstoned(ctx context.Context, id string) bool {
+	if r.ttl.NotFound <= 0 {
+		return false
+	}
+	_, err := r.cache.GetValue(ctx, cache.MovieTombstoneKey(id, r.language))
+	if err != nil && !errors.Is(err, cache.ErrCacheMiss) {
+		log.Printf("Failed to read tombstone for movie ID %s: %v", id, err)
+	}
+	return err == nil
+}
+
+// tombstone records that the upstream API does not know the movie, so that
+// lookups for the same ID are answered from the cache until NotFound elapses.
+func (r *movieRepositoryImpl) tombstone(ctx context.Context, id string) {
+	if r.ttl.NotFound <= 0 {
+		return
+	}
+	err := r.cache.SetValueWithTTL(ctx, cache.MovieTombstoneKey(id, r.language), tombstoneValue, r.ttl.NotFound)
+	if err != nil {
+		log.Printf("Failed to cache not-found result for movie ID %s: %v", id, err)
+	}
+}
+
+// isSaved reports whether the movie cached under id in language was saved
+// through the API rather than fetched from the upstream API.
+func (r *movieRepositoryImpl) isSaved(ctx context.Context, id, language string) (bool, error) {
+	_, err := r.cache.GetField(ctx, cache.SavedMoviesKey(language), id)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// clearSaved removes the movie from the index of saved movies in language. It
+// is called after every upstream write, so that an index entry left behind by
+// an evicted saved movie never labels upstream data.
+func (r *movieRepositoryImpl) clearSaved(ctx context.Context, id, language string) {
+	if err := r.cache.DeleteField(ctx, cache.SavedMoviesKey(language), id); err != nil {
+		log.Printf("Failed to remove movie ID %s from the saved movies: %v", id, err)
+	}
+}
+
+// migrateLegacyMovie looks the movie up under the bare-ID key written by earlier
+// releases and copies it to key with the same remaining lifetime. The legacy
+// entry is left in place for replicas that still read it; it goes away when it
+// expires. Legacy entries carry no language and are only used for the default one.
+func (r *movieRepositoryImpl) migrateLegacyMovie(ctx context.Context, id, key string) (*models.Movie, *cache.Entry, bool) {
+	if !r.legacyKeys || r.language != cache.DefaultLanguage {
+		return nil, nil, false
+	}
+	entry, err := r.cache.GetEntry(ctx, cache.LegacyMovieKey(id))
+	if err != nil {
+		if !errors.Is(err, cache.ErrCacheMiss) {
+			log.Printf("Failed to read legacy cache entry for movie ID %s: %v", id, err)
+		}
+		return nil, nil, false
+	}
+	movie, err := r.movieCodec.Decode(entry.Value)
+	if err != nil {
+		log.Printf("Ignoring unreadable legacy cache entry for movie ID %s: %v", id, err)
+		return nil, nil, false
+	}
+	if err := r.movies.Set(ctx, key, movie, entry.ExpiresIn); err != nil {
+		log.Printf("Failed to migrate legacy cache entry for movie ID %s: %v", id, err)
+	} else {
+		log.Printf("Migrated legacy cache entry for movie ID %s to %s", id, key)
+	}
+	return movie, entry, true
+}
+
+// fetchMovieOnce fetches the movie from the upstream API, sharing a single
+// request and its result or error among all concurrent callers for the same ID.
+// The request is cancelled once every caller waiting for it has gone away.
+func (r *movieRepositoryImpl) fetchMovieOnce(ctx context.Context, id string) (*models.Movie, error) {
+	v, err, shared := r.inflight.do(ctx, cache.MovieKey(id, r.language), func(ctx context.Context) (interface{}, error) {
+		return r.fetchMovie(ctx, id)
+	})
+	if shared {
+		log.Printf("Shared in-flight API request for movie ID %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v.(*models.Movie), nil
+}
+
+// refreshMovie fetches the movie from the upstream API in the background,
+// unless a refresh for the same ID is already running. The refresh joins any
+// in-flight fetch started by a concurrent cache miss. It outlives the request
+// that triggered it, so it is not cancelled with ctx.
+func (r *movieRepositoryImpl) refreshMovie(ctx context.Context, id string) {
+	if r.breaker != nil && r.breaker.State() == CircuitOpen {
+		log.Printf("Upstream circuit open, not refreshing movie ID %s", id)
+		return
+	}
+	if _, running := r.refreshing.LoadOrStore(id, struct{}{}); running {
+		return
+	}
+	refreshCtx := context.WithoutCancel(ctx)
+	r.refreshes.Add(1)
+	go func() {
+		defer r.refreshes.Done()
+		defer r.refreshing.Delete(id)
+		if _, err := r.fetchMovieOnce(refreshCtx, id); err != nil {
+			log.Printf("Background refresh failed for movie ID %s: %v", id, err)
+		}
+	}()
+}
+
+// fetchMovie retrieves the movie from the upstream API and stores it in the cache.
+func (r *movieRepositoryImpl) fetchMovie(ctx context.Context, id string) (*models.Movie, error) {
+	movieURL := fmt.Sprintf("%s/movie/%s?language=%s", r.apiURL, id, url.QueryEscape(r.language))
+	req, err := http.NewRequestWithContext(ctx, "GET", movieURL, nil)
 	if err != nil {
 		log.Printf("Failed to create HTTP request for movie ID %s: %v", id, err)
 		return nil, err
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.authToken))
-	resp, err := r.client.Do(req)
+	resp, err := r.doUpstream(req)
 	if err != nil {
 		log.Printf("Failed to fetch movie with ID %s from API: %v", id, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		log.Printf("API does not know movie ID %s: %v", id, newUpstreamError(resp))
+		// A stale copy left behind would be served, and refreshed, again on
+		// every request.
+		if err := r.movies.Delete(ctx, cache.MovieKey(id, r.language)); err != nil {
+			log.Printf("Failed to evict movie ID %s unknown to the API: %v", id, err)
+		}
+		r.clearSaved(ctx, id, r.language)
+		r.tombstone(ctx, id)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("API returned non-200 status code for movie ID %s: %d", id, resp.StatusCode)
-		return nil, fmt.Errorf("failed to fetch movie from API, status code: %d", resp.StatusCode)
+		upstreamErr := newUpstreamError(resp)
+		log.Printf("API returned an error for movie ID %s: %v", id, upstreamErr)
+		return nil, upstreamErr
 	}
 
 	var movie models.Movie
 	err = json.NewDecoder(resp.Body).Decode(&movie)
 	if err != nil {
 		log.Printf("Failed to decode API response for movie ID %s: %v", id, err)
-		return nil, err
+		return nil, fmt.Errorf("%w: decoding movie %s: %w", ErrUpstream, id, err)
 	}
 
-	movieJSON, err := json.Marshal(movie)
-	if err != nil {
-		log.Printf("Failed to marshal movie data for caching: %v", err)
-		return nil, err
-	}
-	err = r.cache.SetValue(id, string(movieJSON))
+	// The movie is served even when it cannot be cached; the next request
+	// simply fetches it again.
+	err = r.movies.Set(ctx, cache.MovieKey(id, r.language), &movie, r.ttl.Movie)
 	if err != nil {
 		log.Printf("Failed to cache movie data for ID %s: %v", id, err)
-		return nil, err
+		return &movie, nil
 	}
+	r.clearSaved(ctx, id, r.language)
 
 	log.Printf("Movie with ID %s fetched and cached successfully", id)
 	return &movie, nil
 }
 
-func (r *movieRepositoryImpl) GetMovies(page int) (*models.MovieList, error) {
-	url := fmt.Sprintf("%s/discover/movie?page=%d", r.apiURL, page)
-	log.Printf("Fetching movies from URL: %s", url)
-	req, err := http.NewRequest("GET", url, nil)
+// doUpstream sends req to the upstream API through the circuit breaker, if
+// any. Transport errors are typed as ErrUpstreamUnavailable.
+func (r *movieRepositoryImpl) doUpstream(req *http.Request) (*http.Response, error) {
+	var done func(callResult)
+	if r.breaker != nil {
+		var err error
+		if done, err = r.breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := r.client.Do(req)
+	if done != nil {
+		done(upstreamResult(req.Context(), resp, err))
+	}
+	if err != nil {
+		return nil, transportError(req.Context(), err)
+	}
+	return resp, nil
+}
+
+// GetMovies returns a discover page from the cache when present, otherwise from
+// the upstream API. Pages are cached per filter combination. Concurrent misses
+// for the same page share a single upstream call.
+func (r *movieRepositoryImpl) GetMovies(ctx context.Context, query models.DiscoverQuery) (*models.MovieList, error) {
+	params := discoverParams(query)
+	key := cache.DiscoverKey(r.language, params)
+
+	hit, err := r.pages.Get(ctx, key)
+	if err == nil {
+		log.Printf("Cache hit for discover page %s (%s)", params.Encode(), hit.Tier)
+		return hit.Value, nil
+	}
+	if errors.Is(err, cache.ErrCorruptEntry) {
+		r.dropCorruptEntry(ctx, key, err)
+	}
+
+	log.Printf("Cache miss for discover page %s. Fetching from API...", params.Encode())
+	v, err, _ := r.inflight.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return r.fetchMovies(ctx, key, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*models.MovieList), nil
+}
+
+// discoverParams returns the parameters of TMDB's /discover/movie for query.
+// Only the filters that are set are included, and genres are sorted, so that
+// equivalent queries share a cache key.
+func discoverParams(query models.DiscoverQuery) url.Values {
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(max(query.Page, 1)))
+	if len(query.GenreIDs) > 0 {
+		ids := slices.Clone(query.GenreIDs)
+		slices.Sort(ids)
+		ids = slices.Compact(ids)
+		genres := make([]string, len(ids))
+		for i, id := range ids {
+			genres[i] = strconv.Itoa(id)
+		}
+		params.Set("with_genres", strings.Join(genres, ","))
+	}
+	setInt := func(name string, value int) {
+		if value > 0 {
+			params.Set(name, strconv.Itoa(value))
+		}
+	}
+	setString := func(name, value string) {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	setInt("primary_release_year", query.Year)
+	setString("primary_release_date.gte", query.ReleaseDateFrom)
+	setString("primary_release_date.lte", query.ReleaseDateTo)
+	if query.MinVoteAverage > 0 {
+		params.Set("vote_average.gte", strconv.FormatFloat(query.MinVoteAverage, 'f', -1, 64))
+	}
+	setInt("vote_count.gte", query.MinVoteCount)
+	setInt("with_runtime.gte", query.MinRuntime)
+	setInt("with_runtime.lte", query.MaxRuntime)
+	setString("with_original_language", query.OriginalLanguage)
+	setString("region", query.Region)
+	setString("sort_by", query.SortBy)
+	if query.IncludeAdult {
+		params.Set("include_adult", "true")
+	}
+	return params
+}
+
+// fetchMovies retrieves a discover page from the upstream API, caches it under
+// key and seeds the per-movie cache with its results.
+func (r *movieRepositoryImpl) fetchMovies(ctx context.Context, key string, params url.Values) (*models.MovieList, error) {
+	response, err := r.fetchList(ctx, "discover/movie", r.language, params)
+	if err != nil {
+		return nil, err
+	}
+	err = r.pages.Set(ctx, key, response, r.ttl.Discover)
+	if err != nil {
+		log.Printf("Failed to cache discover page %s: %v", key, err)
+		return response, nil
+	}
+	r.seedMovies(ctx, r.language, response.Results)
+	return response, nil
+}
+
+// fetchList retrieves a list of movies from path of the upstream API, e.g.
+// "discover/movie", in the given language.
+func (r *movieRepositoryImpl) fetchList(ctx context.Context, path, language string, params url.Values) (*models.MovieList, error) {
+	query := url.Values{"language": {language}}
+	for name, values := range params {
+		query[name] = values
+	}
+	listURL := fmt.Sprintf("%s/%s?%s", r.apiURL, path, query.Encode())
+	log.Printf("Fetching movies from URL: %s", listURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", listURL, nil)
 	if err != nil {
 		log.Printf("Failed to create HTTP request for movies: %v", err)
 		return nil, err
 	}
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", r.authToken))
-	resp, err := r.client.Do(req)
+	resp, err := r.doUpstream(req)
 	if err != nil {
 		log.Printf("Failed to fetch movies from API: %v", err)
 		return nil, err
@@ -108,36 +584,84 @@ func (r *movieRepositoryImpl) GetMovies(page int) (*models.MovieList, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("API returned non-200 status code for movies: %d", resp.StatusCode)
-		return nil, fmt.Errorf("failed to fetch movies from API, status code: %d", resp.StatusCode)
+		upstreamErr := newUpstreamError(resp)
+		log.Printf("API returned an error for movies: %v", upstreamErr)
+		return nil, upstreamErr
 	}
 
 	var response models.MovieList
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
 		log.Printf("Failed to decode API response for movies: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("%w: decoding %s response: %w", ErrUpstream, path, err)
 	}
 
 	log.Printf("Successfully fetched movies from API: %v", response)
 	return &response, nil
 }
 
-// Note: if the movie exists in the cache, it will be overwritten with the new data
-func (r *movieRepositoryImpl) SaveMovie(movie *models.Movie) error {
-	// Serialize the movie
-	movieJSON, err := json.Marshal(movie)
-	if err != nil {
-		log.Printf("Failed to marshal movie data for ID %d: %v", movie.ID, err)
-		return err
+// seedMovies stores the movies of a discover page or search results as partial
+// movie details in the given language.
+// They are stale as soon as they are written, so a detail lookup is answered
+// right away while the full record is fetched in the background. Movies that
+// are already cached are left untouched.
+func (r *movieRepositoryImpl) seedMovies(ctx context.Context, language string, movies []models.Movie) {
+	ttl, ok := r.ttl.PartialMovieTTL()
+	if !ok {
+		return
+	}
+	seeded := 0
+	for i := range movies {
+		movie := &movies[i]
+		encoded, err := r.movieCodec.Encode(movie)
+		if err != nil {
+			log.Printf("Failed to encode partial movie data for ID %d: %v", movie.ID, err)
+			continue
+		}
+		added, err := r.cache.SetValueIfAbsent(ctx, cache.MovieKey(strconv.Itoa(movie.ID), language), encoded, ttl)
+		if err != nil {
+			log.Printf("Failed to seed partial movie data for ID %d: %v", movie.ID, err)
+			continue
+		}
+		if added {
+			r.clearSaved(ctx, strconv.Itoa(movie.ID), language)
+			seeded++
+		}
+	}
+	log.Printf("Seeded %d of %d movies from movie list", seeded, len(movies))
+}
+
+// Note: if the movie exists in the cache, it will be overwritten with the new data.
+// Saved movies are not subject to the TTL policy and never expire, and are
+// indexed as saved until the upstream version replaces them. A cached not-found
+// result for the same ID is cleared.
+func (r *movieRepositoryImpl) SaveMovie(ctx context.Context, movie *models.Movie) error {
+	if movie.ID <= 0 {
+		return fmt.Errorf("%w: movie ID %d", ErrInvalidInput, movie.ID)
 	}
 	id := strconv.Itoa(movie.ID)
-	// Save to cache
-	err = r.cache.SetValue(id, string(movieJSON))
+	// Save to both cache tiers
+	err := r.movies.Set(ctx, cache.MovieKey(id, r.language), movie, 0)
 	if err != nil {
 		log.Printf("Failed to save movie with ID %d to cache: %v", movie.ID, err)
 		return err
 	}
+	// The index holds a copy of the movie, so that searches read every saved
+	// movie at once. Saving again is harmless, so a client can retry if the
+	// index was not updated.
+	encoded, err := r.movieCodec.Encode(movie)
+	if err == nil {
+		err = r.cache.SetField(ctx, cache.SavedMoviesKey(r.language), id, encoded)
+	}
+	if err != nil {
+		log.Printf("Failed to index saved movie with ID %d: %v", movie.ID, err)
+		return err
+	}
+	// The movie is already readable since its key is checked first; a leftover
+	// tombstone only resurfaces once the movie is deleted.
+	if err := r.cache.Delete(ctx, cache.MovieTombstoneKey(id, r.language)); err != nil {
+		log.Printf("Failed to clear not-found result for movie ID %d: %v", movie.ID, err)
+	}
 
 	log.Printf("Movie with ID %d saved to cache successfully", movie.ID)
 	return nil
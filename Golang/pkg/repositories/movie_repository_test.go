@@ -1,21 +1,29 @@
 package repositories
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redismock/v8"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/h2non/gock.v1"
 
 	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
 	"github.com/elberthcabrales/movies-api/pkg/models"
 )
 
 func TestGetMovieByID_CacheHit(t *testing.T) {
 	db, mock := redismock.NewClientMock()
-	redisCache := cache.NewRedisCache(db, nil)
+	redisCache, _ := cache.NewRedisCache(db, nil)
 
 	expectedMovie := &models.Movie{
 		ID:    573435,
@@ -24,54 +32,157 @@ func TestGetMovieByID_CacheHit(t *testing.T) {
 
 	movieJSON, _ := json.Marshal(expectedMovie)
 
-	mock.ExpectGet("573435").SetVal(string(movieJSON))
+	mock.ExpectGet("movies:v2:movie:573435:en-US").SetVal(string(movieJSON))
+	mock.ExpectPTTL("movies:v2:movie:573435:en-US").SetVal(23*time.Hour + 30*time.Minute)
 
 	repo := NewMovieRepository("dummy-auth-token", redisCache)
 
-	movie, err := repo.GetMovieByID("573435")
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedMovie, movie)
+	assert.Equal(t, cache.StatusHit, status)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovieByID_StaleWhileRevalidate(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache, _ := cache.NewRedisCache(db, nil)
+
+	staleMovie := &models.Movie{ID: 573435, Title: "Bad Boys 4"}
+	freshMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+
+	staleJSON, _ := json.Marshal(staleMovie)
+	freshJSON, _ := json.Marshal(freshMovie)
+
+	// Two hours old under a 1h soft TTL and a 24h hard TTL.
+	mock.ExpectGet("movies:v2:movie:573435:en-US").SetVal(string(staleJSON))
+	mock.ExpectPTTL("movies:v2:movie:573435:en-US").SetVal(22 * time.Hour)
+	mock.ExpectGet("movies:v2:tombstone:movie:573435:en-US").RedisNil()
+	mock.ExpectSet("movies:v2:movie:573435:en-US", string(freshJSON), 24*time.Hour).SetVal("OK")
+	mock.ExpectHDel("movies:v2:saved:movie:en-US", "573435").SetVal(0)
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/573435").
+		Reply(200).
+		JSON(freshJSON)
+
+	repo := NewMovieRepository("dummy-auth-token", redisCache)
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, staleMovie, movie)
+	assert.Equal(t, cache.StatusStale, status)
+
+	repo.(*movieRepositoryImpl).refreshes.Wait()
+
+	assert.True(t, gock.IsDone())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovieByID_SavedMovieNeverStale(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache, _ := cache.NewRedisCache(db, nil)
+
+	savedMovie := &models.Movie{ID: 200002, Title: "Test Movie 2"}
+	movieJSON, _ := json.Marshal(savedMovie)
+
+	mock.ExpectGet("movies:v2:movie:200002:en-US").SetVal(string(movieJSON))
+	mock.ExpectPTTL("movies:v2:movie:200002:en-US").SetVal(-1)
+
+	repo := NewMovieRepository("dummy-auth-token", redisCache)
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "200002")
+
+	assert.NoError(t, err)
+	assert.Equal(t, savedMovie, movie)
+	assert.Equal(t, cache.StatusHit, status)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetMovieByID_APIHit(t *testing.T) {
 	db, mock := redismock.NewClientMock()
-	redisCache := cache.NewRedisCache(db, nil)
+	redisCache, _ := cache.NewRedisCache(db, nil)
 
 	expectedMovie := &models.Movie{
 		ID:    573435,
 		Title: "Bad Boys: Ride or Die",
 	}
 
-	mock.ExpectGet("573435").RedisNil()
+	mock.ExpectGet("movies:v2:movie:573435:en-US").RedisNil()
+	mock.ExpectGet("movies:v2:tombstone:movie:573435:en-US").RedisNil()
 
 	apiResponse, _ := json.Marshal(expectedMovie)
 	defer gock.Off()
 	gock.New("https://api.themoviedb.org/3").
 		Get("/movie/573435").
+		MatchParam("language", "en-US").
 		MatchHeader("Authorization", "Bearer dummy-auth-token").
 		Reply(200).
 		JSON(apiResponse)
 
 	repo := NewMovieRepository("dummy-auth-token", redisCache)
 
-	mock.ExpectSet("573435", string(apiResponse), 0).SetVal("OK")
+	mock.ExpectSet("movies:v2:movie:573435:en-US", string(apiResponse), cache.DefaultTTLPolicy().Movie).SetVal("OK")
+	mock.ExpectHDel("movies:v2:saved:movie:en-US", "573435").SetVal(0)
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovie, movie)
+	assert.Equal(t, cache.StatusMiss, status)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovieByID_APIHit_CustomTTL(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache, _ := cache.NewRedisCache(db, nil)
+
+	expectedMovie := &models.Movie{
+		ID:    573435,
+		Title: "Bad Boys: Ride or Die",
+	}
+
+	mock.ExpectGet("movies:v2:movie:573435:en-US").RedisNil()
+
+	apiResponse, _ := json.Marshal(expectedMovie)
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/573435").
+		Reply(200).
+		JSON(apiResponse)
+
+	policy := cache.TTLPolicy{Movie: 10 * time.Minute}
+	repo := NewMovieRepository("dummy-auth-token", redisCache, WithTTLPolicy(policy))
+
+	mock.ExpectSet("movies:v2:movie:573435:en-US", string(apiResponse), 10*time.Minute).SetVal("OK")
+	mock.ExpectHDel("movies:v2:saved:movie:en-US", "573435").SetVal(0)
 
-	movie, err := repo.GetMovieByID("573435")
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedMovie, movie)
+	assert.Equal(t, cache.StatusMiss, status)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
 func TestGetMovieByID_APINotFound(t *testing.T) {
 	db, mock := redismock.NewClientMock()
-	redisCache := cache.NewRedisCache(db, nil)
+	redisCache, _ := cache.NewRedisCache(db, nil)
 
-	mock.ExpectGet("573435").RedisNil()
+	mock.ExpectGet("movies:v2:movie:573435:en-US").RedisNil()
+	mock.ExpectGet("movies:v2:tombstone:movie:573435:en-US").RedisNil()
+	mock.ExpectDel("movies:v2:movie:573435:en-US").SetVal(0)
+	mock.ExpectHDel("movies:v2:saved:movie:en-US", "573435").SetVal(0)
+	mock.ExpectSet("movies:v2:tombstone:movie:573435:en-US", "1", cache.DefaultTTLPolicy().NotFound).SetVal("OK")
 
+	defer gock.Off()
 	gock.New("https://api.themoviedb.org/3").
 		Get("/movie/573435").
 		MatchHeader("Authorization", "Bearer dummy-auth-token").
@@ -79,19 +190,21 @@ func TestGetMovieByID_APINotFound(t *testing.T) {
 
 	repo := NewMovieRepository("dummy-auth-token", redisCache)
 
-	movie, err := repo.GetMovieByID("573435")
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotFound)
 	assert.Nil(t, movie)
+	assert.Equal(t, cache.StatusMiss, status)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetMovieByID_APIUnavailable(t *testing.T) {
 	db, mock := redismock.NewClientMock()
-	redisCache := cache.NewRedisCache(db, nil)
+	redisCache, _ := cache.NewRedisCache(db, nil)
 
-	mock.ExpectGet("573435").RedisNil()
+	mock.ExpectGet("movies:v2:movie:573435:en-US").RedisNil()
+	mock.ExpectGet("movies:v2:tombstone:movie:573435:en-US").RedisNil()
 
 	gock.New("https://api.themoviedb.org/3").
 		Get("/movie/573435").
@@ -100,14 +213,63 @@ func TestGetMovieByID_APIUnavailable(t *testing.T) {
 
 	repo := NewMovieRepository("dummy-auth-token", redisCache)
 
-	movie, err := repo.GetMovieByID("573435")
+	movie, _, err := repo.GetMovieByID(context.Background(), "573435")
 
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrUpstreamUnavailable)
+	assert.ErrorContains(t, err, "API unavailable")
 	assert.Nil(t, movie)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestGetMovieByID_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"status_code": 7, "status_message": "Invalid API key: You must be granted a valid key.", "success": false}`))
+	}))
+	defer srv.Close()
+	repo := newTestRepository(srv, cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+
+	_, _, err := repo.GetMovieByID(context.Background(), "573435")
+
+	var upstreamErr *UpstreamError
+	assert.ErrorAs(t, err, &upstreamErr)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.Equal(t, 7, upstreamErr.Code)
+	assert.EqualError(t, err, "upstream API returned status 401: Invalid API key: You must be granted a valid key. (code 7)")
+}
+
+func TestGetMovieByID_UndecodableResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`<html>maintenance</html>`))
+	}))
+	defer srv.Close()
+	repo := newTestRepository(srv, cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+
+	_, _, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.ErrorIs(t, err, ErrUpstream)
+}
+
+func TestGetMovieByID_InvalidID(t *testing.T) {
+	counting := &countingCache{Cache: cache.NewMemoryCache(&config.CacheConfig{Shards: 1})}
+	repo := NewMovieRepository("dummy-auth-token", counting)
+
+	for _, id := range []string{"abc", "0", "-3", ""} {
+		_, _, err := repo.GetMovieByID(context.Background(), id)
+		assert.ErrorIs(t, err, ErrInvalidInput, "Expected movie ID %q to be rejected", id)
+	}
+	assert.Equal(t, int32(0), counting.lookups.Load(), "Expected invalid IDs not to be looked up")
+}
+
+func TestSaveMovie_InvalidID(t *testing.T) {
+	repo := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+
+	err := repo.SaveMovie(context.Background(), &models.Movie{Title: "No ID"})
+
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
 func TestGetMovies(t *testing.T) {
 	expectedMovies := []models.Movie{
 		{ID: 533535, Title: "Deadpool & Wolverine"},
@@ -123,21 +285,157 @@ func TestGetMovies(t *testing.T) {
 		Get("/discover/movie").
 		MatchHeader("Authorization", "Bearer dummy-auth-token").
 		MatchParam("page", "1").
+		MatchParam("language", "en-US").
 		Reply(200).
 		JSON(apiResponse)
 
-	repo := NewMovieRepository("dummy-auth-token", nil)
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := NewMovieRepository("dummy-auth-token", memoryCache)
 
-	movieList, err := repo.GetMovies(1)
+	movieList, err := repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 1})
 
 	assert.NoError(t, err)
 	assert.Equal(t, 1, movieList.Page)
 	assert.Equal(t, expectedMovies, movieList.Results)
+	assert.True(t, gock.IsDone())
+
+	entry, err := memoryCache.GetEntry(context.Background(), "movies:v2:discover:en-US:page=1")
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(apiResponse), entry.Value)
+	assert.InDelta(t, cache.DefaultTTLPolicy().Discover, entry.ExpiresIn, float64(time.Second))
+
+	// Served from the cache; gock would fail an unexpected request.
+	movieList, err = repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovies, movieList.Results)
+}
+
+func TestGetMovies_Filters(t *testing.T) {
+	queries := make(chan url.Values, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		queries <- req.URL.Query()
+		_ = json.NewEncoder(w).Encode(models.MovieList{Page: 2})
+	}))
+	defer srv.Close()
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, memoryCache)
+
+	_, err := repo.GetMovies(context.Background(), models.DiscoverQuery{
+		Page:             2,
+		GenreIDs:         []int{28, 12, 28},
+		ReleaseDateFrom:  "2024-01-01",
+		MinVoteAverage:   7.5,
+		MaxRuntime:       150,
+		OriginalLanguage: "es",
+		SortBy:           "vote_average.desc",
+	})
+	assert.NoError(t, err)
+
+	query := <-queries
+	assert.Equal(t, url.Values{
+		"page":                     {"2"},
+		"language":                 {"en-US"},
+		"with_genres":              {"12,28"},
+		"primary_release_date.gte": {"2024-01-01"},
+		"vote_average.gte":         {"7.5"},
+		"with_runtime.lte":         {"150"},
+		"with_original_language":   {"es"},
+		"sort_by":                  {"vote_average.desc"},
+	}, query)
+
+	// Filters are part of the key; the order of the genres is not.
+	key := "movies:v2:discover:en-US:page=2&primary_release_date.gte=2024-01-01&sort_by=vote_average.desc" +
+		"&vote_average.gte=7.5&with_genres=12%2C28&with_original_language=es&with_runtime.lte=150"
+	_, err = memoryCache.GetEntry(context.Background(), key)
+	assert.NoError(t, err)
+
+	_, err = repo.GetMovies(context.Background(), models.DiscoverQuery{
+		Page:             2,
+		GenreIDs:         []int{12, 28},
+		ReleaseDateFrom:  "2024-01-01",
+		MinVoteAverage:   7.5,
+		MaxRuntime:       150,
+		OriginalLanguage: "es",
+		SortBy:           "vote_average.desc",
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, queries, "Expected the page to be served from the cache")
+}
+
+func TestGetMovies_SeedsMovieCache(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	savedMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die", Runtime: 115}
+	fullMovie := &models.Movie{ID: 533535, Title: "Deadpool & Wolverine", Runtime: 128}
+	pageMovies := []models.Movie{
+		{ID: 533535, Title: "Deadpool & Wolverine"},
+		{ID: 573435, Title: "Bad Boys 4"},
+	}
+
+	var movieRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/discover/movie":
+			_ = json.NewEncoder(w).Encode(models.MovieList{Page: 1, Results: pageMovies})
+		case "/movie/533535":
+			movieRequests.Add(1)
+			_ = json.NewEncoder(w).Encode(fullMovie)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	repo := newTestRepository(srv, memoryCache)
+	assert.NoError(t, repo.SaveMovie(context.Background(), savedMovie))
+
+	_, err := repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 1})
+	assert.NoError(t, err)
+
+	// Partial data is served stale while the full record is fetched.
+	movie, status, err := repo.GetMovieByID(context.Background(), "533535")
+	assert.NoError(t, err)
+	assert.Equal(t, &pageMovies[0], movie)
+	assert.Equal(t, cache.StatusStale, status)
+
+	repo.refreshes.Wait()
+	assert.Equal(t, int32(1), movieRequests.Load())
+
+	movie, status, err = repo.GetMovieByID(context.Background(), "533535")
+	assert.NoError(t, err)
+	assert.Equal(t, fullMovie, movie)
+	assert.Equal(t, cache.StatusHit, status)
+
+	// Movies already in the cache are not overwritten by partial data.
+	movie, status, err = repo.GetMovieByID(context.Background(), "573435")
+	assert.NoError(t, err)
+	assert.Equal(t, savedMovie, movie)
+	assert.Equal(t, cache.StatusHit, status)
+}
+
+func TestGetMovies_NoSeedingWithoutSoftTTL(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewEncoder(w).Encode(models.MovieList{Page: 1, Results: []models.Movie{{ID: 533535}}})
+	}))
+	defer srv.Close()
+
+	repo := NewMovieRepository("dummy-auth-token", memoryCache,
+		WithTTLPolicy(cache.TTLPolicy{Movie: time.Hour, Discover: time.Hour}),
+	).(*movieRepositoryImpl)
+	repo.apiURL = srv.URL
+	repo.client = srv.Client()
+
+	_, err := repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 1})
+	assert.NoError(t, err)
+
+	_, err = memoryCache.GetValue(context.Background(), cache.MovieKey("533535", cache.DefaultLanguage))
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
 }
 
 func TestSaveMovie(t *testing.T) {
 	db, mock := redismock.NewClientMock()
-	redisCache := cache.NewRedisCache(db, nil)
+	redisCache, _ := cache.NewRedisCache(db, nil)
 
 	movie := &models.Movie{
 		ID:    573435,
@@ -146,11 +444,13 @@ func TestSaveMovie(t *testing.T) {
 
 	movieJSON, _ := json.Marshal(movie)
 
-	mock.ExpectSet("573435", string(movieJSON), 0).SetVal("OK")
+	mock.ExpectSet("movies:v2:movie:573435:en-US", string(movieJSON), 0).SetVal("OK")
+	mock.ExpectHSet("movies:v2:saved:movie:en-US", "573435", string(movieJSON)).SetVal(1)
+	mock.ExpectDel("movies:v2:tombstone:movie:573435:en-US").SetVal(0)
 
 	repo := NewMovieRepository("dummy-auth-token", redisCache)
 
-	err := repo.SaveMovie(movie)
+	err := repo.SaveMovie(context.Background(), movie)
 
 	assert.NoError(t, err)
 
@@ -159,7 +459,7 @@ func TestSaveMovie(t *testing.T) {
 
 func TestSaveMovie_Failure(t *testing.T) {
 	db, mock := redismock.NewClientMock()
-	redisCache := cache.NewRedisCache(db, nil)
+	redisCache, _ := cache.NewRedisCache(db, nil)
 
 	movie := &models.Movie{
 		ID:    573435,
@@ -168,13 +468,599 @@ func TestSaveMovie_Failure(t *testing.T) {
 
 	movieJSON, _ := json.Marshal(movie)
 
-	mock.ExpectSet("573435", string(movieJSON), 0).SetErr(fmt.Errorf("failed to save movie"))
+	mock.ExpectSet("movies:v2:movie:573435:en-US", string(movieJSON), 0).SetErr(fmt.Errorf("failed to save movie"))
 
 	repo := NewMovieRepository("dummy-auth-token", redisCache)
 
-	err := repo.SaveMovie(movie)
+	err := repo.SaveMovie(context.Background(), movie)
 
 	assert.Error(t, err)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestSaveMovie_MemoryCache(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{MaxEntries: 10, Shards: 2})
+
+	movie := &models.Movie{
+		ID:    573435,
+		Title: "Bad Boys: Ride or Die",
+	}
+
+	repo := NewMovieRepository("dummy-auth-token", memoryCache)
+
+	err := repo.SaveMovie(context.Background(), movie)
+	assert.NoError(t, err)
+
+	cachedMovie, status, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, movie, cachedMovie)
+	assert.Equal(t, cache.StatusHit, status)
+}
+
+// countingCache records how many lookups reached the cache so tests can tell
+// when every concurrent caller has missed it.
+type countingCache struct {
+	cache.Cache
+	lookups atomic.Int32
+}
+
+func (c *countingCache) GetEntry(ctx context.Context, key string) (*cache.Entry, error) {
+	c.lookups.Add(1)
+	return c.Cache.GetEntry(ctx, key)
+}
+
+// newGatedServer starts an upstream that counts requests and holds them until release is closed.
+func newGatedServer(t *testing.T, status int, body interface{}) (*httptest.Server, *atomic.Int32, chan struct{}) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		<-release
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls, release
+}
+
+func newTestRepository(srv *httptest.Server, movieCache cache.Cache) *movieRepositoryImpl {
+	repo := NewMovieRepository("dummy-auth-token", movieCache).(*movieRepositoryImpl)
+	repo.apiURL = srv.URL
+	repo.client = srv.Client()
+	return repo
+}
+
+func TestGetMovieByID_CoalescesConcurrentMisses(t *testing.T) {
+	const callers = 100
+	expectedMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	srv, calls, release := newGatedServer(t, http.StatusOK, expectedMovie)
+
+	movieCache := &countingCache{Cache: cache.NewMemoryCache(&config.CacheConfig{Shards: 1})}
+	repo := newTestRepository(srv, movieCache)
+
+	var wg sync.WaitGroup
+	movies := make([]*models.Movie, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			movies[i], _, errs[i] = repo.GetMovieByID(context.Background(), "573435")
+		}(i)
+	}
+
+	// Hold the upstream response until every caller has missed the cache.
+	assert.Eventually(t, func() bool { return movieCache.lookups.Load() == callers }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "Expected a single upstream request")
+	for i := 0; i < callers; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, expectedMovie, movies[i])
+	}
+}
+
+func TestGetMovieByID_CoalescedMissesShareError(t *testing.T) {
+	const callers = 20
+	srv, calls, release := newGatedServer(t, http.StatusServiceUnavailable, models.ErrorResponse{Error: "down"})
+
+	movieCache := &countingCache{Cache: cache.NewMemoryCache(&config.CacheConfig{Shards: 1})}
+	repo := newTestRepository(srv, movieCache)
+
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = repo.GetMovieByID(context.Background(), "573435")
+		}(i)
+	}
+
+	assert.Eventually(t, func() bool { return movieCache.lookups.Load() == callers }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "Expected a single upstream request")
+	for i := 0; i < callers; i++ {
+		assert.Error(t, errs[i])
+	}
+}
+
+func TestGetMovies_CoalescesConcurrentRequests(t *testing.T) {
+	const callers = 50
+	expectedList := &models.MovieList{Page: 2, Results: []models.Movie{{ID: 533535, Title: "Deadpool & Wolverine"}}}
+	srv, calls, release := newGatedServer(t, http.StatusOK, expectedList)
+
+	repo := newTestRepository(srv, cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+
+	var started, wg sync.WaitGroup
+	lists := make([]*models.MovieList, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			started.Done()
+			lists[i], errs[i] = repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 2})
+		}(i)
+	}
+
+	started.Wait()
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "Expected a single upstream request")
+	for i := 0; i < callers; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, expectedList, lists[i])
+	}
+}
+
+func TestGetMovieByID_LocalCacheHit(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache, _ := cache.NewRedisCache(db, nil)
+
+	movie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	movieJSON, _ := json.Marshal(movie)
+
+	// Only the first lookup reaches Redis; the second is answered by L1.
+	mock.ExpectGet("movies:v2:movie:573435:en-US").SetVal(string(movieJSON))
+	mock.ExpectPTTL("movies:v2:movie:573435:en-US").SetVal(23*time.Hour + 30*time.Minute)
+
+	metrics := cache.NewMetrics()
+	repo := NewMovieRepository("dummy-auth-token", redisCache, WithLocalCache(10, time.Minute), WithMetrics(metrics))
+
+	for i := 0; i < 2; i++ {
+		cachedMovie, status, err := repo.GetMovieByID(context.Background(), "573435")
+		assert.NoError(t, err)
+		assert.Equal(t, movie, cachedMovie)
+		assert.Equal(t, cache.StatusHit, status)
+	}
+
+	assert.Equal(t, map[cache.Tier]cache.TierStats{
+		cache.TierL1: {Hits: 1, Misses: 1, HitRatio: 0.5},
+		cache.TierL2: {Hits: 1, HitRatio: 1},
+	}, metrics.Snapshot(context.Background()).Classes[cache.KeyClassMovie].Tiers)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovieByID_CorruptCacheEntry(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache, _ := cache.NewRedisCache(db, nil)
+
+	expectedMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	apiResponse, _ := json.Marshal(expectedMovie)
+
+	mock.ExpectGet("movies:v2:movie:573435:en-US").SetVal("{not json")
+	mock.ExpectPTTL("movies:v2:movie:573435:en-US").SetVal(time.Hour)
+	mock.ExpectDel("movies:v2:movie:573435:en-US").SetVal(1)
+	mock.ExpectGet("movies:v2:tombstone:movie:573435:en-US").RedisNil()
+	mock.ExpectSet("movies:v2:movie:573435:en-US", string(apiResponse), cache.DefaultTTLPolicy().Movie).SetVal("OK")
+	mock.ExpectHDel("movies:v2:saved:movie:en-US", "573435").SetVal(0)
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/573435").
+		Reply(200).
+		JSON(apiResponse)
+
+	repo := NewMovieRepository("dummy-auth-token", redisCache)
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovie, movie)
+	assert.Equal(t, cache.StatusMiss, status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovies_CorruptCacheEntry(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	key := cache.DiscoverKey(cache.DefaultLanguage, url.Values{"page": {"1"}})
+	assert.NoError(t, memoryCache.SetValue(context.Background(), key, "{not json"))
+
+	expected := &models.MovieList{Page: 1, Results: []models.Movie{{ID: 1, Title: "Fresh"}}}
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/discover/movie").
+		Reply(200).
+		JSON(expected)
+
+	repo := NewMovieRepository("dummy-auth-token", memoryCache)
+
+	list, err := repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Results, list.Results)
+	value, err := memoryCache.GetValue(context.Background(), key)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "{not json", value)
+	assert.True(t, gock.IsDone())
+}
+
+type capturingInvalidator struct {
+	handlers []cache.InvalidationHandler
+}
+
+func (c *capturingInvalidator) Publish(ctx context.Context, keys ...string) error { return nil }
+
+func (c *capturingInvalidator) PublishAll(ctx context.Context) error { return nil }
+
+func (c *capturingInvalidator) Subscribe(handler cache.InvalidationHandler) {
+	c.handlers = append(c.handlers, handler)
+}
+
+// deliver passes keys announced by another replica to every handler.
+func (c *capturingInvalidator) deliver(keys ...string) {
+	for _, handler := range c.handlers {
+		handler.Invalidate(keys...)
+	}
+}
+
+func TestGetMovieByID_InvalidatedByOtherReplica(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	invalidator := &capturingInvalidator{}
+
+	repo := NewMovieRepository("dummy-auth-token", memoryCache,
+		WithLocalCache(10, time.Minute),
+		WithInvalidator(invalidator),
+	)
+
+	assert.NoError(t, repo.SaveMovie(context.Background(), &models.Movie{ID: 200002, Title: "Test Movie"}))
+
+	// Another replica saves a new version and announces it.
+	updated := &models.Movie{ID: 200002, Title: "Test Movie 2"}
+	updatedJSON, _ := json.Marshal(updated)
+	key := cache.MovieKey("200002", cache.DefaultLanguage)
+	assert.NoError(t, memoryCache.SetValue(context.Background(), key, string(updatedJSON)))
+	invalidator.deliver(key)
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "200002")
+
+	assert.NoError(t, err)
+	assert.Equal(t, updated, movie)
+	assert.Equal(t, cache.StatusHit, status)
+}
+
+func TestGetMovieByID_WithLanguage(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache, _ := cache.NewRedisCache(db, nil)
+
+	expectedMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Hasta la muerte"}
+	apiResponse, _ := json.Marshal(expectedMovie)
+
+	mock.ExpectGet("movies:v2:movie:573435:es-MX").RedisNil()
+	mock.ExpectGet("movies:v2:tombstone:movie:573435:es-MX").RedisNil()
+	mock.ExpectSet("movies:v2:movie:573435:es-MX", string(apiResponse), cache.DefaultTTLPolicy().Movie).SetVal("OK")
+	mock.ExpectHDel("movies:v2:saved:movie:es-MX", "573435").SetVal(0)
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/573435").
+		MatchParam("language", "es-MX").
+		Reply(200).
+		JSON(apiResponse)
+
+	// Legacy keys hold default-language data and must not be read for other languages.
+	repo := NewMovieRepository("dummy-auth-token", redisCache, WithLanguage("es-MX"), WithLegacyKeyFallback(true))
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovie, movie)
+	assert.Equal(t, cache.StatusMiss, status)
+	assert.True(t, gock.IsDone())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovieByID_MigratesLegacyKey(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache, _ := cache.NewRedisCache(db, nil)
+
+	legacyMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	movieJSON, _ := json.Marshal(legacyMovie)
+
+	mock.ExpectGet("movies:v2:movie:573435:en-US").RedisNil()
+	mock.ExpectGet("573435").SetVal(string(movieJSON))
+	mock.ExpectPTTL("573435").SetVal(23*time.Hour + 30*time.Minute)
+	mock.ExpectSet("movies:v2:movie:573435:en-US", string(movieJSON), 23*time.Hour+30*time.Minute).SetVal("OK")
+
+	repo := NewMovieRepository("dummy-auth-token", redisCache, WithLegacyKeyFallback(true))
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, legacyMovie, movie)
+	assert.Equal(t, cache.StatusHit, status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovieByID_ValueCodec(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	valueCodec, err := cache.NewValueCodec(&config.CacheConfig{
+		Codec:               config.CacheCodecMsgpack,
+		Compression:         config.CacheCompressionZstd,
+		CompressionMinBytes: 0,
+	})
+	assert.NoError(t, err)
+	repo := NewMovieRepository("dummy-auth-token", memoryCache, WithValueCodec(valueCodec))
+
+	// Entries written as plain JSON are read side by side with new ones.
+	assert.NoError(t, memoryCache.SetValue(context.Background(), cache.MovieKey("1", cache.DefaultLanguage), `{"id":1,"title":"Plain"}`))
+	movie, _, err := repo.GetMovieByID(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Plain", movie.Title)
+
+	saved := &models.Movie{ID: 2, Title: "Compact", Genres: []models.Genre{{ID: 28, Name: "Action"}}}
+	assert.NoError(t, repo.SaveMovie(context.Background(), saved))
+	value, err := memoryCache.GetValue(context.Background(), cache.MovieKey("2", cache.DefaultLanguage))
+	assert.NoError(t, err)
+	assert.Equal(t, "\x00m", value[:2], "Expected a MessagePack entry")
+
+	movie, _, err = repo.GetMovieByID(context.Background(), "2")
+	assert.NoError(t, err)
+	assert.Equal(t, saved, movie)
+
+	entry, err := repo.InspectMovie(context.Background(), "2")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":2,"title":"Compact","genres":[{"id":28,"name":"Action"}]}`, string(entry.Value))
+}
+
+func TestGetMovieByID_LegacyKeyFallbackDisabled(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	legacyJSON, _ := json.Marshal(&models.Movie{ID: 573435, Title: "Bad Boys 4"})
+	assert.NoError(t, memoryCache.SetValue(context.Background(), cache.LegacyMovieKey("573435"), string(legacyJSON)))
+
+	expectedMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	apiResponse, _ := json.Marshal(expectedMovie)
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/573435").
+		Reply(200).
+		JSON(apiResponse)
+
+	repo := NewMovieRepository("dummy-auth-token", memoryCache)
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovie, movie)
+	assert.Equal(t, cache.StatusMiss, status)
+	assert.True(t, gock.IsDone())
+}
+
+func TestGetMovieByID_NotFoundIsCached(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/999999999").
+		Times(1).
+		Reply(404)
+
+	repo := NewMovieRepository("dummy-auth-token", memoryCache)
+
+	_, status, err := repo.GetMovieByID(context.Background(), "999999999")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, cache.StatusMiss, status)
+	assert.True(t, gock.IsDone())
+
+	// Answered from the tombstone; gock would fail an unexpected request.
+	movie, status, err := repo.GetMovieByID(context.Background(), "999999999")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, movie)
+	assert.Equal(t, cache.StatusHit, status)
+
+	entry, err := memoryCache.GetEntry(context.Background(), cache.MovieTombstoneKey("999999999", cache.DefaultLanguage))
+	assert.NoError(t, err)
+	assert.InDelta(t, cache.DefaultTTLPolicy().NotFound, entry.ExpiresIn, float64(time.Second))
+}
+
+func TestGetMovieByID_RefreshNotFoundEvictsStaleMovie(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, memoryCache)
+	partial := &models.Movie{ID: 573435, Title: "Bad Boys 4"}
+	// Seeded by a discover page: stale at once, hard TTL hours away.
+	assert.NoError(t, repo.movies.Set(context.Background(), cache.MovieKey("573435", cache.DefaultLanguage), partial, 22*time.Hour))
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+	assert.NoError(t, err)
+	assert.Equal(t, partial, movie)
+	assert.Equal(t, cache.StatusStale, status)
+	repo.refreshes.Wait()
+
+	for i := 0; i < 5; i++ {
+		_, status, err = repo.GetMovieByID(context.Background(), "573435")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, cache.StatusHit, status)
+	}
+	repo.refreshes.Wait()
+
+	assert.Equal(t, int32(1), calls.Load(), "Expected the not-found result to stop refreshes")
+	_, err = memoryCache.GetValue(context.Background(), cache.MovieKey("573435", cache.DefaultLanguage))
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestGetMovieByID_NegativeCachingDisabled(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/999999999").
+		Times(2).
+		Reply(404)
+
+	policy := cache.DefaultTTLPolicy()
+	policy.NotFound = 0
+	repo := NewMovieRepository("dummy-auth-token", memoryCache, WithTTLPolicy(policy))
+
+	for i := 0; i < 2; i++ {
+		_, status, err := repo.GetMovieByID(context.Background(), "999999999")
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Equal(t, cache.StatusMiss, status)
+	}
+	assert.True(t, gock.IsDone())
+	assert.Equal(t, 0, memoryCache.Len())
+}
+
+func TestSaveMovie_ClearsTombstone(t *testing.T) {
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	tombstoneKey := cache.MovieTombstoneKey("200002", cache.DefaultLanguage)
+	assert.NoError(t, memoryCache.SetValueWithTTL(context.Background(), tombstoneKey, "1", time.Minute))
+
+	repo := NewMovieRepository("dummy-auth-token", memoryCache)
+
+	movie := &models.Movie{ID: 200002, Title: "Test Movie 2"}
+	assert.NoError(t, repo.SaveMovie(context.Background(), movie))
+
+	_, err := memoryCache.GetValue(context.Background(), tombstoneKey)
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+
+	cachedMovie, status, err := repo.GetMovieByID(context.Background(), "200002")
+	assert.NoError(t, err)
+	assert.Equal(t, movie, cachedMovie)
+	assert.Equal(t, cache.StatusHit, status)
+}
+
+func TestGetMovieByID_CacheUnavailable(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache, _ := cache.NewRedisCache(db, nil)
+
+	expectedMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	apiResponse, _ := json.Marshal(expectedMovie)
+
+	unavailable := fmt.Errorf("dial tcp: connection refused")
+	mock.ExpectGet("movies:v2:movie:573435:en-US").SetErr(unavailable)
+	mock.ExpectGet("movies:v2:tombstone:movie:573435:en-US").SetErr(unavailable)
+	mock.ExpectSet("movies:v2:movie:573435:en-US", string(apiResponse), cache.DefaultTTLPolicy().Movie).SetErr(unavailable)
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/movie/573435").
+		Reply(200).
+		JSON(apiResponse)
+
+	repo := NewMovieRepository("dummy-auth-token", redisCache)
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovie, movie)
+	assert.Equal(t, cache.StatusMiss, status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovies_CacheUnavailable(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	redisCache, _ := cache.NewRedisCache(db, nil)
+
+	expectedList := &models.MovieList{Page: 1, Results: []models.Movie{}}
+	apiResponse, _ := json.Marshal(expectedList)
+
+	unavailable := fmt.Errorf("dial tcp: connection refused")
+	mock.ExpectGet("movies:v2:discover:en-US:page=1").SetErr(unavailable)
+	mock.ExpectSet("movies:v2:discover:en-US:page=1", string(apiResponse), cache.DefaultTTLPolicy().Discover).SetErr(unavailable)
+
+	defer gock.Off()
+	gock.New("https://api.themoviedb.org/3").
+		Get("/discover/movie").
+		Reply(200).
+		JSON(apiResponse)
+
+	repo := NewMovieRepository("dummy-auth-token", redisCache)
+
+	movieList, err := repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedList, movieList)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMovieByID_CancelsUpstreamRequest(t *testing.T) {
+	upstreamCancelled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+		close(upstreamCancelled)
+	}))
+	defer srv.Close()
+
+	repo := newTestRepository(srv, cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	movie, _, err := repo.GetMovieByID(ctx, "573435")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, movie)
+	select {
+	case <-upstreamCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the upstream request to be cancelled")
+	}
+}
+
+func TestGetMovieByID_CancelledCallerDoesNotFailOthers(t *testing.T) {
+	expectedMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	srv, calls, release := newGatedServer(t, http.StatusOK, expectedMovie)
+
+	repo := newTestRepository(srv, cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+
+	type result struct {
+		movie *models.Movie
+		err   error
+	}
+	patient := make(chan result, 1)
+	go func() {
+		movie, _, err := repo.GetMovieByID(context.Background(), "573435")
+		patient <- result{movie, err}
+	}()
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := repo.GetMovieByID(ctx, "573435")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	res := <-patient
+	assert.NoError(t, res.err)
+	assert.Equal(t, expectedMovie, res.movie)
+	assert.Equal(t, int32(1), calls.Load())
+}
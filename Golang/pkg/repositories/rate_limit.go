@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/time/rate"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+// RateLimitError is returned, without calling the upstream API, when the
+// request budget does not allow a call before the caller's deadline. It
+// matches ErrRateLimited.
+type RateLimitError struct {
+	// RetryAfter is how long until the budget allows a call.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v, retry in %s", ErrRateLimited, e.RetryAfter.Round(time.Millisecond))
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimiter throttles calls to the upstream API.
+type RateLimiter interface {
+	// Wait blocks until a call may be made. It fails with a RateLimitError
+	// when that would take longer than allowed or past the deadline of ctx.
+	Wait(ctx context.Context) error
+}
+
+// rateLimitKey holds the token bucket shared by every replica.
+var rateLimitKey = cache.BuildKey("ratelimit", "upstream")
+
+// LocalRateLimiter is a token bucket shared by the goroutines of one process.
+type LocalRateLimiter struct {
+	limiter *rate.Limiter
+	maxWait time.Duration
+}
+
+// NewLocalRateLimiter creates a LocalRateLimiter allowing cfg.RateLimit calls
+// per second with bursts of cfg.RateBurst. A call waits at most cfg.RateMaxWait.
+func NewLocalRateLimiter(cfg *config.UpstreamConfig) *LocalRateLimiter {
+	return &LocalRateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(cfg.RateLimit), max(cfg.RateBurst, 1)),
+		maxWait: cfg.RateMaxWait,
+	}
+}
+
+// Wait implements RateLimiter.
+func (l *LocalRateLimiter) Wait(ctx context.Context) error {
+	reservation := l.limiter.Reserve()
+	if !reservation.OK() {
+		return &RateLimitError{}
+	}
+	delay := reservation.Delay()
+	if delay > allowedWait(ctx, l.maxWait) {
+		reservation.Cancel()
+		return &RateLimitError{RetryAfter: delay}
+	}
+	if err := sleep(ctx, delay); err != nil {
+		reservation.Cancel()
+		return err
+	}
+	return nil
+}
+
+// takeTokenScript takes a token from the bucket stored at KEYS[1], refilled at
+// ARGV[1] tokens per millisecond up to ARGV[2] tokens, at time ARGV[3] in
+// milliseconds. The token may be borrowed from the future: the script returns
+// how many milliseconds the caller must wait before using it. When that exceeds
+// ARGV[4] the token is not taken and -1 minus the wait is returned.
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local max_wait = tonumber(ARGV[4])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+	ts = now
+end
+tokens = tokens - 1
+local wait = 0
+if tokens < 0 then
+	wait = math.ceil(-tokens / rate)
+end
+if wait > max_wait then
+	return -1 - wait
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(ts))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate) + max_wait + 1000)
+return wait
+`)
+
+// RedisRateLimiter is a token bucket stored in Redis and shared by every
+// replica, so the rate applies to the whole deployment. While Redis is
+// unreachable each replica falls back to a local bucket with the same settings.
+type RedisRateLimiter struct {
+	client   redis.UniversalClient
+	rate     float64
+	burst    int
+	maxWait  time.Duration
+	fallback *LocalRateLimiter
+	now      func() time.Time
+}
+
+// NewRedisRateLimiter creates a RedisRateLimiter with the settings of
+// NewLocalRateLimiter, storing its bucket through client.
+func NewRedisRateLimiter(client redis.UniversalClient, cfg *config.UpstreamConfig) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client:   client,
+		rate:     cfg.RateLimit,
+		burst:    max(cfg.RateBurst, 1),
+		maxWait:  cfg.RateMaxWait,
+		fallback: NewLocalRateLimiter(cfg),
+		now:      time.Now,
+	}
+}
+
+// Wait implements RateLimiter. A token borrowed from the future is not given
+// back when ctx is cancelled while waiting for it.
+func (l *RedisRateLimiter) Wait(ctx context.Context) error {
+	maxWait := allowedWait(ctx, l.maxWait)
+	args := []interface{}{l.rate / 1000, l.burst, l.now().UnixMilli(), maxWait.Milliseconds()}
+	wait, err := takeTokenScript.Run(ctx, l.client, []string{rateLimitKey}, args...).Int64()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("Shared rate limiter unavailable, limiting locally: %v", err)
+		return l.fallback.Wait(ctx)
+	}
+	if wait < 0 {
+		return &RateLimitError{RetryAfter: time.Duration(-1-wait) * time.Millisecond}
+	}
+	return sleep(ctx, time.Duration(wait)*time.Millisecond)
+}
+
+// allowedWait returns maxWait, shortened to the time left before the deadline
+// of ctx.
+func allowedWait(ctx context.Context, maxWait time.Duration) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		return min(maxWait, time.Until(deadline))
+	}
+	return maxWait
+}
+
+// rateLimitTransport waits for the limiter before every request. A nil next
+// uses http.DefaultTransport.
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	limiter RateLimiter
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req)
+}
+
+// withRateLimit returns a copy of client whose requests wait for limiter.
+func withRateLimit(client *http.Client, limiter RateLimiter) *http.Client {
+	limited := *client
+	limited.Transport = &rateLimitTransport{next: client.Transport, limiter: limiter}
+	return &limited
+}
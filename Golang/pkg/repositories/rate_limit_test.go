@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+func rateLimitConfig(perSecond float64, burst int, maxWait time.Duration) *config.UpstreamConfig {
+	return &config.UpstreamConfig{RateLimit: perSecond, RateBurst: burst, RateMaxWait: maxWait}
+}
+
+func TestLocalRateLimiter_Burst(t *testing.T) {
+	limiter := NewLocalRateLimiter(rateLimitConfig(1, 2, 0))
+
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.NoError(t, limiter.Wait(context.Background()))
+	err := limiter.Wait(context.Background())
+
+	var rateLimited *RateLimitError
+	assert.ErrorAs(t, err, &rateLimited)
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.InDelta(t, time.Second, rateLimited.RetryAfter, float64(50*time.Millisecond))
+}
+
+func TestLocalRateLimiter_QueuesUpToMaxWait(t *testing.T) {
+	limiter := NewLocalRateLimiter(rateLimitConfig(50, 1, time.Second))
+
+	assert.NoError(t, limiter.Wait(context.Background()))
+	start := time.Now()
+	assert.NoError(t, limiter.Wait(context.Background()))
+
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond, "Expected the second call to wait for a token")
+}
+
+func TestLocalRateLimiter_RespectsDeadline(t *testing.T) {
+	limiter := NewLocalRateLimiter(rateLimitConfig(1, 1, time.Minute))
+	assert.NoError(t, limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := limiter.Wait(ctx)
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Less(t, time.Since(start), 50*time.Millisecond, "Expected a call that cannot make its deadline to fail at once")
+}
+
+func TestRedisRateLimiter_SharedBetweenReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	cfg := rateLimitConfig(1, 2, 0)
+	replicaA := NewRedisRateLimiter(client, cfg)
+	replicaB := NewRedisRateLimiter(client, cfg)
+
+	assert.NoError(t, replicaA.Wait(context.Background()))
+	assert.NoError(t, replicaB.Wait(context.Background()))
+	err := replicaA.Wait(context.Background())
+
+	var rateLimited *RateLimitError
+	assert.ErrorAs(t, err, &rateLimited)
+	assert.Greater(t, rateLimited.RetryAfter, 900*time.Millisecond)
+	assert.True(t, server.Exists(rateLimitKey))
+	assert.Greater(t, server.TTL(rateLimitKey), time.Duration(0))
+}
+
+func TestRedisRateLimiter_Refills(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	limiter := NewRedisRateLimiter(client, rateLimitConfig(10, 1, 0))
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.ErrorIs(t, limiter.Wait(context.Background()), ErrRateLimited)
+
+	now = now.Add(100 * time.Millisecond)
+	assert.NoError(t, limiter.Wait(context.Background()))
+}
+
+func TestRedisRateLimiter_QueuesUpToMaxWait(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	limiter := NewRedisRateLimiter(client, rateLimitConfig(50, 1, time.Second))
+
+	assert.NoError(t, limiter.Wait(context.Background()))
+	start := time.Now()
+	assert.NoError(t, limiter.Wait(context.Background()))
+
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond, "Expected the second call to wait for a token")
+}
+
+func TestRedisRateLimiter_FallsBackWhenRedisIsDown(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	limiter := NewRedisRateLimiter(client, rateLimitConfig(1, 1, 0))
+	server.Close()
+
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.ErrorIs(t, limiter.Wait(context.Background()), ErrRateLimited, "Expected the local bucket to apply")
+}
+
+func TestRateLimitedRequestsAreNotRetried(t *testing.T) {
+	srv, calls := flakyServer(t, 0, http.StatusOK, nil)
+	limiter := NewLocalRateLimiter(rateLimitConfig(1, 1, 0))
+	client := newUpstreamClient(&config.UpstreamConfig{MaxRetries: 3, RetryBaseDelay: time.Millisecond, RetryMaxDelay: time.Millisecond, RetryBudget: time.Second}, limiter)
+
+	resp, err := client.Get(srv.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	_, err = client.Get(srv.URL)
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestGetMovieByID_RateLimited(t *testing.T) {
+	srv, calls := flakyServer(t, 0, http.StatusOK, nil)
+	breaker, _ := newTestBreaker(1, 1)
+	repo := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}),
+		WithUpstream(&config.UpstreamConfig{BaseURL: srv.URL, Timeout: time.Second}),
+		WithRateLimiter(NewLocalRateLimiter(rateLimitConfig(1, 1, 0))),
+		WithCircuitBreaker(breaker),
+	)
+
+	_, _, err := repo.GetMovieByID(context.Background(), "1")
+	assert.NoError(t, err)
+	_, _, err = repo.GetMovieByID(context.Background(), "2")
+
+	var rateLimited *RateLimitError
+	assert.True(t, errors.As(err, &rateLimited), "Expected a RateLimitError, got %v", err)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, CircuitClosed, breaker.State(), "Expected rate-limited calls not to count as upstream failures")
+}
+
+func TestWithRateLimiter_CustomClient(t *testing.T) {
+	var calls int
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, errors.New("unreachable")
+	})}
+	repo := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}),
+		WithHTTPClient(client),
+		WithRateLimiter(NewLocalRateLimiter(rateLimitConfig(1, 1, 0))),
+	)
+
+	_, _ = repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 1})
+	_, err := repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 2})
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxDrainBytes bounds how much of a failed response is read so that its
+// connection can be reused.
+const maxDrainBytes = 4 << 10
+
+// retryTransport retries idempotent requests that failed with a transport
+// error or a status meaning the upstream API is briefly unable to answer:
+// 429, 502, 503 and 504. Delays grow exponentially with full jitter, except
+// that a Retry-After header is honoured. A request is no longer retried once
+// its next delay would exceed the retry budget; the last failure is returned.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	budget     time.Duration
+
+	// jitter and now are replaced in tests.
+	jitter func(time.Duration) time.Duration
+	now    func() time.Time
+}
+
+func newRetryTransport(next http.RoundTripper, maxRetries int, baseDelay, maxDelay, budget time.Duration) *retryTransport {
+	return &retryTransport{
+		next:       next,
+		maxRetries: maxRetries,
+		baseDelay:  baseDelay,
+		maxDelay:   maxDelay,
+		budget:     budget,
+		jitter:     fullJitter,
+		now:        time.Now,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isIdempotent(req) {
+		return t.next.RoundTrip(req)
+	}
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.maxRetries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+		delay := t.backoff(attempt)
+		if after, ok := retryAfter(resp, t.now()); ok {
+			delay = after
+		}
+		if waited+delay > t.budget {
+			log.Printf("Not retrying %s %s: retry budget of %s exhausted", req.Method, req.URL.Path, t.budget)
+			return resp, err
+		}
+
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			drain(resp)
+		}
+		log.Printf("Retrying %s %s in %s (attempt %d of %d): %s", req.Method, req.URL.Path, delay, attempt+1, t.maxRetries, reason)
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		waited += delay
+	}
+}
+
+// backoff returns the delay before retry number attempt+1.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	delay := t.maxDelay
+	if attempt < 32 && t.baseDelay<<attempt < t.maxDelay {
+		delay = t.baseDelay << attempt
+	}
+	return t.jitter(delay)
+}
+
+// isIdempotent reports whether req may be sent again: GET and HEAD requests
+// without a body.
+func isIdempotent(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		// A request abandoned by the caller is not a failure of the API, and
+		// an exhausted rate limit is not lifted by retrying at once.
+		return req.Context().Err() == nil && !errors.Is(err, ErrRateLimited)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the delay asked for by the Retry-After header of a 429 or
+// 503 response, given in seconds or as an HTTP date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// fullJitter returns a random delay between zero and d.
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
+}
+
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+// flakyServer fails the first failures requests with status, then answers 200.
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) <= failures {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"status_message":"try again"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":573435}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newTestRetryTransport(maxRetries int, budget time.Duration) *retryTransport {
+	transport := newRetryTransport(http.DefaultTransport, maxRetries, time.Millisecond, 10*time.Millisecond, budget)
+	transport.jitter = func(d time.Duration) time.Duration { return d }
+	return transport
+}
+
+func TestRetryTransport_RetriesTransientStatuses(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		srv, calls := flakyServer(t, 2, status, nil)
+		client := &http.Client{Transport: newTestRetryTransport(2, time.Second)}
+
+		resp, err := client.Get(srv.URL)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "Expected status %d to be retried", status)
+		assert.Equal(t, int32(3), calls.Load())
+		resp.Body.Close()
+	}
+}
+
+func TestRetryTransport_GivesUpAfterMaxRetries(t *testing.T) {
+	srv, calls := flakyServer(t, 5, http.StatusServiceUnavailable, nil)
+	client := &http.Client{Transport: newTestRetryTransport(2, time.Second)}
+
+	resp, err := client.Get(srv.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+	resp.Body.Close()
+}
+
+func TestRetryTransport_DoesNotRetryOtherFailures(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusUnauthorized, http.StatusInternalServerError} {
+		srv, calls := flakyServer(t, 1, status, nil)
+		client := &http.Client{Transport: newTestRetryTransport(2, time.Second)}
+
+		resp, err := client.Get(srv.URL)
+
+		assert.NoError(t, err)
+		assert.Equal(t, status, resp.StatusCode)
+		assert.Equal(t, int32(1), calls.Load(), "Expected status %d not to be retried", status)
+		resp.Body.Close()
+	}
+}
+
+func TestRetryTransport_OnlyRetriesIdempotentRequests(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusServiceUnavailable, nil)
+	client := &http.Client{Transport: newTestRetryTransport(2, time.Second)}
+
+	resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+	resp.Body.Close()
+}
+
+func TestRetryTransport_HonoursRetryAfter(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+	client := &http.Client{Transport: newTestRetryTransport(2, 2*time.Second)}
+
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "Expected the delay asked for by Retry-After")
+	resp.Body.Close()
+}
+
+func TestRetryTransport_RespectsBudget(t *testing.T) {
+	// The API asks for more than the whole budget: the 429 is returned right away.
+	srv, calls := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"30"}})
+	client := &http.Client{Transport: newTestRetryTransport(2, time.Second)}
+
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Less(t, time.Since(start), time.Second)
+	resp.Body.Close()
+}
+
+func TestRetryTransport_RetriesTransportErrors(t *testing.T) {
+	var calls atomic.Int32
+	transport := newTestRetryTransport(2, time.Second)
+	transport.next = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			return nil, context.DeadlineExceeded
+		}
+		return httptest.NewRecorder().Result(), nil
+	})
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get("http://tmdb.invalid/movie/1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestRetryTransport_StopsWhenCallerGivesUp(t *testing.T) {
+	srv, calls := flakyServer(t, 5, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+	client := &http.Client{Transport: newTestRetryTransport(2, 5*time.Second)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	_, err := client.Do(req)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		status   int
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{status: http.StatusTooManyRequests, value: "3", expected: 3 * time.Second, ok: true},
+		{status: http.StatusServiceUnavailable, value: "Thu, 01 Aug 2024 12:00:10 GMT", expected: 10 * time.Second, ok: true},
+		{status: http.StatusTooManyRequests, value: "Thu, 01 Aug 2024 11:00:00 GMT", expected: 0, ok: true},
+		{status: http.StatusTooManyRequests, value: "soon"},
+		{status: http.StatusTooManyRequests, value: ""},
+		{status: http.StatusBadGateway, value: "3"},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		resp.Header.Set("Retry-After", tt.value)
+
+		delay, ok := retryAfter(resp, now)
+
+		assert.Equal(t, tt.ok, ok, "Retry-After %q", tt.value)
+		assert.Equal(t, tt.expected, delay, "Retry-After %q", tt.value)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	transport := newRetryTransport(http.DefaultTransport, 10, 100*time.Millisecond, time.Second, time.Minute)
+	transport.jitter = func(d time.Duration) time.Duration { return d }
+
+	assert.Equal(t, 100*time.Millisecond, transport.backoff(0))
+	assert.Equal(t, 400*time.Millisecond, transport.backoff(2))
+	assert.Equal(t, time.Second, transport.backoff(4))
+	assert.Equal(t, time.Second, transport.backoff(40))
+	assert.LessOrEqual(t, fullJitter(time.Second), time.Second)
+}
+
+func TestGetMovieByID_RetriesFlakyUpstream(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusBadGateway, nil)
+
+	repo := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}),
+		WithUpstream(&config.UpstreamConfig{BaseURL: srv.URL, MaxRetries: 2, RetryBaseDelay: time.Millisecond, RetryMaxDelay: time.Millisecond, RetryBudget: time.Second}),
+	)
+
+	movie, status, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 573435, movie.ID)
+	assert.Equal(t, cache.StatusMiss, status)
+	assert.Equal(t, int32(3), calls.Load())
+}
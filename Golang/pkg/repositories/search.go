@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+// maxSearchQueryLength bounds, in characters, the text of a search.
+const maxSearchQueryLength = 200
+
+// languagePattern matches the ISO 639-1 languages, with an optional ISO 3166-1
+// region, accepted by the upstream API, e.g. "es" or "es-MX".
+var languagePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+// SearchMovies returns a page of the movies whose title matches query, from the
+// cache when present, otherwise from the upstream API. Results are cached per
+// normalized query, so searches differing only in case or spacing share an
+// entry. Concurrent misses for the same search share a single upstream call.
+// Movies saved through the API are merged into every response, cached or not.
+func (r *movieRepositoryImpl) SearchMovies(ctx context.Context, query models.SearchQuery) (*models.MovieList, error) {
+	language, params, err := r.searchParams(query)
+	if err != nil {
+		return nil, err
+	}
+	key := cache.SearchKey(language, params)
+
+	hit, err := r.pages.Get(ctx, key)
+	if err == nil {
+		log.Printf("Cache hit for search %q (%s)", params.Get("query"), hit.Tier)
+		return r.mergeSavedMovies(ctx, language, params, hit.Value), nil
+	}
+	if errors.Is(err, cache.ErrCorruptEntry) {
+		r.dropCorruptEntry(ctx, key, err)
+	}
+
+	log.Printf("Cache miss for search %q. Fetching from API...", params.Get("query"))
+	v, err, _ := r.inflight.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return r.fetchSearch(ctx, key, language, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.mergeSavedMovies(ctx, language, params, v.(*models.MovieList)), nil
+}
+
+// searchParams validates query and returns its language and the normalized
+// parameters sent to the upstream API and used in the cache key.
+func (r *movieRepositoryImpl) searchParams(query models.SearchQuery) (string, url.Values, error) {
+	text := normalizeSearchText(query.Query)
+	if text == "" {
+		return "", nil, fmt.Errorf("%w: empty search query", ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(text) > maxSearchQueryLength {
+		return "", nil, fmt.Errorf("%w: search query longer than %d characters", ErrInvalidInput, maxSearchQueryLength)
+	}
+	if query.Year < 0 {
+		return "", nil, fmt.Errorf("%w: year %d", ErrInvalidInput, query.Year)
+	}
+	page := query.Page
+	if page == 0 {
+		page = 1
+	}
+	if page < 0 {
+		return "", nil, fmt.Errorf("%w: page %d", ErrInvalidInput, page)
+	}
+	language := query.Language
+	if language == "" {
+		language = r.language
+	} else if !languagePattern.MatchString(language) {
+		return "", nil, fmt.Errorf("%w: language %q", ErrInvalidInput, language)
+	}
+
+	params := url.Values{}
+	params.Set("query", text)
+	params.Set("page", strconv.Itoa(page))
+	params.Set("include_adult", strconv.FormatBool(query.IncludeAdult))
+	if query.Year > 0 {
+		params.Set("year", strconv.Itoa(query.Year))
+	}
+	return language, params, nil
+}
+
+// normalizeSearchText lowercases text and collapses its whitespace. The
+// upstream search ignores both.
+func normalizeSearchText(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// fetchSearch retrieves search results from the upstream API, caches them
+// under key and seeds the per-movie cache with them.
+func (r *movieRepositoryImpl) fetchSearch(ctx context.Context, key, language string, params url.Values) (*models.MovieList, error) {
+	results, err := r.fetchList(ctx, "search/movie", language, params)
+	if err != nil {
+		return nil, err
+	}
+	err = r.pages.Set(ctx, key, results, r.ttl.Search)
+	if err != nil {
+		log.Printf("Failed to cache search %s: %v", key, err)
+		return results, nil
+	}
+	r.seedMovies(ctx, language, results.Results)
+	return results, nil
+}
+
+// mergeSavedMovies returns results with the movies saved through the API in
+// language replacing the upstream version of the same ID. When the upstream
+// results fit on a single page, saved movies that match the search but are
+// missing from them are added too; on longer searches the upstream API may
+// return them on another page, where they would show up twice. results is
+// shared with the cache and left untouched; it is returned as is when nothing
+// is saved or the saved movies cannot be read.
+func (r *movieRepositoryImpl) mergeSavedMovies(ctx context.Context, language string, params url.Values, results *models.MovieList) *models.MovieList {
+	saved, err := r.savedMovies(ctx, language)
+	if err != nil {
+		log.Printf("Failed to look up saved movies for search %q: %v", params.Get("query"), err)
+		return results
+	}
+	if len(saved) == 0 {
+		return results
+	}
+
+	merged := *results
+	merged.Results = slices.Clone(results.Results)
+	replaced := 0
+	for i := range merged.Results {
+		if movie, ok := saved[merged.Results[i].ID]; ok {
+			merged.Results[i] = *movie
+			delete(saved, movie.ID)
+			replaced++
+		}
+	}
+	added := 0
+	if results.Page <= 1 && results.TotalPages <= 1 {
+		ids := make([]int, 0, len(saved))
+		for id := range saved {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		for _, id := range ids {
+			if matchesSearch(saved[id], params) {
+				merged.Results = append(merged.Results, *saved[id])
+				added++
+			}
+		}
+		merged.TotalResults += added
+	}
+	if replaced > 0 || added > 0 {
+		log.Printf("Merged %d saved movies into search results, %d of them not returned by the API", replaced+added, added)
+	}
+	return &merged
+}
+
+// savedMovies returns the movies saved through the API in language, by ID,
+// read from their index in a single lookup.
+func (r *movieRepositoryImpl) savedMovies(ctx context.Context, language string) (map[int]*models.Movie, error) {
+	fields, err := r.cache.GetFields(ctx, cache.SavedMoviesKey(language))
+	if err != nil {
+		return nil, err
+	}
+	saved := make(map[int]*models.Movie, len(fields))
+	for id, value := range fields {
+		movie, err := r.movieCodec.Decode(value)
+		if err != nil {
+			log.Printf("Ignoring unreadable saved movie ID %s: %v", id, err)
+			continue
+		}
+		saved[movie.ID] = movie
+	}
+	return saved, nil
+}
+
+// matchesSearch reports whether movie would be a result of the search: its
+// title contains the query text and it passes the year and adult filters.
+func matchesSearch(movie *models.Movie, params url.Values) bool {
+	if movie.Adult && params.Get("include_adult") != "true" {
+		return false
+	}
+	if year := params.Get("year"); year != "" && !strings.HasPrefix(movie.ReleaseDate, year+"-") {
+		return false
+	}
+	text := params.Get("query")
+	return strings.Contains(normalizeSearchText(movie.Title), text) ||
+		strings.Contains(normalizeSearchText(movie.OriginalTitle), text)
+}
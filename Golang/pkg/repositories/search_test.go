@@ -0,0 +1,256 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+// newSearchServer starts an upstream answering searches with results and
+// recording the query of every request.
+func newSearchServer(t *testing.T, results []models.Movie) (*httptest.Server, *atomic.Int32, chan url.Values) {
+	var calls atomic.Int32
+	queries := make(chan url.Values, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/search/movie" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		calls.Add(1)
+		queries <- req.URL.Query()
+		_ = json.NewEncoder(w).Encode(models.MovieList{Page: 1, Results: results, TotalPages: 3, TotalResults: 55})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls, queries
+}
+
+// newPagedSearchServer starts an upstream answering searches with the given
+// pages of results, numbered from 1.
+func newPagedSearchServer(t *testing.T, pages ...[]models.Movie) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	total := 0
+	for _, page := range pages {
+		total += len(page)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+		list := models.MovieList{Page: page, Results: []models.Movie{}, TotalPages: len(pages), TotalResults: total}
+		if page >= 1 && page <= len(pages) {
+			list.Results = pages[page-1]
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestSearchMovies(t *testing.T) {
+	expectedMovies := []models.Movie{{ID: 533535, Title: "Deadpool & Wolverine"}}
+	srv, calls, queries := newSearchServer(t, expectedMovies)
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, memoryCache)
+
+	movieList, err := repo.SearchMovies(context.Background(), models.SearchQuery{Query: "  Deadpool   WOLVERINE ", Year: 2024})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovies, movieList.Results)
+	assert.Equal(t, 3, movieList.TotalPages)
+	assert.Equal(t, 55, movieList.TotalResults)
+
+	query := <-queries
+	assert.Equal(t, "deadpool wolverine", query.Get("query"))
+	assert.Equal(t, "2024", query.Get("year"))
+	assert.Equal(t, "1", query.Get("page"))
+	assert.Equal(t, "false", query.Get("include_adult"))
+	assert.Equal(t, "en-US", query.Get("language"))
+
+	entry, err := memoryCache.GetEntry(context.Background(), "movies:v2:search:en-US:include_adult=false&page=1&query=deadpool+wolverine&year=2024")
+	assert.NoError(t, err)
+	assert.InDelta(t, cache.DefaultTTLPolicy().Search, entry.ExpiresIn, float64(time.Second))
+
+	// Searches differing only in case and spacing share the cached results.
+	movieList, err = repo.SearchMovies(context.Background(), models.SearchQuery{Query: "deadpool wolverine", Year: 2024, Page: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovies, movieList.Results)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestSearchMovies_Language(t *testing.T) {
+	srv, _, queries := newSearchServer(t, []models.Movie{{ID: 533535, Title: "Deadpool y Wolverine"}})
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, memoryCache)
+
+	_, err := repo.SearchMovies(context.Background(), models.SearchQuery{Query: "deadpool", Language: "es-MX", IncludeAdult: true})
+	assert.NoError(t, err)
+
+	query := <-queries
+	assert.Equal(t, "es-MX", query.Get("language"))
+	assert.Equal(t, "true", query.Get("include_adult"))
+
+	// Results seed the movie cache in the language they were searched in.
+	_, err = memoryCache.GetEntry(context.Background(), cache.MovieKey("533535", "es-MX"))
+	assert.NoError(t, err)
+	_, err = memoryCache.GetEntry(context.Background(), cache.MovieKey("533535", cache.DefaultLanguage))
+	assert.ErrorIs(t, err, cache.ErrCacheMiss)
+}
+
+func TestSearchMovies_MergesSavedMovies(t *testing.T) {
+	savedMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die", Runtime: 115}
+	upstreamMovies := []models.Movie{
+		{ID: 533535, Title: "Deadpool & Wolverine"},
+		{ID: 573435, Title: "Bad Boys 4"},
+	}
+	srv, _, _ := newSearchServer(t, upstreamMovies)
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, memoryCache)
+	assert.NoError(t, repo.SaveMovie(context.Background(), savedMovie))
+
+	movieList, err := repo.SearchMovies(context.Background(), models.SearchQuery{Query: "bad boys"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Movie{upstreamMovies[0], *savedMovie}, movieList.Results)
+}
+
+func TestSearchMovies_MergesSavedMoviesOnRead(t *testing.T) {
+	upstreamMovies := []models.Movie{{ID: 573435, Title: "Bad Boys 4"}}
+	srv, calls := newPagedSearchServer(t, upstreamMovies)
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, memoryCache)
+
+	movieList, err := repo.SearchMovies(context.Background(), models.SearchQuery{Query: "bad boys"})
+	assert.NoError(t, err)
+	assert.Equal(t, upstreamMovies, movieList.Results)
+
+	// Movies saved after the results were cached show up right away, including
+	// matches the upstream API does not know.
+	savedMovie := models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	customMovie := models.Movie{ID: 900001, Title: "Bad Boys: Director's Cut", ReleaseDate: "2024-06-05"}
+	otherMovie := models.Movie{ID: 900002, Title: "Inside Out 2"}
+	for _, movie := range []models.Movie{savedMovie, customMovie, otherMovie} {
+		assert.NoError(t, repo.SaveMovie(context.Background(), &movie))
+	}
+
+	movieList, err = repo.SearchMovies(context.Background(), models.SearchQuery{Query: "Bad Boys"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Movie{savedMovie, customMovie}, movieList.Results)
+	assert.Equal(t, 2, movieList.TotalResults)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// The cached results are left as the upstream API returned them.
+	cached, err := memoryCache.GetValue(context.Background(), "movies:v2:search:en-US:include_adult=false&page=1&query=bad+boys")
+	assert.NoError(t, err)
+	assert.NotContains(t, cached, "Director's Cut")
+
+	// Matches missing upstream are only added if they pass the filters of the search.
+	movieList, err = repo.SearchMovies(context.Background(), models.SearchQuery{Query: "bad boys", Year: 2020})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Movie{savedMovie}, movieList.Results)
+}
+
+func TestSearchMovies_SavedMovieOnLaterPage(t *testing.T) {
+	firstPage := []models.Movie{{ID: 9737, Title: "Bad Boys"}}
+	srv, _ := newPagedSearchServer(t, firstPage, []models.Movie{{ID: 573435, Title: "Bad Boys 4"}})
+	repo := newTestRepository(srv, cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+	savedMovie := models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	assert.NoError(t, repo.SaveMovie(context.Background(), &savedMovie))
+
+	// The upstream API returns the saved movie on the second page, so it is not
+	// added to the first one as well.
+	movieList, err := repo.SearchMovies(context.Background(), models.SearchQuery{Query: "bad boys"})
+	assert.NoError(t, err)
+	assert.Equal(t, firstPage, movieList.Results)
+	assert.Equal(t, 2, movieList.TotalResults)
+
+	movieList, err = repo.SearchMovies(context.Background(), models.SearchQuery{Query: "bad boys", Page: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Movie{savedMovie}, movieList.Results)
+}
+
+func TestSearchMovies_IgnoresUnsavedMoviesWithoutExpiry(t *testing.T) {
+	upstreamMovies := []models.Movie{{ID: 573435, Title: "Bad Boys: Ride or Die"}}
+	srv, _, _ := newSearchServer(t, upstreamMovies)
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, memoryCache)
+
+	// Movies fetched from the upstream API never expire with CACHE_TTL_MOVIE=0.
+	assert.NoError(t, memoryCache.SetValue(context.Background(), cache.MovieKey("573435", cache.DefaultLanguage), `{"id":573435,"title":"Bad Boys 4"}`))
+
+	movieList, err := repo.SearchMovies(context.Background(), models.SearchQuery{Query: "bad boys"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, upstreamMovies, movieList.Results)
+}
+
+func TestSearchMovies_CorruptCacheEntry(t *testing.T) {
+	expectedMovies := []models.Movie{{ID: 533535, Title: "Deadpool & Wolverine"}}
+	srv, calls, _ := newSearchServer(t, expectedMovies)
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, memoryCache)
+	key := "movies:v2:search:en-US:include_adult=false&page=1&query=deadpool"
+	assert.NoError(t, memoryCache.SetValue(context.Background(), key, "{not json"))
+
+	movieList, err := repo.SearchMovies(context.Background(), models.SearchQuery{Query: "deadpool"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovies, movieList.Results)
+	assert.Equal(t, int32(1), calls.Load())
+	value, err := memoryCache.GetValue(context.Background(), key)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "{not json", value)
+}
+
+func TestSearchMovies_InvalidInput(t *testing.T) {
+	srv, calls, _ := newSearchServer(t, nil)
+	repo := newTestRepository(srv, cache.NewMemoryCache(&config.CacheConfig{Shards: 1}))
+
+	queries := map[string]models.SearchQuery{
+		"empty":     {Query: "   "},
+		"too long":  {Query: strings.Repeat("a", maxSearchQueryLength+1)},
+		"year":      {Query: "deadpool", Year: -1},
+		"page":      {Query: "deadpool", Page: -2},
+		"language":  {Query: "deadpool", Language: "spanish"},
+		"lowercase": {Query: "deadpool", Language: "es-mx"},
+	}
+	for name, query := range queries {
+		_, err := repo.SearchMovies(context.Background(), query)
+		assert.ErrorIs(t, err, ErrInvalidInput, name)
+	}
+	assert.Equal(t, int32(0), calls.Load())
+}
+
+func TestSearchMovies_UpstreamError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"status_code": 22, "status_message": "Invalid page."}`))
+	}))
+	defer srv.Close()
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := newTestRepository(srv, memoryCache)
+
+	_, err := repo.SearchMovies(context.Background(), models.SearchQuery{Query: "deadpool", Page: 1000})
+
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	assert.ErrorIs(t, err, ErrUpstream)
+	var keys []string
+	_ = memoryCache.ScanKeys(context.Background(), "movies:v2:search:*", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	assert.Empty(t, keys, "Expected errors not to be cached")
+}
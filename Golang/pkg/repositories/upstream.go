@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+)
+
+// newUpstreamClient creates the HTTP client used to call the upstream API.
+// Every attempt, retries included, waits for limiter when it is not nil.
+func newUpstreamClient(cfg *config.UpstreamConfig, limiter RateLimiter) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if cfg.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(cfg.ProxyURL)
+	}
+	transport.DialContext = (&net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	var roundTripper http.RoundTripper = transport
+	if limiter != nil {
+		roundTripper = &rateLimitTransport{next: roundTripper, limiter: limiter}
+	}
+	if cfg.MaxRetries > 0 {
+		roundTripper = newRetryTransport(roundTripper, cfg.MaxRetries, cfg.RetryBaseDelay, cfg.RetryMaxDelay, cfg.RetryBudget)
+	}
+	return &http.Client{Transport: roundTripper, Timeout: cfg.Timeout}
+}
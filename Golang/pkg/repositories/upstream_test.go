@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+func TestNewUpstreamClient(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.corp:3128")
+	client := newUpstreamClient(&config.UpstreamConfig{
+		Timeout:               3 * time.Second,
+		ResponseHeaderTimeout: time.Second,
+		MaxIdleConnsPerHost:   32,
+		ProxyURL:              proxyURL,
+	}, nil)
+
+	assert.Equal(t, 3*time.Second, client.Timeout)
+	transport := client.Transport.(*http.Transport)
+	assert.Equal(t, time.Second, transport.ResponseHeaderTimeout)
+	assert.Equal(t, 32, transport.MaxIdleConnsPerHost)
+
+	req, _ := http.NewRequest("GET", "https://api.themoviedb.org/3/movie/1", nil)
+	proxy, err := transport.Proxy(req)
+	assert.NoError(t, err)
+	assert.Equal(t, proxyURL, proxy)
+}
+
+func TestWithUpstream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/tmdb/3/movie/573435" || req.Header.Get("Authorization") != "Bearer mirror-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(models.Movie{ID: 573435, Title: "From the mirror"})
+	}))
+	defer srv.Close()
+
+	repo := NewMovieRepository("ignored-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}),
+		WithUpstream(&config.UpstreamConfig{BaseURL: srv.URL + "/tmdb/3", Token: "mirror-token", Timeout: time.Second}),
+	)
+
+	movie, _, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "From the mirror", movie.Title)
+}
+
+func TestWithUpstream_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	repo := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}),
+		WithUpstream(&config.UpstreamConfig{BaseURL: srv.URL, ResponseHeaderTimeout: 20 * time.Millisecond}),
+	)
+
+	_, _, err := repo.GetMovieByID(context.Background(), "573435")
+
+	assert.ErrorContains(t, err, "timeout")
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	var called bool
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		called = true
+		return httptest.NewRecorder().Result(), nil
+	})}
+
+	repo := NewMovieRepository("dummy-auth-token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}), WithHTTPClient(client))
+	_, _ = repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 1})
+
+	assert.True(t, called, "Expected the custom client to be used")
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
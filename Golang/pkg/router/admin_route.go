@@ -0,0 +1,314 @@
+package router
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/services"
+	"github.com/elberthcabrales/movies-api/pkg/warmup"
+)
+
+// Warmer reports and starts cache warm-up runs.
+type Warmer interface {
+	Status() warmup.Status
+	Trigger(ctx context.Context) error
+}
+
+// AdminRouter exposes operational endpoints under /admin. Every request must
+// carry the admin token as a bearer token.
+type AdminRouter struct {
+	adminService services.CacheAdminService
+	metrics      *cache.Metrics
+	warmer       Warmer
+	token        string
+	// maxSnapshotBytes bounds the body of snapshot uploads; zero means unlimited.
+	maxSnapshotBytes int64
+}
+
+// NewAdminRouter creates a new AdminRouter. An empty token disables the admin
+// routes: every request to them is rejected. Snapshot uploads larger than
+// maxSnapshotBytes are rejected with 413; zero means unlimited.
+func NewAdminRouter(adminService services.CacheAdminService, metrics *cache.Metrics, warmer Warmer, token string, maxSnapshotBytes int64) *AdminRouter {
+	return &AdminRouter{adminService: adminService, metrics: metrics, warmer: warmer, token: token, maxSnapshotBytes: maxSnapshotBytes}
+}
+
+// RegisterRoutes adds the admin routes to router
+func (a *AdminRouter) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin", a.requireToken)
+	admin.GET("/cache/stats", a.getCacheStats)
+	admin.DELETE("/cache", a.purgeCache)
+	admin.GET("/cache/movies/:id", a.inspectMovie)
+	admin.DELETE("/cache/movies/:id", a.evictMovie)
+	admin.POST("/cache/movies/:id/refresh", a.refreshMovie)
+	admin.GET("/cache/snapshot", a.exportSnapshot)
+	admin.POST("/cache/snapshot", a.importSnapshot)
+	admin.GET("/warmup", a.getWarmupStatus)
+	admin.POST("/warmup", a.triggerWarmup)
+}
+
+// requireToken rejects requests without the admin bearer token.
+func (a *AdminRouter) requireToken(c *gin.Context) {
+	if a.token == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{Error: "Admin API is disabled"})
+		return
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid admin token"})
+		return
+	}
+	c.Next()
+}
+
+// getCacheStats godoc
+// @Summary Cache statistics
+// @Description Returns hits, misses, stale serves, errors, evictions and operation latency
+// @Description of the cache for each key class, together with the size of the backend.
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Success 200 {object} cache.Stats
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/cache/stats [get]
+func (a *AdminRouter) getCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, a.metrics.Snapshot(c.Request.Context()))
+}
+
+// inspectMovie godoc
+// @Summary Inspect the cache entry of a movie
+// @Description Returns the entry stored in the shared cache for a movie, with its key, remaining TTL
+// @Description and source: saved, tmdb, legacy or tombstone.
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Param id path string true "Movie ID"
+// @Success 200 {object} models.CacheEntry
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/cache/movies/{id} [get]
+func (a *AdminRouter) inspectMovie(c *gin.Context) {
+	entry, err := a.adminService.InspectMovie(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// evictMovie godoc
+// @Summary Evict a movie from the cache
+// @Description Removes the cached details, not-found result, saved copy and legacy entry of a movie.
+// @Tags admin
+// @Security AdminToken
+// @Param id path string true "Movie ID"
+// @Success 204
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/cache/movies/{id} [delete]
+func (a *AdminRouter) evictMovie(c *gin.Context) {
+	if err := a.adminService.EvictMovie(c.Request.Context(), c.Param("id")); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// refreshMovie godoc
+// @Summary Refresh a movie from TMDB
+// @Description Fetches the movie from TMDB and replaces its cache entry, even if it is fresh.
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Param id path string true "Movie ID"
+// @Success 200 {object} models.Movie
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/cache/movies/{id}/refresh [post]
+func (a *AdminRouter) refreshMovie(c *gin.Context) {
+	movie, err := a.adminService.RefreshMovie(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, movie)
+}
+
+// purgeCache godoc
+// @Summary Purge cache keys by pattern
+// @Description Removes every key matching a glob pattern, which must start with the "movies:" namespace,
+// @Description e.g. "movies:v2:discover:*" for all discover pages or "movies:*" for everything.
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Param pattern query string true "Key pattern"
+// @Success 200 {object} models.PurgeResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/cache [delete]
+func (a *AdminRouter) purgeCache(c *gin.Context) {
+	pattern := c.Query("pattern")
+	if pattern == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Missing pattern"})
+		return
+	}
+	n, err := a.adminService.PurgeCache(c.Request.Context(), pattern)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.PurgeResponse{Pattern: pattern, Deleted: n})
+}
+
+// exportSnapshot godoc
+// @Summary Export a cache snapshot
+// @Description Streams every cached movie, discover page and saved movie with its remaining TTL as
+// @Description newline-delimited JSON, gzip-compressed if requested. The first line is a header with the snapshot
+// @Description version.
+// @Tags admin
+// @Produce  application/x-ndjson
+// @Produce  application/gzip
+// @Security AdminToken
+// @Param gzip query bool false "Compress the snapshot with gzip"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/cache/snapshot [get]
+func (a *AdminRouter) exportSnapshot(c *gin.Context) {
+	compress, err := strconv.ParseBool(c.DefaultQuery("gzip", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid gzip parameter"})
+		return
+	}
+	contentType, filename := "application/x-ndjson", "cache-snapshot.ndjson"
+	if compress {
+		contentType, filename = "application/gzip", filename+".gz"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	_, err = a.adminService.ExportSnapshot(c.Request.Context(), c.Writer, compress)
+	if err == nil {
+		return
+	}
+	if !c.Writer.Written() {
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		writeError(c, err)
+		return
+	}
+	// Part of the snapshot is already sent, so the status can no longer change.
+	log.Printf("Cache snapshot download ended early: %v", err)
+}
+
+// importSnapshot godoc
+// @Summary Import a cache snapshot
+// @Description Loads a snapshot written by GET /admin/cache/snapshot, plain or gzip-compressed, restoring
+// @Description the remaining TTL of every entry. Keys that already hold a value are skipped unless mode is overwrite.
+// @Description Snapshots larger than CACHE_SNAPSHOT_MAX_BYTES, as uploaded or once decompressed, are rejected.
+// @Tags admin
+// @Accept  application/x-ndjson
+// @Accept  application/gzip
+// @Produce  json
+// @Security AdminToken
+// @Param mode query string false "What to do with existing keys" Enums(skip, overwrite) default(skip)
+// @Success 200 {object} cache.SnapshotResult
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/cache/snapshot [post]
+func (a *AdminRouter) importSnapshot(c *gin.Context) {
+	var overwrite bool
+	switch c.DefaultQuery("mode", "skip") {
+	case "skip":
+	case "overwrite":
+		overwrite = true
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid mode, expected skip or overwrite"})
+		return
+	}
+	body := c.Request.Body
+	if a.maxSnapshotBytes > 0 {
+		body = http.MaxBytesReader(c.Writer, body, a.maxSnapshotBytes)
+	}
+	result, err := a.adminService.ImportSnapshot(c.Request.Context(), body, overwrite)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = fmt.Errorf("%w: upload of more than %d bytes", cache.ErrSnapshotTooLarge, tooLarge.Limit)
+		}
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// getWarmupStatus godoc
+// @Summary Cache warm-up status
+// @Description Reports the progress of the current or last warm-up run and whether it warmed every
+// @Description discover page and movie.
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Success 200 {object} warmup.Status
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/warmup [get]
+func (a *AdminRouter) getWarmupStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, a.warmer.Status())
+}
+
+// triggerWarmup godoc
+// @Summary Start a cache warm-up
+// @Description Starts a warm-up run in the background. Its progress is reported by GET /admin/warmup.
+// @Tags admin
+// @Produce  json
+// @Security AdminToken
+// @Success 202 {object} warmup.Status
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/warmup [post]
+func (a *AdminRouter) triggerWarmup(c *gin.Context) {
+	err := a.warmer.Trigger(c.Request.Context())
+	switch {
+	case errors.Is(err, warmup.ErrRunning), errors.Is(err, warmup.ErrDisabled):
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusAccepted, a.warmer.Status())
+	}
+}
@@ -0,0 +1,382 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
+	"github.com/elberthcabrales/movies-api/pkg/warmup"
+)
+
+const testAdminToken = "admin-secret"
+
+type MockCacheAdminService struct {
+	mock.Mock
+}
+
+func (m *MockCacheAdminService) InspectMovie(ctx context.Context, id string) (*models.CacheEntry, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.CacheEntry), args.Error(1)
+}
+
+func (m *MockCacheAdminService) EvictMovie(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockCacheAdminService) PurgeCache(ctx context.Context, pattern string) (int, error) {
+	args := m.Called(ctx, pattern)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockCacheAdminService) RefreshMovie(ctx context.Context, id string) (*models.Movie, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Movie), args.Error(1)
+}
+
+func (m *MockCacheAdminService) ExportSnapshot(ctx context.Context, w io.Writer, compress bool) (cache.SnapshotResult, error) {
+	args := m.Called(ctx, w, compress)
+	return args.Get(0).(cache.SnapshotResult), args.Error(1)
+}
+
+func (m *MockCacheAdminService) ImportSnapshot(ctx context.Context, r io.Reader, overwrite bool) (cache.SnapshotResult, error) {
+	args := m.Called(ctx, r, overwrite)
+	return args.Get(0).(cache.SnapshotResult), args.Error(1)
+}
+
+type stubWarmer struct {
+	status warmup.Status
+	err    error
+}
+
+func (s *stubWarmer) Status() warmup.Status {
+	return s.status
+}
+
+func (s *stubWarmer) Trigger(ctx context.Context) error {
+	if s.err == nil {
+		s.status.State = warmup.StateRunning
+	}
+	return s.err
+}
+
+func newAdminTestRouter(adminService *MockCacheAdminService, metrics *cache.Metrics) *gin.Engine {
+	return newAdminTestRouterWithWarmer(adminService, metrics, &stubWarmer{})
+}
+
+func newAdminTestRouterWithWarmer(adminService *MockCacheAdminService, metrics *cache.Metrics, warmer Warmer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewAdminRouter(adminService, metrics, warmer, testAdminToken, 0).RegisterRoutes(router)
+	return router
+}
+
+func newAdminRequest(method, target string) *http.Request {
+	req, _ := http.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+func TestAdminRoutes_RequireToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		configured    string
+		authorization string
+		expected      int
+	}{
+		{name: "missing token", configured: testAdminToken, expected: http.StatusUnauthorized},
+		{name: "wrong token", configured: testAdminToken, authorization: "Bearer nope", expected: http.StatusUnauthorized},
+		{name: "not a bearer token", configured: testAdminToken, authorization: testAdminToken, expected: http.StatusUnauthorized},
+		{name: "admin API disabled", configured: "", authorization: "Bearer ", expected: http.StatusForbidden},
+		{name: "valid token", configured: testAdminToken, authorization: "Bearer " + testAdminToken, expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			NewAdminRouter(new(MockCacheAdminService), cache.NewMetrics(), &stubWarmer{}, tt.configured, 0).RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/admin/cache/stats", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestGetCacheStats(t *testing.T) {
+	metrics := cache.NewMetrics()
+	movieCache := cache.NewInstrumentedCache(cache.NewMemoryCache(&config.CacheConfig{Shards: 1}), metrics)
+	_, _ = movieCache.GetValue(context.Background(), cache.MovieKey("573435", cache.DefaultLanguage))
+	router := newAdminTestRouter(new(MockCacheAdminService), metrics)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("GET", "/admin/cache/stats"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stats cache.Stats
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, uint64(1), stats.Classes[cache.KeyClassMovie].Misses)
+	assert.Equal(t, &cache.BackendStats{Entries: 0, Evictions: 0}, stats.Backend)
+}
+
+func TestInspectMovie(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	entry := &models.CacheEntry{
+		Key:        "movies:v2:movie:1:en-US",
+		Source:     repositories.SourceTMDB,
+		TTLSeconds: 3600,
+		Value:      json.RawMessage(`{"id":1}`),
+	}
+	adminService.On("InspectMovie", mock.Anything, "1").Return(entry, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("GET", "/admin/cache/movies/1"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"key": "movies:v2:movie:1:en-US", "source": "tmdb", "ttl_seconds": 3600, "stale": false, "value": {"id": 1}}`, w.Body.String())
+}
+
+func TestInspectMovie_NotCached(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	notFound := fmt.Errorf("%w: 1", repositories.ErrNotFound)
+	adminService.On("InspectMovie", mock.Anything, "1").Return((*models.CacheEntry)(nil), notFound)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("GET", "/admin/cache/movies/1"))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestEvictMovie(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	adminService.On("EvictMovie", mock.Anything, "1").Return(nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("DELETE", "/admin/cache/movies/1"))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	adminService.AssertExpectations(t)
+}
+
+func TestEvictMovie_CacheUnavailable(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	adminService.On("EvictMovie", mock.Anything, "1").Return(cache.ErrCacheUnavailable)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("DELETE", "/admin/cache/movies/1"))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestRefreshMovie(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	adminService.On("RefreshMovie", mock.Anything, "1").Return(&models.Movie{ID: 1, Title: "Test Movie"}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("POST", "/admin/cache/movies/1/refresh"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var movie models.Movie
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &movie))
+	assert.Equal(t, "Test Movie", movie.Title)
+}
+
+func TestPurgeCache(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	adminService.On("PurgeCache", mock.Anything, "movies:v2:discover:*").Return(4, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("DELETE", "/admin/cache?pattern=movies:v2:discover:*"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"pattern": "movies:v2:discover:*", "deleted": 4}`, w.Body.String())
+}
+
+func TestPurgeCache_InvalidPattern(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	adminService.On("PurgeCache", mock.Anything, "*").Return(0, fmt.Errorf("%w: outside namespace", cache.ErrInvalidPattern))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("DELETE", "/admin/cache?pattern=*"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("DELETE", "/admin/cache"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	adminService.AssertNumberOfCalls(t, "PurgeCache", 1)
+}
+
+func TestExportSnapshot(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	adminService.On("ExportSnapshot", mock.Anything, mock.Anything, true).
+		Run(func(args mock.Arguments) {
+			_, _ = args.Get(1).(io.Writer).Write([]byte("snapshot"))
+		}).
+		Return(cache.SnapshotResult{Written: 1}, nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("GET", "/admin/cache/snapshot?gzip=true"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="cache-snapshot.ndjson.gz"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "snapshot", w.Body.String())
+}
+
+func TestExportSnapshot_CacheUnavailable(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	adminService.On("ExportSnapshot", mock.Anything, mock.Anything, false).Return(cache.SnapshotResult{}, cache.ErrCacheUnavailable)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("GET", "/admin/cache/snapshot"))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+}
+
+func TestImportSnapshot(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		overwrite bool
+	}{
+		{name: "skip by default", query: "", overwrite: false},
+		{name: "skip", query: "?mode=skip", overwrite: false},
+		{name: "overwrite", query: "?mode=overwrite", overwrite: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adminService := new(MockCacheAdminService)
+			router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+			adminService.On("ImportSnapshot", mock.Anything, mock.Anything, tt.overwrite).Return(cache.SnapshotResult{Written: 3, Skipped: 1}, nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, newAdminRequest("POST", "/admin/cache/snapshot"+tt.query))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, `{"written": 3, "skipped": 1}`, w.Body.String())
+		})
+	}
+}
+
+func TestImportSnapshot_BadRequest(t *testing.T) {
+	adminService := new(MockCacheAdminService)
+	router := newAdminTestRouter(adminService, cache.NewMetrics())
+
+	adminService.On("ImportSnapshot", mock.Anything, mock.Anything, false).Return(cache.SnapshotResult{}, fmt.Errorf("%w: missing header", cache.ErrInvalidSnapshot))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("POST", "/admin/cache/snapshot"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("POST", "/admin/cache/snapshot?mode=merge"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	adminService.AssertNumberOfCalls(t, "ImportSnapshot", 1)
+}
+
+func TestImportSnapshot_TooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	adminService := new(MockCacheAdminService)
+	router := gin.New()
+	NewAdminRouter(adminService, cache.NewMetrics(), &stubWarmer{}, testAdminToken, 16).RegisterRoutes(router)
+
+	// The service reads the whole body, as the snapshot import does.
+	call := adminService.On("ImportSnapshot", mock.Anything, mock.Anything, false)
+	call.Run(func(args mock.Arguments) {
+		_, err := io.ReadAll(args.Get(1).(io.Reader))
+		call.ReturnArguments = mock.Arguments{cache.SnapshotResult{}, err}
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/cache/snapshot", strings.NewReader(`{"snapshot":1}`+"\n"+`{"key":"movies:v2:movie:1:en-US"}`))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "snapshot too large")
+}
+
+func TestGetWarmupStatus(t *testing.T) {
+	warmer := &stubWarmer{status: warmup.Status{
+		State:  warmup.StateDone,
+		Warm:   true,
+		Runs:   1,
+		Pages:  warmup.Progress{Total: 2, Done: 2},
+		Movies: warmup.Progress{Total: 40, Done: 39, Failed: 1},
+	}}
+	router := newAdminTestRouterWithWarmer(new(MockCacheAdminService), cache.NewMetrics(), warmer)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("GET", "/admin/warmup"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"state": "done",
+		"warm": true,
+		"runs": 1,
+		"pages": {"total": 2, "done": 2, "failed": 0},
+		"movies": {"total": 40, "done": 39, "failed": 1}
+	}`, w.Body.String())
+}
+
+func TestTriggerWarmup(t *testing.T) {
+	router := newAdminTestRouterWithWarmer(new(MockCacheAdminService), cache.NewMetrics(), &stubWarmer{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("POST", "/admin/warmup"))
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"running"`)
+}
+
+func TestTriggerWarmup_AlreadyRunning(t *testing.T) {
+	router := newAdminTestRouterWithWarmer(new(MockCacheAdminService), cache.NewMetrics(), &stubWarmer{err: warmup.ErrRunning})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, newAdminRequest("POST", "/admin/warmup"))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"error": "warm-up already running"}`, w.Body.String())
+}
@@ -0,0 +1,123 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+// releaseDateLayout is the format of the release date filters.
+const releaseDateLayout = "2006-01-02"
+
+var (
+	originalLanguagePattern = regexp.MustCompile(`^[a-z]{2}$`)
+	regionPattern           = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// sortOrders are the sort_by values accepted by TMDB's /discover/movie.
+var sortOrders = func() map[string]bool {
+	orders := map[string]bool{}
+	for _, field := range []string{"popularity", "revenue", "primary_release_date", "vote_average", "vote_count", "title", "original_title"} {
+		orders[field+".asc"] = true
+		orders[field+".desc"] = true
+	}
+	return orders
+}()
+
+// parseDiscoverQuery reads the page and filters of GET /movies. Its errors
+// are meant for the client.
+func parseDiscoverQuery(c *gin.Context) (models.DiscoverQuery, error) {
+	query := models.DiscoverQuery{
+		Page:             1,
+		ReleaseDateFrom:  c.Query("release_date_from"),
+		ReleaseDateTo:    c.Query("release_date_to"),
+		OriginalLanguage: c.Query("original_language"),
+		Region:           c.Query("region"),
+		SortBy:           c.Query("sort_by"),
+	}
+	ints := []struct {
+		name  string
+		value *int
+	}{
+		{"page", &query.Page},
+		{"year", &query.Year},
+		{"min_vote_count", &query.MinVoteCount},
+		{"min_runtime", &query.MinRuntime},
+		{"max_runtime", &query.MaxRuntime},
+	}
+	for _, param := range ints {
+		if raw := c.Query(param.name); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil || value < 0 {
+				return query, fmt.Errorf("Invalid %s value", param.name)
+			}
+			*param.value = value
+		}
+	}
+	if raw := c.Query("genres"); raw != "" {
+		for _, genre := range strings.Split(raw, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(genre))
+			if err != nil || id <= 0 {
+				return query, errors.New("Invalid genres value")
+			}
+			query.GenreIDs = append(query.GenreIDs, id)
+		}
+	}
+	if raw := c.Query("min_vote_average"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 || value > 10 {
+			return query, errors.New("Invalid min_vote_average value")
+		}
+		query.MinVoteAverage = value
+	}
+	if raw := c.Query("include_adult"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return query, errors.New("Invalid include_adult value")
+		}
+		query.IncludeAdult = value
+	}
+	return query, validateDiscoverQuery(query)
+}
+
+// validateDiscoverQuery checks the values that TMDB would reject or ignore.
+func validateDiscoverQuery(query models.DiscoverQuery) error {
+	if !validPage(query.Page) {
+		return errPageOutOfRange
+	}
+	var from, to time.Time
+	var err error
+	if query.ReleaseDateFrom != "" {
+		if from, err = time.Parse(releaseDateLayout, query.ReleaseDateFrom); err != nil {
+			return errors.New("Invalid release_date_from value")
+		}
+	}
+	if query.ReleaseDateTo != "" {
+		if to, err = time.Parse(releaseDateLayout, query.ReleaseDateTo); err != nil {
+			return errors.New("Invalid release_date_to value")
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return errors.New("release_date_from is after release_date_to")
+	}
+	if query.MaxRuntime > 0 && query.MinRuntime > query.MaxRuntime {
+		return errors.New("min_runtime is greater than max_runtime")
+	}
+	if query.OriginalLanguage != "" && !originalLanguagePattern.MatchString(query.OriginalLanguage) {
+		return errors.New("Invalid original_language value")
+	}
+	if query.Region != "" && !regionPattern.MatchString(query.Region) {
+		return errors.New("Invalid region value")
+	}
+	if query.SortBy != "" && !sortOrders[query.SortBy] {
+		return errors.New("Invalid sort_by value")
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+func discoverContext(rawQuery string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/movies?"+rawQuery, nil)
+	return c
+}
+
+func TestParseDiscoverQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	query, err := parseDiscoverQuery(discoverContext("page=3&genres=28,%2012&year=2024" +
+		"&release_date_from=2024-01-01&release_date_to=2024-06-30&min_vote_average=7.5&min_vote_count=100" +
+		"&min_runtime=90&max_runtime=150&original_language=es&region=MX&sort_by=vote_average.desc&include_adult=true"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.DiscoverQuery{
+		Page:             3,
+		GenreIDs:         []int{28, 12},
+		Year:             2024,
+		ReleaseDateFrom:  "2024-01-01",
+		ReleaseDateTo:    "2024-06-30",
+		MinVoteAverage:   7.5,
+		MinVoteCount:     100,
+		MinRuntime:       90,
+		MaxRuntime:       150,
+		OriginalLanguage: "es",
+		Region:           "MX",
+		SortBy:           "vote_average.desc",
+		IncludeAdult:     true,
+	}, query)
+}
+
+func TestParseDiscoverQuery_Defaults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	query, err := parseDiscoverQuery(discoverContext(""))
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.DiscoverQuery{Page: 1}, query)
+}
+
+func TestParseDiscoverQuery_Invalid(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := map[string]string{
+		"page=0":                      "page must be between 1 and 500",
+		"page=501":                    "page must be between 1 and 500",
+		"page=next":                   "Invalid page value",
+		"year=last":                   "Invalid year value",
+		"min_vote_count=-1":           "Invalid min_vote_count value",
+		"genres=28,action":            "Invalid genres value",
+		"genres=28,":                  "Invalid genres value",
+		"min_vote_average=11":         "Invalid min_vote_average value",
+		"include_adult=maybe":         "Invalid include_adult value",
+		"release_date_from=2024-13-1": "Invalid release_date_from value",
+		"release_date_to=yesterday":   "Invalid release_date_to value",
+		"release_date_from=2024-02-01&release_date_to=2024-01-01": "release_date_from is after release_date_to",
+		"min_runtime=120&max_runtime=90":                          "min_runtime is greater than max_runtime",
+		"original_language=spanish":                               "Invalid original_language value",
+		"region=mx":                                               "Invalid region value",
+		"sort_by=random":                                          "Invalid sort_by value",
+	}
+	for rawQuery, message := range tests {
+		_, err := parseDiscoverQuery(discoverContext(rawQuery))
+		assert.EqualError(t, err, message, rawQuery)
+	}
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
+)
+
+// errorStatus maps an error returned by a service to a status code. Errors of
+// the upstream API that are not the client's doing are reported as 502.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repositories.ErrInvalidInput),
+		errors.Is(err, cache.ErrInvalidPattern), errors.Is(err, cache.ErrInvalidSnapshot):
+		return http.StatusBadRequest
+	case errors.Is(err, cache.ErrSnapshotTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, repositories.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, repositories.ErrUpstreamUnavailable), errors.Is(err, cache.ErrCacheUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, repositories.ErrUnauthorized), errors.Is(err, repositories.ErrUpstream):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError answers with the status of err. When the rate limiter or the
+// upstream API asked to wait, the delay is sent in a Retry-After header.
+func writeError(c *gin.Context, err error) {
+	if delay, ok := repositories.RetryAfter(err); ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	}
+	c.JSON(errorStatus(err), models.ErrorResponse{Error: err.Error()})
+}
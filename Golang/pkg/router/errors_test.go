@@ -0,0 +1,52 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{err: fmt.Errorf("%w: 1", repositories.ErrNotFound), expected: http.StatusNotFound},
+		{err: &repositories.UpstreamError{StatusCode: http.StatusNotFound}, expected: http.StatusNotFound},
+		{err: fmt.Errorf("%w: movie ID \"abc\"", repositories.ErrInvalidInput), expected: http.StatusBadRequest},
+		{err: &repositories.UpstreamError{StatusCode: http.StatusUnprocessableEntity}, expected: http.StatusBadRequest},
+		{err: cache.ErrInvalidPattern, expected: http.StatusBadRequest},
+		{err: fmt.Errorf("%w: more than 1 bytes", cache.ErrSnapshotTooLarge), expected: http.StatusRequestEntityTooLarge},
+		{err: &repositories.RateLimitError{}, expected: http.StatusTooManyRequests},
+		{err: &repositories.UpstreamError{StatusCode: http.StatusTooManyRequests}, expected: http.StatusTooManyRequests},
+		{err: repositories.ErrUpstreamUnavailable, expected: http.StatusServiceUnavailable},
+		{err: cache.ErrCacheUnavailable, expected: http.StatusServiceUnavailable},
+		{err: &repositories.UpstreamError{StatusCode: http.StatusUnauthorized}, expected: http.StatusBadGateway},
+		{err: &repositories.UpstreamError{StatusCode: http.StatusInternalServerError}, expected: http.StatusBadGateway},
+		{err: errors.New("boom"), expected: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, errorStatus(tt.err), "Unexpected status for %v", tt.err)
+	}
+}
+
+func TestWriteError_RetryAfter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	writeError(c, &repositories.UpstreamError{StatusCode: http.StatusTooManyRequests, Message: "Slow down", Code: 25, RetryAfter: 10 * time.Second})
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error": "upstream API returned status 429: Slow down (code 25)"}`, w.Body.String())
+}
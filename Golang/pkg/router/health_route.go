@@ -0,0 +1,62 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
+)
+
+// Values reported by the health endpoint.
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+)
+
+// HealthRouter reports the health of the service and its dependencies.
+type HealthRouter struct {
+	cache   cache.Cache
+	breaker *repositories.CircuitBreaker
+}
+
+// NewHealthRouter creates a new HealthRouter. breaker may be nil when the
+// upstream API is called without a circuit breaker.
+func NewHealthRouter(movieCache cache.Cache, breaker *repositories.CircuitBreaker) *HealthRouter {
+	return &HealthRouter{cache: movieCache, breaker: breaker}
+}
+
+// RegisterRoutes adds the health routes to router
+func (h *HealthRouter) RegisterRoutes(router *gin.Engine) {
+	router.GET("/health", h.getHealth)
+}
+
+// getHealth godoc
+// @Summary Service health
+// @Description Reports whether the service, its cache and the upstream API are healthy. The
+// @Description service keeps answering from the cache while either is unavailable, so a degraded
+// @Description dependency still returns 200. The upstream API is degraded while its circuit
+// @Description breaker is open or half-open.
+// @Tags health
+// @Produce  json
+// @Success 200 {object} models.HealthResponse
+// @Router /health [get]
+func (h *HealthRouter) getHealth(c *gin.Context) {
+	response := models.HealthResponse{Status: healthOK, Cache: healthOK}
+	if !h.cache.Healthy() {
+		response.Status = healthDegraded
+		response.Cache = healthDegraded
+	}
+	if h.breaker != nil {
+		state := h.breaker.State()
+		response.Circuit = string(state)
+		response.Upstream = healthOK
+		if state != repositories.CircuitClosed {
+			response.Status = healthDegraded
+			response.Upstream = healthDegraded
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
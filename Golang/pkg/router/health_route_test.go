@@ -0,0 +1,88 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
+)
+
+type stubCache struct {
+	cache.Cache
+	healthy bool
+}
+
+func (s *stubCache) Healthy() bool {
+	return s.healthy
+}
+
+func TestGetHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		healthy  bool
+		expected string
+	}{
+		{name: "healthy", healthy: true, expected: `{"status": "ok", "cache": "ok"}`},
+		{name: "degraded", healthy: false, expected: `{"status": "degraded", "cache": "degraded"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			NewHealthRouter(&stubCache{healthy: tt.healthy}, nil).RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/health", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.expected, w.Body.String())
+		})
+	}
+}
+
+func TestGetHealth_CircuitBreaker(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+	cfg := &config.UpstreamConfig{
+		BaseURL:               upstream.URL,
+		BreakerFailureRate:    0.5,
+		BreakerMinRequests:    1,
+		BreakerWindow:         time.Minute,
+		BreakerOpenTimeout:    time.Minute,
+		BreakerHalfOpenProbes: 1,
+	}
+	breaker := repositories.NewCircuitBreaker(cfg)
+	router := gin.New()
+	NewHealthRouter(&stubCache{healthy: true}, breaker).RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/health", nil)
+	router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"status": "ok", "cache": "ok", "upstream": "ok", "circuit": "closed"}`, w.Body.String())
+
+	repo := repositories.NewMovieRepository("token", cache.NewMemoryCache(&config.CacheConfig{Shards: 1}),
+		repositories.WithUpstream(cfg), repositories.WithCircuitBreaker(breaker))
+	_, err := repo.GetMovies(context.Background(), models.DiscoverQuery{Page: 1})
+	assert.Error(t, err)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "degraded", "cache": "ok", "upstream": "degraded", "circuit": "open"}`, w.Body.String())
+}
@@ -1,15 +1,20 @@
 package router
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
 	"github.com/elberthcabrales/movies-api/pkg/services"
 )
 
+// cacheStatusHeader tells clients whether a response was served from the cache.
+const cacheStatusHeader = "X-Cache"
+
 // MovieRouter handles the routing of movie-related HTTP requests.
 type MovieRouter struct {
 	movieService services.MovieService
@@ -26,9 +31,14 @@ func NewMovieRouter(movieService services.MovieService) *MovieRouter {
 // @description This is a sample server for managing movies.
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Admin token as "Bearer <ADMIN_TOKEN>"
 func (r *MovieRouter) SetupRouter() *gin.Engine {
 	router := gin.Default()
 
+	router.GET("/movies/search", r.searchMovies)
 	router.GET("/movies/:id", r.getMovieByID)
 	router.GET("/movies", r.getMovies)
 	router.POST("/movies", r.saveMovie)
@@ -44,42 +54,126 @@ func (r *MovieRouter) SetupRouter() *gin.Engine {
 // @Produce  json
 // @Param id path string true "Movie ID"
 // @Success 200 {object} models.Movie
+// @Header 200 {string} X-Cache "HIT, STALE or MISS"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Header 404 {string} X-Cache "HIT or MISS"
+// @Failure 429 {object} models.ErrorResponse
+// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /movies/{id} [get]
 func (r *MovieRouter) getMovieByID(c *gin.Context) {
 	id := c.Param("id")
-	movie, err := r.movieService.GetMovieByID(id)
+	movie, status, err := r.movieService.GetMovieByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		if errors.Is(err, repositories.ErrNotFound) {
+			c.Header(cacheStatusHeader, string(status))
+		}
+		writeError(c, err)
 		return
 	}
+	c.Header(cacheStatusHeader, string(status))
 	c.JSON(http.StatusOK, movie)
 }
 
 // getMovies godoc
 // @Summary Get movies
-// @Description Get a list of movies
+// @Description Get a list of movies from TMDB's discover, optionally filtered and sorted
 // @Tags movies
 // @Accept  json
 // @Produce  json
-// @Param page query int false "Page number"
-// @Success 200 {array} models.Movie
+// @Param page query int false "Page number, 1 to 500"
+// @Param genres query string false "Comma-separated genre IDs; movies must have all of them"
+// @Param year query int false "Primary release year"
+// @Param release_date_from query string false "Earliest primary release date, YYYY-MM-DD"
+// @Param release_date_to query string false "Latest primary release date, YYYY-MM-DD"
+// @Param min_vote_average query number false "Minimum vote average, 0 to 10"
+// @Param min_vote_count query int false "Minimum vote count"
+// @Param min_runtime query int false "Minimum runtime in minutes"
+// @Param max_runtime query int false "Maximum runtime in minutes"
+// @Param original_language query string false "ISO 639-1 original language, e.g. es"
+// @Param region query string false "ISO 3166-1 region, e.g. MX"
+// @Param sort_by query string false "Sort order, e.g. popularity.desc or vote_average.asc"
+// @Param include_adult query bool false "Include adult movies"
+// @Success 200 {object} models.MovieList
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /movies [get]
 func (r *MovieRouter) getMovies(c *gin.Context) {
-	page := c.DefaultQuery("page", "1")
-	pageInt, err := strconv.Atoi(page)
+	query, err := parseDiscoverQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	movies, err := r.movieService.GetMovies(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid page value"})
+		writeError(c, err)
 		return
 	}
-	movies, err := r.movieService.GetMovies(pageInt)
+	c.JSON(http.StatusOK, paginate(c, movies))
+}
+
+// searchMovies godoc
+// @Summary Search movies
+// @Description Search movies by title. Movies saved through the API replace the results with the same ID;
+// @Description saved movies TMDB does not return are added when their title matches and the results fit on one page.
+// @Tags movies
+// @Accept  json
+// @Produce  json
+// @Param q query string true "Title to search for"
+// @Param year query int false "Release year"
+// @Param page query int false "Page number, 1 to 500"
+// @Param language query string false "Language of the results, e.g. es-MX"
+// @Param include_adult query bool false "Include adult movies"
+// @Success 200 {object} models.MovieList
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Header 429 {integer} Retry-After "Seconds to wait before retrying"
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /movies/search [get]
+func (r *MovieRouter) searchMovies(c *gin.Context) {
+	query := models.SearchQuery{Query: c.Query("q"), Language: c.Query("language")}
+	if query.Query == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Missing q parameter"})
+		return
+	}
+	var err error
+	if year := c.Query("year"); year != "" {
+		if query.Year, err = strconv.Atoi(year); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid year value"})
+			return
+		}
+	}
+	if page := c.Query("page"); page != "" {
+		if query.Page, err = strconv.Atoi(page); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid page value"})
+			return
+		}
+		if !validPage(query.Page) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: errPageOutOfRange.Error()})
+			return
+		}
+	}
+	if includeAdult := c.Query("include_adult"); includeAdult != "" {
+		if query.IncludeAdult, err = strconv.ParseBool(includeAdult); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid include_adult value"})
+			return
+		}
+	}
+	movies, err := r.movieService.SearchMovies(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		writeError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, movies)
+	c.JSON(http.StatusOK, paginate(c, movies))
 }
 
 // saveMovie godoc
@@ -92,6 +186,7 @@ func (r *MovieRouter) getMovies(c *gin.Context) {
 // @Success 200 {object} models.SuccessResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /movies [post]
 func (r *MovieRouter) saveMovie(c *gin.Context) {
 	var movie models.Movie
@@ -99,8 +194,8 @@ func (r *MovieRouter) saveMovie(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body"})
 		return
 	}
-	if err := r.movieService.SaveMovie(&movie); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+	if err := r.movieService.SaveMovie(c.Request.Context(), &movie); err != nil {
+		writeError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Movie saved successfully"})
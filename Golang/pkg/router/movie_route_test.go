@@ -2,35 +2,45 @@ package router
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/elberthcabrales/movies-api/pkg/cache"
 	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
 )
 
 type MockMovieService struct {
 	mock.Mock
 }
 
-func (m *MockMovieService) GetMovieByID(id string) (*models.Movie, error) {
-	args := m.Called(id)
-	return args.Get(0).(*models.Movie), args.Error(1)
+func (m *MockMovieService) GetMovieByID(ctx context.Context, id string) (*models.Movie, cache.Status, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Movie), args.Get(1).(cache.Status), args.Error(2)
 }
 
-func (m *MockMovieService) GetMovies(page int) (*models.MovieList, error) {
-	args := m.Called(page)
+func (m *MockMovieService) GetMovies(ctx context.Context, query models.DiscoverQuery) (*models.MovieList, error) {
+	args := m.Called(ctx, query)
 	return args.Get(0).(*models.MovieList), args.Error(1)
 }
 
-func (m *MockMovieService) SaveMovie(movie *models.Movie) error {
-	args := m.Called(movie)
+func (m *MockMovieService) SearchMovies(ctx context.Context, query models.SearchQuery) (*models.MovieList, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(*models.MovieList), args.Error(1)
+}
+
+func (m *MockMovieService) SaveMovie(ctx context.Context, movie *models.Movie) error {
+	args := m.Called(ctx, movie)
 	return args.Error(0)
 }
 
@@ -45,13 +55,14 @@ func TestGetMovieByID(t *testing.T) {
 		Title: "Test Movie",
 	}
 
-	mockService.On("GetMovieByID", "1").Return(expectedMovie, nil)
+	mockService.On("GetMovieByID", mock.Anything, "1").Return(expectedMovie, cache.StatusHit, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/movies/1", nil)
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
 
 	var actualMovie models.Movie
 	err := json.Unmarshal(w.Body.Bytes(), &actualMovie)
@@ -59,13 +70,34 @@ func TestGetMovieByID(t *testing.T) {
 	assert.Equal(t, expectedMovie, &actualMovie)
 }
 
+type ctxKey struct{}
+
+func TestGetMovieByID_PassesRequestContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockMovieService)
+	router := NewMovieRouter(mockService).SetupRouter()
+
+	fromRequest := mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Value(ctxKey{}) == "request"
+	})
+	mockService.On("GetMovieByID", fromRequest, "1").Return(&models.Movie{ID: 1}, cache.StatusHit, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(context.WithValue(context.Background(), ctxKey{}, "request"), "GET", "/movies/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
 func TestGetMovieByID_ServiceError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	mockService := new(MockMovieService)
 	router := NewMovieRouter(mockService).SetupRouter()
 
-	mockService.On("GetMovieByID", "1").Return((*models.Movie)(nil), errors.New("service error"))
+	mockService.On("GetMovieByID", mock.Anything, "1").Return((*models.Movie)(nil), cache.StatusMiss, errors.New("service error"))
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/movies/1", nil)
@@ -75,6 +107,56 @@ func TestGetMovieByID_ServiceError(t *testing.T) {
 	assert.JSONEq(t, `{"error": "service error"}`, w.Body.String())
 }
 
+func TestGetMovieByID_StaleHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockMovieService)
+	router := NewMovieRouter(mockService).SetupRouter()
+
+	mockService.On("GetMovieByID", mock.Anything, "1").Return(&models.Movie{ID: 1, Title: "Test Movie"}, cache.StatusStale, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "STALE", w.Header().Get("X-Cache"))
+}
+
+func TestGetMovieByID_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockMovieService)
+	router := NewMovieRouter(mockService).SetupRouter()
+
+	notFound := fmt.Errorf("%w: 999999999", repositories.ErrNotFound)
+	mockService.On("GetMovieByID", mock.Anything, "999999999").Return((*models.Movie)(nil), cache.StatusHit, notFound)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies/999999999", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.JSONEq(t, `{"error": "movie not found: 999999999"}`, w.Body.String())
+}
+
+func TestGetMovieByID_UpstreamUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockMovieService)
+	router := NewMovieRouter(mockService).SetupRouter()
+
+	mockService.On("GetMovieByID", mock.Anything, "1").Return((*models.Movie)(nil), cache.StatusMiss, repositories.ErrUpstreamUnavailable)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error": "upstream API unavailable"}`, w.Body.String())
+}
+
 func TestGetMovies(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -87,10 +169,12 @@ func TestGetMovies(t *testing.T) {
 			{ID: 2, Title: "Movie 2"},
 			{ID: 3, Title: "Movie 3"},
 		},
-		Page: 1,
+		Page:         1,
+		TotalPages:   1,
+		TotalResults: 3,
 	}
 
-	mockService.On("GetMovies", 1).Return(expectedMovies, nil)
+	mockService.On("GetMovies", mock.Anything, models.DiscoverQuery{Page: 1}).Return(expectedMovies, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "/movies?page=1", nil)
@@ -101,7 +185,87 @@ func TestGetMovies(t *testing.T) {
 	var actualMovies models.MovieList
 	err := json.Unmarshal(w.Body.Bytes(), &actualMovies)
 	assert.NoError(t, err)
-	assert.Equal(t, *expectedMovies, actualMovies)
+	paginated := *expectedMovies
+	paginated.PageSize = 20
+	assert.Equal(t, paginated, actualMovies)
+	assert.Zero(t, expectedMovies.PageSize, "Expected the service's list to be left untouched")
+}
+
+func TestGetMovies_Filters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockMovieService)
+	router := NewMovieRouter(mockService).SetupRouter()
+
+	expectedMovies := &models.MovieList{Results: []models.Movie{{ID: 1, Title: "Movie 1"}}, Page: 2}
+	query := models.DiscoverQuery{Page: 2, GenreIDs: []int{28, 12}, MinVoteAverage: 7, SortBy: "vote_average.desc"}
+	mockService.On("GetMovies", mock.Anything, query).Return(expectedMovies, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies?page=2&genres=28,12&min_vote_average=7&sort_by=vote_average.desc", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetMovies_InvalidFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := NewMovieRouter(nil).SetupRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies?sort_by=random", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "Invalid sort_by value"}`, w.Body.String())
+}
+
+func TestGetMovies_PageOutOfRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := NewMovieRouter(nil).SetupRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies?page=501", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "page must be between 1 and 500"}`, w.Body.String())
+}
+
+func TestGetMovies_UpstreamUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockMovieService)
+	router := NewMovieRouter(mockService).SetupRouter()
+
+	mockService.On("GetMovies", mock.Anything, models.DiscoverQuery{Page: 2}).Return((*models.MovieList)(nil), repositories.ErrUpstreamUnavailable)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies?page=2", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestGetMovies_RateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockMovieService)
+	router := NewMovieRouter(mockService).SetupRouter()
+
+	rateLimited := &repositories.RateLimitError{RetryAfter: 1500 * time.Millisecond}
+	mockService.On("GetMovies", mock.Anything, models.DiscoverQuery{Page: 1}).Return((*models.MovieList)(nil), rateLimited)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error": "upstream rate limit exceeded, retry in 1.5s"}`, w.Body.String())
 }
 
 func TestGetMovies_InvalidPage(t *testing.T) {
@@ -117,6 +281,74 @@ func TestGetMovies_InvalidPage(t *testing.T) {
 	assert.JSONEq(t, `{"error": "Invalid page value"}`, w.Body.String())
 }
 
+func TestSearchMovies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockMovieService)
+	router := NewMovieRouter(mockService).SetupRouter()
+
+	expectedMovies := &models.MovieList{
+		Results:      []models.Movie{{ID: 1, Title: "Movie 1"}},
+		Page:         2,
+		TotalPages:   3,
+		TotalResults: 41,
+	}
+	query := models.SearchQuery{Query: "movie", Year: 2024, Page: 2, Language: "es-MX", IncludeAdult: true}
+	mockService.On("SearchMovies", mock.Anything, query).Return(expectedMovies, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies/search?q=movie&year=2024&page=2&language=es-MX&include_adult=true", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var actualMovies models.MovieList
+	err := json.Unmarshal(w.Body.Bytes(), &actualMovies)
+	assert.NoError(t, err)
+	paginated := *expectedMovies
+	paginated.PageSize = 20
+	paginated.Previous = "/movies/search?include_adult=true&language=es-MX&page=1&q=movie&year=2024"
+	paginated.Next = "/movies/search?include_adult=true&language=es-MX&page=3&q=movie&year=2024"
+	assert.Equal(t, paginated, actualMovies)
+}
+
+func TestSearchMovies_InvalidParameters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := NewMovieRouter(nil).SetupRouter()
+
+	tests := map[string]string{
+		"/movies/search":                             "Missing q parameter",
+		"/movies/search?q=movie&year=soon":           "Invalid year value",
+		"/movies/search?q=movie&page=501":            "page must be between 1 and 500",
+		"/movies/search?q=movie&page=last":           "Invalid page value",
+		"/movies/search?q=movie&include_adult=maybe": "Invalid include_adult value",
+	}
+	for url, message := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.JSONEq(t, `{"error": "`+message+`"}`, w.Body.String(), url)
+	}
+}
+
+func TestSearchMovies_InvalidInput(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockService := new(MockMovieService)
+	router := NewMovieRouter(mockService).SetupRouter()
+
+	err := fmt.Errorf("%w: language %q", repositories.ErrInvalidInput, "spanish")
+	mockService.On("SearchMovies", mock.Anything, mock.Anything).Return((*models.MovieList)(nil), err)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/movies/search?q=movie&language=spanish", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestSaveMovie(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -128,7 +360,7 @@ func TestSaveMovie(t *testing.T) {
 		Title: "New Movie",
 	}
 
-	mockService.On("SaveMovie", newMovie).Return(nil)
+	mockService.On("SaveMovie", mock.Anything, newMovie).Return(nil)
 
 	jsonMovie, err := json.Marshal(newMovie)
 	assert.NoError(t, err)
@@ -151,7 +383,7 @@ func TestSaveMovie_ServiceError(t *testing.T) {
 		Title: "Test Movie",
 	}
 
-	mockService.On("SaveMovie", movieToSave).Return(errors.New("service error"))
+	mockService.On("SaveMovie", mock.Anything, movieToSave).Return(errors.New("service error"))
 
 	movieJSON, _ := json.Marshal(movieToSave)
 	w := httptest.NewRecorder()
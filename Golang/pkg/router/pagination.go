@@ -0,0 +1,52 @@
+package router
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+const (
+	// maxPage is the last page TMDB serves; it rejects later pages.
+	maxPage = 500
+	// pageSize is the number of movies in a page from TMDB.
+	pageSize = 20
+)
+
+// errPageOutOfRange is answered for pages TMDB would reject.
+var errPageOutOfRange = fmt.Errorf("page must be between 1 and %d", maxPage)
+
+// validPage reports whether TMDB serves page.
+func validPage(page int) bool {
+	return page >= 1 && page <= maxPage
+}
+
+// paginate returns a copy of list with its page size and the links to the
+// neighbouring pages of the request. The list itself may be shared through the
+// cache and is left untouched.
+func paginate(c *gin.Context, list *models.MovieList) *models.MovieList {
+	response := *list
+	response.PageSize = pageSize
+	if list.Page > 1 {
+		response.Previous = pageLink(c, min(list.Page-1, lastPage(list)))
+	}
+	if list.Page < lastPage(list) {
+		response.Next = pageLink(c, list.Page+1)
+	}
+	return &response
+}
+
+// lastPage returns the last page of list that can be requested.
+func lastPage(list *models.MovieList) int {
+	return max(min(list.TotalPages, maxPage), 1)
+}
+
+// pageLink returns the path and query of the request with its page replaced.
+func pageLink(c *gin.Context, page int) string {
+	query := c.Request.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	return c.Request.URL.Path + "?" + query.Encode()
+}
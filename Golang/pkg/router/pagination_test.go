@@ -0,0 +1,41 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+func TestPaginate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		list     models.MovieList
+		next     string
+		previous string
+	}{
+		{name: "first page", list: models.MovieList{Page: 1, TotalPages: 3}, next: "/movies?genres=28&page=2"},
+		{name: "middle page", list: models.MovieList{Page: 2, TotalPages: 3}, next: "/movies?genres=28&page=3", previous: "/movies?genres=28&page=1"},
+		{name: "last page", list: models.MovieList{Page: 3, TotalPages: 3}, previous: "/movies?genres=28&page=2"},
+		{name: "single page", list: models.MovieList{Page: 1, TotalPages: 1}},
+		{name: "no results", list: models.MovieList{Page: 1}},
+		{name: "past the end", list: models.MovieList{Page: 7, TotalPages: 3}, previous: "/movies?genres=28&page=3"},
+		{name: "capped by TMDB", list: models.MovieList{Page: 500, TotalPages: 900}, previous: "/movies?genres=28&page=499"},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/movies?page=9&genres=28", nil)
+
+		paginated := paginate(c, &tt.list)
+
+		assert.Equal(t, pageSize, paginated.PageSize, tt.name)
+		assert.Equal(t, tt.next, paginated.Next, tt.name)
+		assert.Equal(t, tt.previous, paginated.Previous, tt.name)
+		assert.Empty(t, tt.list.Next, "Expected the list to be left untouched")
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"io"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
+)
+
+// CacheAdminService defines the operations available to cache operators
+type CacheAdminService interface {
+	InspectMovie(ctx context.Context, id string) (*models.CacheEntry, error)
+	EvictMovie(ctx context.Context, id string) error
+	PurgeCache(ctx context.Context, pattern string) (int, error)
+	RefreshMovie(ctx context.Context, id string) (*models.Movie, error)
+	ExportSnapshot(ctx context.Context, w io.Writer, compress bool) (cache.SnapshotResult, error)
+	ImportSnapshot(ctx context.Context, r io.Reader, overwrite bool) (cache.SnapshotResult, error)
+}
+
+type cacheAdminService struct {
+	repo repositories.CacheAdminRepository
+}
+
+// NewCacheAdminService creates a new instance of CacheAdminService
+func NewCacheAdminService(repo repositories.CacheAdminRepository) CacheAdminService {
+	return &cacheAdminService{
+		repo: repo,
+	}
+}
+
+// InspectMovie returns the raw cache entry of a movie
+func (s *cacheAdminService) InspectMovie(ctx context.Context, id string) (*models.CacheEntry, error) {
+	return s.repo.InspectMovie(ctx, id)
+}
+
+// EvictMovie removes every cache entry of a movie
+func (s *cacheAdminService) EvictMovie(ctx context.Context, id string) error {
+	return s.repo.EvictMovie(ctx, id)
+}
+
+// PurgeCache removes every cache key matching pattern
+func (s *cacheAdminService) PurgeCache(ctx context.Context, pattern string) (int, error) {
+	return s.repo.PurgeCache(ctx, pattern)
+}
+
+// RefreshMovie fetches a movie from the API and replaces its cache entry
+func (s *cacheAdminService) RefreshMovie(ctx context.Context, id string) (*models.Movie, error) {
+	return s.repo.RefreshMovie(ctx, id)
+}
+
+// ExportSnapshot writes the cached movies, discover pages and saved movies to w
+func (s *cacheAdminService) ExportSnapshot(ctx context.Context, w io.Writer, compress bool) (cache.SnapshotResult, error) {
+	return s.repo.ExportSnapshot(ctx, w, compress)
+}
+
+// ImportSnapshot loads a cache snapshot read from r
+func (s *cacheAdminService) ImportSnapshot(ctx context.Context, r io.Reader, overwrite bool) (cache.SnapshotResult, error) {
+	return s.repo.ImportSnapshot(ctx, r, overwrite)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+)
+
+type MockCacheAdminRepository struct {
+	mock.Mock
+}
+
+func (m *MockCacheAdminRepository) InspectMovie(ctx context.Context, id string) (*models.CacheEntry, error) {
+	args := m.Called(ctx, id)
+	if entry, ok := args.Get(0).(*models.CacheEntry); ok {
+		return entry, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCacheAdminRepository) EvictMovie(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockCacheAdminRepository) PurgeCache(ctx context.Context, pattern string) (int, error) {
+	args := m.Called(ctx, pattern)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockCacheAdminRepository) RefreshMovie(ctx context.Context, id string) (*models.Movie, error) {
+	args := m.Called(ctx, id)
+	if movie, ok := args.Get(0).(*models.Movie); ok {
+		return movie, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockCacheAdminRepository) ExportSnapshot(ctx context.Context, w io.Writer, compress bool) (cache.SnapshotResult, error) {
+	args := m.Called(ctx, w, compress)
+	return args.Get(0).(cache.SnapshotResult), args.Error(1)
+}
+
+func (m *MockCacheAdminRepository) ImportSnapshot(ctx context.Context, r io.Reader, overwrite bool) (cache.SnapshotResult, error) {
+	args := m.Called(ctx, r, overwrite)
+	return args.Get(0).(cache.SnapshotResult), args.Error(1)
+}
+
+func TestCacheAdminService_InspectMovie(t *testing.T) {
+	mockRepo := new(MockCacheAdminRepository)
+	service := NewCacheAdminService(mockRepo)
+
+	expectedEntry := &models.CacheEntry{Key: "movies:v2:movie:573435:en-US", Source: "tmdb", TTLSeconds: 60}
+	mockRepo.On("InspectMovie", mock.Anything, "573435").Return(expectedEntry, nil)
+
+	entry, err := service.InspectMovie(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedEntry, entry)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCacheAdminService_EvictMovie(t *testing.T) {
+	mockRepo := new(MockCacheAdminRepository)
+	service := NewCacheAdminService(mockRepo)
+
+	mockRepo.On("EvictMovie", mock.Anything, "573435").Return(nil)
+
+	assert.NoError(t, service.EvictMovie(context.Background(), "573435"))
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCacheAdminService_PurgeCache(t *testing.T) {
+	mockRepo := new(MockCacheAdminRepository)
+	service := NewCacheAdminService(mockRepo)
+
+	mockRepo.On("PurgeCache", mock.Anything, "movies:v2:discover:*").Return(3, nil)
+
+	n, err := service.PurgeCache(context.Background(), "movies:v2:discover:*")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCacheAdminService_RefreshMovie(t *testing.T) {
+	mockRepo := new(MockCacheAdminRepository)
+	service := NewCacheAdminService(mockRepo)
+
+	expectedMovie := &models.Movie{ID: 573435, Title: "Bad Boys: Ride or Die"}
+	mockRepo.On("RefreshMovie", mock.Anything, "573435").Return(expectedMovie, nil)
+
+	movie, err := service.RefreshMovie(context.Background(), "573435")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovie, movie)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCacheAdminService_ExportSnapshot(t *testing.T) {
+	mockRepo := new(MockCacheAdminRepository)
+	service := NewCacheAdminService(mockRepo)
+
+	var buf bytes.Buffer
+	mockRepo.On("ExportSnapshot", mock.Anything, &buf, true).Return(cache.SnapshotResult{Written: 2}, nil)
+
+	result, err := service.ExportSnapshot(context.Background(), &buf, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, cache.SnapshotResult{Written: 2}, result)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCacheAdminService_ImportSnapshot(t *testing.T) {
+	mockRepo := new(MockCacheAdminRepository)
+	service := NewCacheAdminService(mockRepo)
+
+	snapshot := strings.NewReader(`{"snapshot":1}`)
+	mockRepo.On("ImportSnapshot", mock.Anything, snapshot, false).Return(cache.SnapshotResult{Written: 1, Skipped: 1}, nil)
+
+	result, err := service.ImportSnapshot(context.Background(), snapshot, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, cache.SnapshotResult{Written: 1, Skipped: 1}, result)
+
+	mockRepo.AssertExpectations(t)
+}
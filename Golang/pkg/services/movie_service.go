@@ -1,15 +1,19 @@
 package services
 
 import (
+	"context"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
 	"github.com/elberthcabrales/movies-api/pkg/models"
 	"github.com/elberthcabrales/movies-api/pkg/repositories"
 )
 
 // MovieService defines the interface for movie-related operations
 type MovieService interface {
-	GetMovieByID(id string) (*models.Movie, error)
-	GetMovies(page int) (*models.MovieList, error)
-	SaveMovie(movie *models.Movie) error
+	GetMovieByID(ctx context.Context, id string) (*models.Movie, cache.Status, error)
+	GetMovies(ctx context.Context, query models.DiscoverQuery) (*models.MovieList, error)
+	SearchMovies(ctx context.Context, query models.SearchQuery) (*models.MovieList, error)
+	SaveMovie(ctx context.Context, movie *models.Movie) error
 }
 
 type movieService struct {
@@ -23,17 +27,23 @@ func NewMovieService(repo repositories.MovieRepository) MovieService {
 	}
 }
 
-// GetMovieByID retrieves a movie by its ID, either from the cache or the API
-func (s *movieService) GetMovieByID(id string) (*models.Movie, error) {
-	return s.repo.GetMovieByID(id)
+// GetMovieByID retrieves a movie by its ID, either from the cache or the API,
+// and reports how fresh the returned data is
+func (s *movieService) GetMovieByID(ctx context.Context, id string) (*models.Movie, cache.Status, error) {
+	return s.repo.GetMovieByID(ctx, id)
+}
+
+// GetMovies retrieves a list of movies matching query from the API
+func (s *movieService) GetMovies(ctx context.Context, query models.DiscoverQuery) (*models.MovieList, error) {
+	return s.repo.GetMovies(ctx, query)
 }
 
-// GetMovies retrieves a list of movies from the API
-func (s *movieService) GetMovies(page int) (*models.MovieList, error) {
-	return s.repo.GetMovies(page)
+// SearchMovies searches movies by title, from the cache or the API
+func (s *movieService) SearchMovies(ctx context.Context, query models.SearchQuery) (*models.MovieList, error) {
+	return s.repo.SearchMovies(ctx, query)
 }
 
 // SaveMovie saves a movie in the cache
-func (s *movieService) SaveMovie(movie *models.Movie) error {
-	return s.repo.SaveMovie(movie)
+func (s *movieService) SaveMovie(ctx context.Context, movie *models.Movie) error {
+	return s.repo.SaveMovie(ctx, movie)
 }
@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/elberthcabrales/movies-api/pkg/cache"
 	"github.com/elberthcabrales/movies-api/pkg/models"
 )
 
@@ -13,24 +15,32 @@ type MockMovieRepository struct {
 	mock.Mock
 }
 
-func (m *MockMovieRepository) GetMovieByID(id string) (*models.Movie, error) {
-	args := m.Called(id)
+func (m *MockMovieRepository) GetMovieByID(ctx context.Context, id string) (*models.Movie, cache.Status, error) {
+	args := m.Called(ctx, id)
 	if movie, ok := args.Get(0).(*models.Movie); ok {
-		return movie, args.Error(1)
+		return movie, args.Get(1).(cache.Status), args.Error(2)
+	}
+	return nil, args.Get(1).(cache.Status), args.Error(2)
+}
+
+func (m *MockMovieRepository) GetMovies(ctx context.Context, query models.DiscoverQuery) (*models.MovieList, error) {
+	args := m.Called(ctx, query)
+	if movies, ok := args.Get(0).(*models.MovieList); ok {
+		return movies, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockMovieRepository) GetMovies(page int) (*models.MovieList, error) {
-	args := m.Called(page)
+func (m *MockMovieRepository) SearchMovies(ctx context.Context, query models.SearchQuery) (*models.MovieList, error) {
+	args := m.Called(ctx, query)
 	if movies, ok := args.Get(0).(*models.MovieList); ok {
 		return movies, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockMovieRepository) SaveMovie(movie *models.Movie) error {
-	args := m.Called(movie)
+func (m *MockMovieRepository) SaveMovie(ctx context.Context, movie *models.Movie) error {
+	args := m.Called(ctx, movie)
 	return args.Error(0)
 }
 
@@ -43,12 +53,13 @@ func TestMovieService_GetMovieByID(t *testing.T) {
 		Title: "Bad Boys: Ride or Die",
 	}
 
-	mockRepo.On("GetMovieByID", "573435").Return(expectedMovie, nil)
+	mockRepo.On("GetMovieByID", mock.Anything, "573435").Return(expectedMovie, cache.StatusStale, nil)
 
-	movie, err := service.GetMovieByID("573435")
+	movie, status, err := service.GetMovieByID(context.Background(), "573435")
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedMovie, movie)
+	assert.Equal(t, cache.StatusStale, status)
 
 	mockRepo.AssertExpectations(t)
 }
@@ -65,9 +76,32 @@ func TestMovieService_GetMovies(t *testing.T) {
 		Page: 1,
 	}
 
-	mockRepo.On("GetMovies", 1).Return(expectedMovies, nil)
+	query := models.DiscoverQuery{Page: 1, GenreIDs: []int{28}, SortBy: "vote_average.desc"}
+	mockRepo.On("GetMovies", mock.Anything, query).Return(expectedMovies, nil)
+
+	movies, err := service.GetMovies(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMovies, movies)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMovieService_SearchMovies(t *testing.T) {
+	mockRepo := new(MockMovieRepository)
+	service := NewMovieService(mockRepo)
+
+	query := models.SearchQuery{Query: "wolverine", Page: 1}
+	expectedMovies := &models.MovieList{
+		Results:      []models.Movie{{ID: 533535, Title: "Dead pool & Wolverine"}},
+		Page:         1,
+		TotalPages:   1,
+		TotalResults: 1,
+	}
+
+	mockRepo.On("SearchMovies", mock.Anything, query).Return(expectedMovies, nil)
 
-	movies, err := service.GetMovies(1)
+	movies, err := service.SearchMovies(context.Background(), query)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedMovies, movies)
@@ -84,9 +118,9 @@ func TestMovieService_SaveMovie(t *testing.T) {
 		Title: "Bad Boys: Ride or Die",
 	}
 
-	mockRepo.On("SaveMovie", movie).Return(nil)
+	mockRepo.On("SaveMovie", mock.Anything, movie).Return(nil)
 
-	err := service.SaveMovie(movie)
+	err := service.SaveMovie(context.Background(), movie)
 
 	assert.NoError(t, err)
 
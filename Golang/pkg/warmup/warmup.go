@@ -0,0 +1,306 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	"github.com/elberthcabrales/movies-api/pkg/config"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
+)
+
+// Errors returned when a warm-up cannot be started.
+var (
+	ErrRunning  = errors.New("warm-up already running")
+	ErrDisabled = errors.New("warm-up is disabled")
+)
+
+// Possible values of Status.State.
+const (
+	StateIdle    = "idle"
+	StateRunning = "running"
+	StateDone    = "done"
+)
+
+// Progress counts the items of one kind handled by the current or last run.
+type Progress struct {
+	Total  int `json:"total"`
+	Done   int `json:"done"`
+	Failed int `json:"failed"`
+}
+
+// Status describes the current or last warm-up run.
+type Status struct {
+	State string `json:"state"`
+	// Warm reports whether the last completed run warmed every page and movie.
+	// It keeps its value while the next run is in progress.
+	Warm       bool       `json:"warm"`
+	Runs       int        `json:"runs"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Pages      Progress   `json:"pages"`
+	// Movies has no total until every page has been warmed.
+	Movies    Progress `json:"movies"`
+	LastError string   `json:"last_error,omitempty"`
+}
+
+// Repository is the part of the movie repository a Warmer uses: discover
+// pages, and the cache entry of each movie they list.
+type Repository interface {
+	GetMovies(ctx context.Context, query models.DiscoverQuery) (*models.MovieList, error)
+	InspectMovie(ctx context.Context, id string) (*models.CacheEntry, error)
+	RefreshMovie(ctx context.Context, id string) (*models.Movie, error)
+}
+
+// Warmer prefetches the first discover pages and the details of every movie
+// in them, so that the first users after a deploy or a cache flush do not wait
+// on the upstream API.
+type Warmer struct {
+	repo        Repository
+	pages       int
+	interval    time.Duration
+	concurrency int
+	limiter     *rate.Limiter
+
+	// mu guards status. A run is in progress while status.State is
+	// StateRunning, so the state and the running check cannot disagree.
+	mu     sync.Mutex
+	status Status
+	now    func() time.Time
+}
+
+// NewWarmer creates a Warmer that warms the cache through repo.
+func NewWarmer(repo Repository, cfg *config.WarmupConfig) *Warmer {
+	limit := rate.Inf
+	if cfg.RatePerSecond > 0 {
+		limit = rate.Limit(cfg.RatePerSecond)
+	}
+	burst := cfg.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	concurrency := cfg.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Warmer{
+		repo:        repo,
+		pages:       cfg.Pages,
+		interval:    cfg.Interval,
+		concurrency: concurrency,
+		limiter:     rate.NewLimiter(limit, burst),
+		status:      Status{State: StateIdle},
+		now:         time.Now,
+	}
+}
+
+// Enabled reports whether the Warmer has any page to warm.
+func (w *Warmer) Enabled() bool {
+	return w.pages > 0
+}
+
+// Run warms the cache right away and then on every interval until ctx is
+// cancelled. Scheduled runs are skipped while a triggered one is in progress.
+func (w *Warmer) Run(ctx context.Context) {
+	if !w.Enabled() {
+		return
+	}
+	w.runLogged(ctx)
+	if w.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.runLogged(ctx)
+		}
+	}
+}
+
+// Trigger starts a run in the background. It outlives the request that
+// triggered it, so it is not cancelled with ctx.
+func (w *Warmer) Trigger(ctx context.Context) error {
+	if !w.Enabled() {
+		return ErrDisabled
+	}
+	// The run is marked as started before returning, so that the status
+	// reported right after a trigger already describes it.
+	started, err := w.start()
+	if err != nil {
+		return err
+	}
+	go w.warm(context.WithoutCancel(ctx), started)
+	return nil
+}
+
+// Status returns the state of the current or last run.
+func (w *Warmer) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// Warm runs the warm-up once and waits for it to finish. It returns ErrRunning
+// if a run is already in progress.
+func (w *Warmer) Warm(ctx context.Context) error {
+	started, err := w.start()
+	if err != nil {
+		return err
+	}
+	return w.warm(ctx, started)
+}
+
+func (w *Warmer) runLogged(ctx context.Context) {
+	if err := w.Warm(ctx); errors.Is(err, ErrRunning) {
+		log.Println("Skipping scheduled warm-up, one is already running")
+	}
+}
+
+// start resets the status for a new run and returns its start time. It
+// returns ErrRunning if a run is already in progress.
+func (w *Warmer) start() (time.Time, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.status.State == StateRunning {
+		return time.Time{}, ErrRunning
+	}
+	started := w.now()
+	w.status.State = StateRunning
+	w.status.Runs++
+	w.status.StartedAt = &started
+	w.status.FinishedAt = nil
+	w.status.Pages = Progress{Total: w.pages}
+	w.status.Movies = Progress{}
+	w.status.LastError = ""
+	return started, nil
+}
+
+// warm fetches the discover pages, then the details of every movie listed in
+// them. Failures are counted and logged; they do not stop the run.
+func (w *Warmer) warm(ctx context.Context, started time.Time) error {
+	log.Printf("Warming up cache with %d discover pages...", w.pages)
+
+	ids := w.warmPages(ctx)
+	w.update(func(s *Status) { s.Movies.Total = len(ids) })
+	w.warmMovies(ctx, ids)
+
+	// Setting StateDone also ends the run, so a new one can start as soon
+	// as the status reports this one as done.
+	finished := w.now()
+	status := w.update(func(s *Status) {
+		s.State = StateDone
+		s.FinishedAt = &finished
+		if ctx.Err() == nil {
+			s.Warm = s.Pages.Failed == 0 && s.Movies.Failed == 0
+		}
+	})
+	log.Printf("Cache warm-up finished in %s: %d/%d pages, %d/%d movies",
+		finished.Sub(started), status.Pages.Done, status.Pages.Total, status.Movies.Done, status.Movies.Total)
+	return ctx.Err()
+}
+
+// warmPages fetches every discover page and returns the IDs of the movies they list.
+func (w *Warmer) warmPages(ctx context.Context) []string {
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+	var ids []string
+
+	g := w.group()
+	for page := 1; page <= w.pages; page++ {
+		g.Go(func() error {
+			if err := w.limiter.Wait(ctx); err != nil {
+				w.failed(func(s *Status) { s.Pages.Failed++ }, err)
+				return nil
+			}
+			list, err := w.repo.GetMovies(ctx, models.DiscoverQuery{Page: page})
+			if err != nil {
+				log.Printf("Warm-up failed for discover page %d: %v", page, err)
+				w.failed(func(s *Status) { s.Pages.Failed++ }, err)
+				return nil
+			}
+			mu.Lock()
+			for _, movie := range list.Results {
+				if !seen[movie.ID] {
+					seen[movie.ID] = true
+					ids = append(ids, strconv.Itoa(movie.ID))
+				}
+			}
+			mu.Unlock()
+			w.update(func(s *Status) { s.Pages.Done++ })
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return ids
+}
+
+// warmMovies makes sure the full details of every movie are cached. Movies that
+// are missing or stale, such as the partial entries seeded from discover pages,
+// are fetched from the upstream API.
+func (w *Warmer) warmMovies(ctx context.Context, ids []string) {
+	g := w.group()
+	for _, id := range ids {
+		g.Go(func() error {
+			if err := w.warmMovie(ctx, id); err != nil {
+				log.Printf("Warm-up failed for movie ID %s: %v", id, err)
+				w.failed(func(s *Status) { s.Movies.Failed++ }, err)
+				return nil
+			}
+			w.update(func(s *Status) { s.Movies.Done++ })
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// warmMovie fetches the movie unless the cache holds a fresh entry for it or
+// a recent not-found result. A movie the upstream API does not know is not a
+// failure: its not-found result is cached like any other.
+func (w *Warmer) warmMovie(ctx context.Context, id string) error {
+	entry, err := w.repo.InspectMovie(ctx, id)
+	switch {
+	case err == nil && (!entry.Stale || entry.Source == repositories.SourceTombstone):
+		return nil
+	case err != nil && !errors.Is(err, repositories.ErrNotFound):
+		return err
+	}
+	if err := w.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	_, err = w.repo.RefreshMovie(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (w *Warmer) group() *errgroup.Group {
+	g := &errgroup.Group{}
+	g.SetLimit(w.concurrency)
+	return g
+}
+
+func (w *Warmer) failed(count func(*Status), err error) {
+	w.update(func(s *Status) {
+		count(s)
+		s.LastError = err.Error()
+	})
+}
+
+func (w *Warmer) update(fn func(*Status)) Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fn(&w.status)
+	return w.status
+}
@@ -0,0 +1,201 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elberthcabrales/movies-api/pkg/cache"
+	"github.com/elberthcabrales/movies-api/pkg/config"
+	"github.com/elberthcabrales/movies-api/pkg/models"
+	"github.com/elberthcabrales/movies-api/pkg/repositories"
+)
+
+// fakeRepository serves two movies per discover page, with movie IDs shared
+// between consecutive pages, and records every call.
+type fakeRepository struct {
+	mu        sync.Mutex
+	cached    map[string]*models.CacheEntry
+	refreshed []string
+	pageErr   map[int]error
+	refresh   func(id string) error
+
+	active    atomic.Int32
+	maxActive atomic.Int32
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{cached: make(map[string]*models.CacheEntry), pageErr: make(map[int]error)}
+}
+
+func (f *fakeRepository) enter() func() {
+	n := f.active.Add(1)
+	for {
+		max := f.maxActive.Load()
+		if n <= max || f.maxActive.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return func() { f.active.Add(-1) }
+}
+
+func (f *fakeRepository) GetMovies(ctx context.Context, query models.DiscoverQuery) (*models.MovieList, error) {
+	defer f.enter()()
+	page := query.Page
+	if err := f.pageErr[page]; err != nil {
+		return nil, err
+	}
+	return &models.MovieList{Page: page, Results: []models.Movie{{ID: page}, {ID: page + 1}}}, nil
+}
+
+func (f *fakeRepository) InspectMovie(ctx context.Context, id string) (*models.CacheEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if entry, ok := f.cached[id]; ok {
+		return entry, nil
+	}
+	return nil, fmt.Errorf("%w: %s", repositories.ErrNotFound, id)
+}
+
+func (f *fakeRepository) RefreshMovie(ctx context.Context, id string) (*models.Movie, error) {
+	defer f.enter()()
+	f.mu.Lock()
+	f.refreshed = append(f.refreshed, id)
+	f.mu.Unlock()
+	if f.refresh != nil {
+		if err := f.refresh(id); err != nil {
+			return nil, err
+		}
+	}
+	movieID, _ := strconv.Atoi(id)
+	return &models.Movie{ID: movieID}, nil
+}
+
+func newTestWarmer(repo Repository, pages int) *Warmer {
+	return NewWarmer(repo, &config.WarmupConfig{Pages: pages, Concurrency: 2})
+}
+
+func TestWarmer_WarmsPagesAndMovies(t *testing.T) {
+	repo := newFakeRepository()
+	repo.cached["2"] = &models.CacheEntry{Source: repositories.SourceTMDB}
+	repo.cached["3"] = &models.CacheEntry{Source: repositories.SourceTMDB, Stale: true}
+	repo.cached["4"] = &models.CacheEntry{Source: repositories.SourceTombstone, Stale: false}
+	warmer := newTestWarmer(repo, 4)
+
+	assert.NoError(t, warmer.Warm(context.Background()))
+
+	// Pages 1-4 list movies 1-5; 2 is fresh and 4 is known not to exist.
+	assert.ElementsMatch(t, []string{"1", "3", "5"}, repo.refreshed)
+	status := warmer.Status()
+	assert.Equal(t, StateDone, status.State)
+	assert.True(t, status.Warm)
+	assert.Equal(t, 1, status.Runs)
+	assert.Equal(t, Progress{Total: 4, Done: 4}, status.Pages)
+	assert.Equal(t, Progress{Total: 5, Done: 5}, status.Movies)
+	assert.NotNil(t, status.StartedAt)
+	assert.NotNil(t, status.FinishedAt)
+	assert.LessOrEqual(t, repo.maxActive.Load(), int32(2), "Expected at most 2 concurrent upstream calls")
+}
+
+func TestWarmer_CountsFailures(t *testing.T) {
+	repo := newFakeRepository()
+	repo.pageErr[2] = errors.New("upstream timeout")
+	repo.refresh = func(id string) error {
+		switch id {
+		case "1":
+			return fmt.Errorf("%w: %s", repositories.ErrNotFound, id)
+		case "4":
+			return errors.New("status code: 500")
+		}
+		return nil
+	}
+	warmer := newTestWarmer(repo, 3)
+
+	assert.NoError(t, warmer.Warm(context.Background()))
+
+	status := warmer.Status()
+	assert.False(t, status.Warm)
+	assert.Equal(t, Progress{Total: 3, Done: 2, Failed: 1}, status.Pages)
+	// Pages 1 and 3 list movies 1, 2, 3 and 4; a movie TMDB does not know is not a failure.
+	assert.Equal(t, Progress{Total: 4, Done: 3, Failed: 1}, status.Movies)
+	assert.Equal(t, "status code: 500", status.LastError)
+}
+
+func TestWarmer_RateLimitsUpstreamCalls(t *testing.T) {
+	repo := newFakeRepository()
+	warmer := NewWarmer(repo, &config.WarmupConfig{Pages: 1, Concurrency: 4, RatePerSecond: 20, Burst: 1})
+
+	start := time.Now()
+	assert.NoError(t, warmer.Warm(context.Background()))
+
+	// One page and two movies at 20/s with a burst of 1 take at least 100ms.
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	assert.Len(t, repo.refreshed, 2)
+}
+
+func TestWarmer_RejectsConcurrentRuns(t *testing.T) {
+	repo := newFakeRepository()
+	release := make(chan struct{})
+	repo.refresh = func(string) error {
+		<-release
+		return nil
+	}
+	warmer := newTestWarmer(repo, 1)
+
+	assert.NoError(t, warmer.Trigger(context.Background()))
+	assert.Eventually(t, func() bool { return warmer.Status().State == StateRunning }, time.Second, time.Millisecond)
+
+	assert.ErrorIs(t, warmer.Trigger(context.Background()), ErrRunning)
+	assert.ErrorIs(t, warmer.Warm(context.Background()), ErrRunning)
+
+	close(release)
+	assert.Eventually(t, func() bool { return warmer.Status().State == StateDone }, time.Second, time.Millisecond)
+	assert.NoError(t, warmer.Warm(context.Background()))
+	assert.Equal(t, 2, warmer.Status().Runs)
+}
+
+func TestWarmer_Disabled(t *testing.T) {
+	warmer := newTestWarmer(newFakeRepository(), 0)
+
+	assert.False(t, warmer.Enabled())
+	assert.ErrorIs(t, warmer.Trigger(context.Background()), ErrDisabled)
+	warmer.Run(context.Background())
+	assert.Equal(t, Status{State: StateIdle}, warmer.Status())
+}
+
+func TestWarmer_RunsOnSchedule(t *testing.T) {
+	repo := newFakeRepository()
+	warmer := NewWarmer(repo, &config.WarmupConfig{Pages: 1, Interval: 10 * time.Millisecond, Concurrency: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		warmer.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool { return warmer.Status().Runs >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestWarmer_WorksWithMovieRepository(t *testing.T) {
+	// The real repository satisfies what the warmer needs without an upstream
+	// call when everything is cached.
+	memoryCache := cache.NewMemoryCache(&config.CacheConfig{Shards: 1})
+	repo := repositories.NewMovieRepository("dummy-auth-token", memoryCache)
+	assert.NoError(t, repo.SaveMovie(context.Background(), &models.Movie{ID: 7}))
+	assert.NoError(t, memoryCache.SetValue(context.Background(), "movies:v2:discover:en-US:page=1", `{"page":1,"results":[{"id":7}]}`))
+
+	warmer := newTestWarmer(repo, 1)
+	assert.NoError(t, warmer.Warm(context.Background()))
+	assert.Equal(t, Progress{Total: 1, Done: 1}, warmer.Status().Movies)
+}